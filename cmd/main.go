@@ -9,9 +9,19 @@ import (
 	"syscall"
 
 	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/config"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/alert"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/anomaly"
 	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/backup"
 	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/bot"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/budget"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/category"
 	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/envelope"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/goals"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/idempotency"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/receipt"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/token"
 	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/web"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -34,6 +44,62 @@ func main() {
 		log.Panic(err)
 	}
 
+	templates, err := recurring.New(filepath.Join(filepath.Dir(dataPath), "recurring.csv"))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	dataDir := filepath.Dir(dataPath)
+	envelopes, err := envelope.New(filepath.Join(dataDir, "envelopes.csv"), filepath.Join(dataDir, "envelope_moves.csv"))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	goalStore, err := goals.New(filepath.Join(dataDir, "goals.csv"), filepath.Join(dataDir, "goal_contributions.csv"))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	anomalies, err := anomaly.New(filepath.Join(dataDir, "anomaly_suppressions.csv"), filepath.Join(dataDir, "anomaly_pending.csv"))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	alerts, err := alert.New(filepath.Join(dataDir, "alerts_sent.csv"))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	tokens, err := token.New(filepath.Join(dataDir, "tokens.csv"))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	keys, err := idempotency.New(filepath.Join(dataDir, "idempotency_keys.csv"))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	categories, err := category.New(filepath.Join(dataDir, "categories.csv"))
+	if err != nil {
+		log.Panic(err)
+	}
+	// New and edited transactions store the managed category for any of its spellings,
+	// and envelopes name categories the same way
+	db.SetCategoryResolver(categories.Normalize)
+	envelopes.SetCategoryResolver(categories.Normalize)
+
+	receipts, err := receipt.New(filepath.Join(dataDir, "receipts"))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Budget settings are read once; the planner is shared by the bot and the web server
+	settings := budget.FromEnv()
+	// FIXED_CATEGORIES match every spelling of a managed category, merged ones too
+	settings.ResolveCategory = categories.Normalize
+	planner := budget.NewPlanner(db, templates, settings)
+
 	api, err := tgbotapi.NewBotAPI(cfg.TelegramBotToken)
 	if err != nil {
 		log.Panic(err)
@@ -43,16 +109,26 @@ func main() {
 
 	log.Printf("Authorized on account %s", api.Self.UserName)
 
-	b := bot.New(api, db)
+	b := bot.New(api, db, templates, planner, envelopes, goalStore, anomalies, alerts, tokens, keys, categories, receipts)
+	// Days of transactions with a time are counted in the time zone of the reports
+	db.SetLocation(b.Location())
+	// Check for unusual spending after every added transaction and import, off the request path
+	db.OnAdd(func(added []data.Transaction) { go b.CheckAnomalies(added) })
 	go b.Start()
 
 	// Start daily backup scheduler
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 	backupDir := filepath.Join(filepath.Dir(dataPath), "backups")
-	go backup.RunDaily(ctx, dataPath, backupDir, cfg.BackupTime, cfg.BackupTimezone, cfg.BackupRetention, nil)
+	go backup.RunDaily(ctx, dataPath, receipts.Dir(), backupDir, cfg.BackupTime, cfg.BackupTimezone, cfg.BackupRetention, nil)
+
+	// Start recurring charges scheduler
+	go recurring.RunDaily(ctx, templates, db, cfg.RecurringTime, cfg.ReportTimezone, nil)
+
+	// Start daily alerts (suspected subscriptions: missing charges, price increases)
+	go b.RunAlerts(ctx, cfg.ReportTime)
 
-	server := web.New(db, b)
+	server := web.New(db, b, templates, planner, envelopes, goalStore, tokens, keys, categories, receipts)
 	if err := server.Start(cfg.WebAddress, cfg.CertPath, cfg.KeyPath); err != nil {
 		log.Fatal(err)
 	}
@@ -17,6 +17,9 @@ type Config struct {
 	BackupTime       string // HH:MM local time
 	BackupTimezone   string // e.g., Europe/Moscow
 	BackupRetention  int    // days to keep backups
+	ReportTime       string // HH:MM local time for daily alerts
+	ReportTimezone   string // e.g., Europe/Moscow
+	RecurringTime    string // HH:MM local time to materialize recurring charges
 }
 
 func Load() *Config {
@@ -34,6 +37,9 @@ func Load() *Config {
 		BackupTime:       getEnv("BACKUP_TIME", "03:00"),
 		BackupTimezone:   getEnv("BACKUP_TIMEZONE", ""),
 		BackupRetention:  getEnvInt("BACKUP_RETENTION_DAYS", 30),
+		ReportTime:       getEnv("DAILY_REPORT_TIME", "19:00"),
+		ReportTimezone:   getEnv("DAILY_REPORT_TIMEZONE", ""),
+		RecurringTime:    getEnv("RECURRING_TIME", "00:05"),
 	}
 }
 
@@ -0,0 +1,106 @@
+package alert
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Retention is how long a sent alert is remembered. Keys name the day or cycle
+// they are about, so older ones cannot come up again.
+const Retention = 400 * 24 * time.Hour
+
+var header = []string{"Key", "Sent"}
+
+// Store remembers which alerts were pushed, so each one is sent once even across
+// restarts. Keys survive in a CSV file next to the ledger.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	sent map[string]time.Time
+}
+
+func New(path string) (*Store, error) {
+	s := &Store{path: path, sent: map[string]time.Time{}}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if strings.Join(records[0], ",") != strings.Join(header, ",") {
+		return errors.New("sent alerts CSV header does not match expected format")
+	}
+	for i, r := range records[1:] {
+		sent, err := time.Parse(dateLayout, r[1])
+		if err != nil {
+			return fmt.Errorf("invalid date on line %d: %w", i+2, err)
+		}
+		s.sent[r[0]] = sent
+	}
+	return nil
+}
+
+// save drops expired keys and persists the rest; callers must hold s.mu.
+func (s *Store) save(now time.Time) error {
+	for key, sent := range s.sent {
+		if now.Sub(sent) > Retention {
+			delete(s.sent, key)
+		}
+	}
+
+	file, err := os.Create(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	for key, sent := range s.sent {
+		if err := writer.Write([]string{key, sent.Format(dateLayout)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Mark records an alert key and reports whether it was not seen before. The
+// key counts as seen even if saving fails, so the alert is not repeated until
+// the next restart at least.
+func (s *Store) Mark(key string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sent[key]; ok {
+		return false, nil
+	}
+	s.sent[key] = now
+	return true, s.save(now)
+}
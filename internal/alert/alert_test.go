@@ -0,0 +1,39 @@
+package alert
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMark(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "alerts_sent.csv")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 8, 11, 19, 0, 0, 0, time.UTC)
+	if first, err := s.Mark("forecast:2024-06-15", now.Add(-Retention-24*time.Hour)); err != nil || !first {
+		t.Fatalf("Mark() of a new key = %v, %v; want true", first, err)
+	}
+	if first, err := s.Mark("forecast:2025-07-15", now); err != nil || !first {
+		t.Fatalf("Mark() of a new key = %v, %v; want true", first, err)
+	}
+	if first, _ := s.Mark("forecast:2025-07-15", now); first {
+		t.Error("Mark() of a key seen before = true, want false")
+	}
+
+	// Keys survive a restart; expired ones are dropped.
+	s, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, _ := s.Mark("forecast:2025-07-15", now.Add(time.Hour)); first {
+		t.Error("Mark() after a restart = true, want the key remembered")
+	}
+	if first, _ := s.Mark("forecast:2024-06-15", now); !first {
+		t.Errorf("Mark() of a key older than %v = false, want it forgotten", Retention)
+	}
+}
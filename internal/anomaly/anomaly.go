@@ -0,0 +1,373 @@
+package anomaly
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+const dateLayout = "2006-01-02"
+
+const (
+	recentDays     = 3   // only days this close to today are checked, so imports of old data stay quiet
+	dayWindow      = 30  // trailing days compared against for daily spikes
+	categoryWindow = 180 // trailing days compared against for category spikes
+	minDayHistory  = 7   // days with spending needed before daily spikes are reported
+	minCatHistory  = 4   // days with spending in a category needed before its spikes are reported
+	spikeFactor    = 3.0 // a spike is at least this many times the trailing median...
+	minDayExcess   = 500 // ...and at least this much above it, in RUB
+	minCatExcess   = 300
+	suppressMargin = 1.2 // a suppressed spike also hides similar ones up to 20% larger
+	pendingDays    = 30  // an alert can be marked as expected for this many days after its date
+)
+
+// Kind is the type of an anomaly.
+type Kind string
+
+const (
+	DaySpike      Kind = "day"       // a day's total far above the trailing median
+	CategorySpike Kind = "category"  // a category's daily spend far above its history
+	Duplicate     Kind = "duplicate" // same amount at the same merchant on the same day
+)
+
+// Anomaly is an unusual day, category spend or a likely duplicated charge.
+type Anomaly struct {
+	Kind     Kind
+	Date     string
+	Subject  string  // category for category spikes, merchant and amount for duplicates
+	Category string  // category of a duplicated charge
+	Amount   float64 // day total, category total or the duplicated amount
+	Baseline float64 // trailing median; 0 for duplicates
+	Count    int     // number of identical charges for duplicates
+}
+
+// Key identifies the anomaly, e.g. for inline buttons.
+func (a Anomaly) Key() string {
+	sum := sha1.Sum([]byte(string(a.Kind) + "|" + a.Date + "|" + a.Subject))
+	return hex.EncodeToString(sum[:4])
+}
+
+// Detect checks the days touched by added against the whole ledger. Spending for
+// which exclude returns true (fixed costs) does not count towards spikes.
+func Detect(all, added []data.Transaction, today time.Time, exclude func(data.Transaction) bool) []Anomaly {
+	earliest := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -recentDays).Format(dateLayout)
+	dates := map[string]bool{}
+	categories := map[string]map[string]bool{}
+	for _, tx := range added {
+		if tx.Date < earliest {
+			continue
+		}
+		dates[tx.Date] = true
+		if categories[tx.Date] == nil {
+			categories[tx.Date] = map[string]bool{}
+		}
+		for _, p := range tx.Parts() {
+			categories[tx.Date][strings.ToLower(p.Category)] = true
+		}
+	}
+
+	dayTotals := map[string]float64{}
+	catTotals := map[string]map[string]float64{}
+	charges := map[string]int{}
+	for _, tx := range all {
+		if dates[tx.Date] {
+			charges[duplicateSubject(tx)+"|"+tx.Date]++
+		}
+		if exclude != nil && exclude(tx) {
+			continue
+		}
+		dayTotals[tx.Date] += tx.Amount
+		for _, p := range tx.Parts() {
+			cat := strings.ToLower(p.Category)
+			if catTotals[cat] == nil {
+				catTotals[cat] = map[string]float64{}
+			}
+			catTotals[cat][tx.Date] += p.Amount
+		}
+	}
+
+	var res []Anomaly
+	for date := range dates {
+		if base, ok := trailingMedian(dayTotals, date, dayWindow, minDayHistory); ok && isSpike(dayTotals[date], base, minDayExcess) {
+			res = append(res, Anomaly{Kind: DaySpike, Date: date, Amount: dayTotals[date], Baseline: base})
+		}
+		for cat := range categories[date] {
+			total := catTotals[cat][date]
+			if base, ok := trailingMedian(catTotals[cat], date, categoryWindow, minCatHistory); ok && isSpike(total, base, minCatExcess) {
+				res = append(res, Anomaly{Kind: CategorySpike, Date: date, Subject: cat, Amount: total, Baseline: base})
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, tx := range added {
+		subject := duplicateSubject(tx)
+		n := charges[subject+"|"+tx.Date]
+		if n < 2 || seen[subject+"|"+tx.Date] {
+			continue
+		}
+		seen[subject+"|"+tx.Date] = true
+		res = append(res, Anomaly{Kind: Duplicate, Date: tx.Date, Subject: subject, Category: tx.Category, Amount: tx.Amount, Count: n})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Date != res[j].Date {
+			return res[i].Date < res[j].Date
+		}
+		if res[i].Kind != res[j].Kind {
+			return res[i].Kind < res[j].Kind
+		}
+		return res[i].Subject < res[j].Subject
+	})
+	return res
+}
+
+func duplicateSubject(tx data.Transaction) string {
+	return tx.Merchant() + "|" + strings.ToLower(tx.Category) + "|" + strconv.FormatFloat(tx.Amount, 'f', 2, 64)
+}
+
+func isSpike(amount, baseline, minExcess float64) bool {
+	return amount >= baseline*spikeFactor && amount-baseline >= minExcess
+}
+
+// trailingMedian returns the median of the totals of the days with spending within
+// window days before date, if there are at least minDays of them.
+func trailingMedian(totals map[string]float64, date string, window, minDays int) (float64, bool) {
+	d, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return 0, false
+	}
+	from := d.AddDate(0, 0, -window).Format(dateLayout)
+	var values []float64
+	for day, total := range totals {
+		if day >= from && day < date && total > 0 {
+			values = append(values, total)
+		}
+	}
+	if len(values) < minDays {
+		return 0, false
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2, true
+	}
+	return values[mid], true
+}
+
+var (
+	header        = []string{"Kind", "Subject", "Amount", "Created"}
+	pendingHeader = []string{"Kind", "Date", "Subject", "Category", "Amount", "Baseline", "Count"}
+)
+
+// suppression hides future anomalies of the same kind and subject up to Amount.
+type suppression struct {
+	Kind    Kind
+	Subject string
+	Amount  float64
+	Created string
+}
+
+// Store keeps the anomalies marked as expected, and the ones alerted about that
+// may still be, in two CSV files.
+type Store struct {
+	mu           sync.Mutex
+	path         string
+	pendingPath  string
+	suppressions []suppression
+	pending      []Anomaly
+}
+
+func New(path, pendingPath string) (*Store, error) {
+	s := &Store{path: path, pendingPath: pendingPath}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func readCSV(path string, header []string) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	if strings.Join(records[0], ",") != strings.Join(header, ",") {
+		return nil, fmt.Errorf("%s header does not match expected format", path)
+	}
+	return records[1:], nil
+}
+
+func (s *Store) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := readCSV(s.path, header)
+	if err != nil {
+		return err
+	}
+	for i, r := range records {
+		amount, err := strconv.ParseFloat(r[2], 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount on line %d: %w", i+2, err)
+		}
+		s.suppressions = append(s.suppressions, suppression{Kind: Kind(r[0]), Subject: r[1], Amount: amount, Created: r[3]})
+	}
+
+	records, err = readCSV(s.pendingPath, pendingHeader)
+	if err != nil {
+		return err
+	}
+	for i, r := range records {
+		amount, err1 := strconv.ParseFloat(r[4], 64)
+		baseline, err2 := strconv.ParseFloat(r[5], 64)
+		count, err3 := strconv.Atoi(r[6])
+		if err := errors.Join(err1, err2, err3); err != nil {
+			return fmt.Errorf("invalid pending anomaly on line %d: %w", i+2, err)
+		}
+		s.pending = append(s.pending, Anomaly{Kind: Kind(r[0]), Date: r[1], Subject: r[2], Category: r[3], Amount: amount, Baseline: baseline, Count: count})
+	}
+	return nil
+}
+
+// save persists suppressions; callers must hold s.mu.
+func (s *Store) save() error {
+	file, err := os.Create(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	for _, sp := range s.suppressions {
+		if err := writer.Write([]string{string(sp.Kind), sp.Subject, strconv.FormatFloat(sp.Amount, 'f', 2, 64), sp.Created}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// savePending persists the pending anomalies; callers must hold s.mu.
+func (s *Store) savePending() error {
+	file, err := os.Create(s.pendingPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(pendingHeader)
+	for _, a := range s.pending {
+		err := writer.Write([]string{
+			string(a.Kind),
+			a.Date,
+			a.Subject,
+			a.Category,
+			strconv.FormatFloat(a.Amount, 'f', 2, 64),
+			strconv.FormatFloat(a.Baseline, 'f', 2, 64),
+			strconv.Itoa(a.Count),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// AddPending keeps an anomaly that was alerted about, so that it can be marked as
+// expected by its Key until pendingDays after its date, across restarts too.
+func (s *Store) AddPending(a Anomaly, today time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := today.AddDate(0, 0, -pendingDays).Format(dateLayout)
+	kept := s.pending[:0]
+	for _, p := range s.pending {
+		if p.Date >= cutoff && p.Key() != a.Key() {
+			kept = append(kept, p)
+		}
+	}
+	s.pending = append(kept, a)
+	return s.savePending()
+}
+
+// Pending returns the pending anomaly with the given key.
+func (s *Store) Pending(key string) (Anomaly, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.pending {
+		if a.Key() == key {
+			return a, true
+		}
+	}
+	return Anomaly{}, false
+}
+
+// Expect marks an anomaly as expected: similar anomalies are not reported again.
+// For spikes that means the same kind and category up to a slightly larger amount;
+// for duplicates any repeated charge of the same amount at the same merchant.
+// The anomaly is no longer pending.
+func (s *Store) Expect(a Anomaly, today time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.suppressions = append(s.suppressions, suppression{
+		Kind:    a.Kind,
+		Subject: a.Subject,
+		Amount:  a.Amount * suppressMargin,
+		Created: today.Format(dateLayout),
+	})
+	if err := s.save(); err != nil {
+		return err
+	}
+	for i, p := range s.pending {
+		if p.Key() == a.Key() {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return s.savePending()
+		}
+	}
+	return nil
+}
+
+// Filter drops anomalies similar to ones marked as expected.
+func (s *Store) Filter(anomalies []Anomaly) []Anomaly {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []Anomaly
+	for _, a := range anomalies {
+		suppressed := false
+		for _, sp := range s.suppressions {
+			if sp.Kind == a.Kind && sp.Subject == a.Subject && (a.Kind == Duplicate || a.Amount <= sp.Amount) {
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			res = append(res, a)
+		}
+	}
+	return res
+}
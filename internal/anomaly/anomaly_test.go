@@ -0,0 +1,162 @@
+package anomaly
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	tx := func(date, category, description string, amount float64) data.Transaction {
+		return data.Transaction{Date: date, Category: category, Description: description, Amount: amount}
+	}
+	// Ten ordinary days of groceries and transport in August 2025.
+	var history []data.Transaction
+	for d := 1; d <= 10; d++ {
+		history = append(history,
+			tx(fmt.Sprintf("2025-08-%02d", d), "groceries", "Pyaterochka", 600),
+			tx(fmt.Sprintf("2025-08-%02d", d), "transport", "Metro", 100),
+		)
+	}
+	with := func(txs ...data.Transaction) []data.Transaction {
+		return append(append([]data.Transaction(nil), history...), txs...)
+	}
+	rent := func(tx data.Transaction) bool { return tx.Category == "rent" }
+
+	tests := []struct {
+		name  string
+		all   []data.Transaction
+		added []data.Transaction
+		today string
+		want  []Kind
+	}{
+		{
+			name:  "ordinary day",
+			all:   with(tx("2025-08-11", "groceries", "Pyaterochka", 700)),
+			added: []data.Transaction{tx("2025-08-11", "groceries", "Pyaterochka", 700)},
+			today: "2025-08-11",
+		},
+		{
+			name:  "day and category spike",
+			all:   with(tx("2025-08-11", "groceries", "Azbuka", 4000)),
+			added: []data.Transaction{tx("2025-08-11", "groceries", "Azbuka", 4000)},
+			today: "2025-08-11",
+			want:  []Kind{CategorySpike, DaySpike},
+		},
+		{
+			name:  "category spike only",
+			all:   with(tx("2025-08-11", "transport", "Taxi", 900)),
+			added: []data.Transaction{tx("2025-08-11", "transport", "Taxi", 900)},
+			today: "2025-08-11",
+			want:  []Kind{CategorySpike},
+		},
+		{
+			name:  "fixed costs do not spike",
+			all:   with(tx("2025-08-11", "rent", "Landlord", 40000)),
+			added: []data.Transaction{tx("2025-08-11", "rent", "Landlord", 40000)},
+			today: "2025-08-11",
+		},
+		{
+			name:  "duplicate charge",
+			all:   with(tx("2025-08-11", "dining", "Coffee 12", 250), tx("2025-08-11", "dining", "COFFEE 13", 250)),
+			added: []data.Transaction{tx("2025-08-11", "dining", "COFFEE 13", 250)},
+			today: "2025-08-11",
+			want:  []Kind{Duplicate},
+		},
+		{
+			name:  "old imported days are not checked",
+			all:   with(tx("2025-08-11", "groceries", "Azbuka", 4000)),
+			added: []data.Transaction{tx("2025-08-11", "groceries", "Azbuka", 4000)},
+			today: "2025-09-01",
+		},
+		{
+			name:  "too little history",
+			all:   []data.Transaction{tx("2025-08-10", "groceries", "", 500), tx("2025-08-11", "groceries", "", 5000)},
+			added: []data.Transaction{tx("2025-08-11", "groceries", "", 5000)},
+			today: "2025-08-11",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			today, _ := time.Parse(dateLayout, tt.today)
+			got := Detect(tt.all, tt.added, today, rent)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d anomalies %+v, want %v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if got[i].Kind != tt.want[i] {
+					t.Errorf("anomaly %d kind = %s, want %s", i, got[i].Kind, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStoreExpect(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path, pendingPath := filepath.Join(dir, "anomaly_suppressions.csv"), filepath.Join(dir, "anomaly_pending.csv")
+	s, err := New(path, pendingPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := time.Date(2025, 8, 11, 0, 0, 0, 0, time.UTC)
+	spike := Anomaly{Kind: CategorySpike, Date: "2025-08-11", Subject: "groceries", Amount: 4000, Baseline: 600}
+	old := Anomaly{Kind: Duplicate, Date: "2025-06-01", Subject: "cafe|250.00", Category: "dining", Amount: 250, Count: 2}
+	for _, a := range []Anomaly{old, spike} {
+		if err := s.AddPending(a, today); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Alerts can be marked as expected after a restart, old ones expire.
+	s, err = New(path, pendingPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := s.Pending(spike.Key()); !ok || got != spike {
+		t.Errorf("Pending() after reload = %+v, %v; want the spike", got, ok)
+	}
+	if _, ok := s.Pending(old.Key()); ok {
+		t.Errorf("Pending() found an anomaly older than %d days", pendingDays)
+	}
+	if err := s.Expect(spike, today); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Pending(spike.Key()); ok {
+		t.Error("Pending() found the anomaly marked as expected")
+	}
+
+	// Reload to check persistence.
+	s, err = New(path, pendingPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Pending(spike.Key()); ok {
+		t.Error("Pending() after reload found the anomaly marked as expected")
+	}
+	tests := []struct {
+		name       string
+		a          Anomaly
+		suppressed bool
+	}{
+		{"similar amount", Anomaly{Kind: CategorySpike, Subject: "groceries", Amount: 4500}, true},
+		{"much larger", Anomaly{Kind: CategorySpike, Subject: "groceries", Amount: 9000}, false},
+		{"other category", Anomaly{Kind: CategorySpike, Subject: "dining", Amount: 1000}, false},
+		{"other kind", Anomaly{Kind: DaySpike, Amount: 1000}, false},
+	}
+	for _, tt := range tests {
+		got := s.Filter([]Anomaly{tt.a})
+		if (len(got) == 0) != tt.suppressed {
+			t.Errorf("%s: suppressed = %v, want %v", tt.name, len(got) == 0, tt.suppressed)
+		}
+	}
+}
@@ -9,12 +9,14 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strings"
 	"time"
 )
 
 // RunDaily starts a daily backup loop: at the configured local time, copy sourcePath
-// to backupDir/YYYY-MM-DD.csv and maintain retentionDays worth of backups.
-func RunDaily(ctx context.Context, sourcePath string, backupDir string, timeOfDay string, tz string, retentionDays int, logger *log.Logger) {
+// to backupDir/YYYY-MM-DD.csv and maintain retentionDays worth of backups. When
+// receiptsDir is set, it is mirrored into backupDir/receipts, see mirrorReceipts.
+func RunDaily(ctx context.Context, sourcePath string, receiptsDir string, backupDir string, timeOfDay string, tz string, retentionDays int, logger *log.Logger) {
 	if logger == nil {
 		logger = log.Default()
 	}
@@ -37,7 +39,7 @@ func RunDaily(ctx context.Context, sourcePath string, backupDir string, timeOfDa
 	ensureDir(backupDir, logger)
 
 	// Run immediately on start to ensure at least one backup exists
-	doBackup(sourcePath, backupDir, retentionDays, loc, logger)
+	doBackup(sourcePath, receiptsDir, backupDir, retentionDays, loc, logger)
 
 	for {
 		next := nextAtTime(time.Now().In(loc), h, m)
@@ -51,7 +53,7 @@ func RunDaily(ctx context.Context, sourcePath string, backupDir string, timeOfDa
 			logger.Printf("backup: stopping: %v", ctx.Err())
 			return
 		case <-timer.C:
-			doBackup(sourcePath, backupDir, retentionDays, loc, logger)
+			doBackup(sourcePath, receiptsDir, backupDir, retentionDays, loc, logger)
 		}
 	}
 }
@@ -81,7 +83,7 @@ func ensureDir(dir string, logger *log.Logger) {
 	}
 }
 
-func doBackup(sourcePath, backupDir string, retentionDays int, loc *time.Location, logger *log.Logger) {
+func doBackup(sourcePath, receiptsDir, backupDir string, retentionDays int, loc *time.Location, logger *log.Logger) {
 	// Use date in the chosen timezone
 	today := time.Now().In(loc).Format("2006-01-02")
 	dst := filepath.Join(backupDir, fmt.Sprintf("%s.csv", today))
@@ -105,6 +107,57 @@ func doBackup(sourcePath, backupDir string, retentionDays int, loc *time.Locatio
 	if retentionDays > 0 {
 		enforceRetention(backupDir, retentionDays, logger)
 	}
+
+	if receiptsDir != "" {
+		mirrorReceipts(receiptsDir, filepath.Join(backupDir, "receipts"), today, retentionDays, logger)
+	}
+}
+
+// mirrorReceipts copies the receipt files that are new or changed since the last
+// run into dst. The files are content-addressed and never change, so each is
+// copied once; files detached since stay in the backup. The index is copied
+// as is and as a dated snapshot, which follows the retention of the ledger
+// backups.
+func mirrorReceipts(src, dst, today string, retentionDays int, logger *log.Logger) {
+	copied := 0
+	err := filepath.WalkDir(src, func(path string, e os.DirEntry, err error) error {
+		if err != nil || e.IsDir() || strings.HasSuffix(path, ".tmp") || strings.HasPrefix(e.Name(), "upload-") {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		if existing, err := os.Stat(target); err == nil && existing.Size() == info.Size() && !info.ModTime().After(existing.ModTime()) {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		copied++
+		return copyFileAtomic(path, target+".tmp", target)
+	})
+	if err != nil {
+		logger.Printf("backup: failed to mirror receipts %s -> %s: %v", src, dst, err)
+		return
+	}
+	logger.Printf("backup: mirrored receipts to %s (%d files copied)", dst, copied)
+
+	index := filepath.Join(src, "receipts.csv")
+	if _, err := os.Stat(index); err != nil {
+		return
+	}
+	if err := copyFile(index, filepath.Join(dst, today+".csv")); err != nil {
+		logger.Printf("backup: failed to snapshot receipts index: %v", err)
+	}
+	if retentionDays > 0 {
+		enforceRetention(dst, retentionDays, logger)
+	}
 }
 
 func copyFileAtomic(src, tmp, final string) error {
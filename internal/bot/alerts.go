@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// parseChatIDs parses a comma separated list of Telegram chat IDs (NOTIFY_CHAT_IDS).
+func parseChatIDs(s string) []int64 {
+	var ids []int64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			log.Printf("Invalid chat ID %q in NOTIFY_CHAT_IDS: %v", part, err)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// notify pushes a message to every chat listed in NOTIFY_CHAT_IDS.
+func (b *Bot) notify(text string) {
+	if len(b.notifyChatIDs) == 0 {
+		log.Printf("No NOTIFY_CHAT_IDS configured, alert not sent: %s", text)
+		return
+	}
+	for _, id := range b.notifyChatIDs {
+		if _, err := b.api.Send(tgbotapi.NewMessage(id, text)); err != nil {
+			log.Printf("Failed to send alert to %d: %v", id, err)
+		}
+	}
+}
+
+// notifyWithMarkup pushes a message with inline buttons to every chat listed in NOTIFY_CHAT_IDS.
+func (b *Bot) notifyWithMarkup(text string, markup tgbotapi.InlineKeyboardMarkup) {
+	if len(b.notifyChatIDs) == 0 {
+		log.Printf("No NOTIFY_CHAT_IDS configured, alert not sent: %s", text)
+		return
+	}
+	for _, id := range b.notifyChatIDs {
+		msg := tgbotapi.NewMessage(id, text)
+		msg.ReplyMarkup = markup
+		if _, err := b.api.Send(msg); err != nil {
+			log.Printf("Failed to send alert to %d: %v", id, err)
+		}
+	}
+}
+
+// markAlerted records an alert key and reports whether it was not seen before.
+// Keys are kept in alerts_sent.csv, so alerts are not repeated after a restart.
+func (b *Bot) markAlerted(key string) bool {
+	first, err := b.alerts.Mark(key, time.Now().In(b.location))
+	if err != nil {
+		log.Printf("Failed to save sent alert %s: %v", key, err)
+	}
+	return first
+}
+
+func (b *Bot) answerCallback(cb *tgbotapi.CallbackQuery, text string) {
+	if _, err := b.api.Request(tgbotapi.NewCallback(cb.ID, text)); err != nil {
+		log.Printf("Failed to answer callback: %v", err)
+	}
+}
+
+// RunAlerts runs the daily checks (subscriptions, cycle forecast) at the configured local time
+// (DAILY_REPORT_TIME in DAILY_REPORT_TIMEZONE) until ctx is cancelled.
+func (b *Bot) RunAlerts(ctx context.Context, timeOfDay string) {
+	h, m := 19, 0
+	if t, err := time.Parse("15:04", timeOfDay); err == nil {
+		h, m = t.Hour(), t.Minute()
+	} else if timeOfDay != "" {
+		log.Printf("Invalid DAILY_REPORT_TIME %q, defaulting 19:00: %v", timeOfDay, err)
+	}
+
+	for {
+		now := time.Now().In(b.location)
+		next := time.Date(now.Year(), now.Month(), now.Day(), h, m, 0, 0, b.location)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			b.checkSubscriptions()
+			b.checkForecast()
+		}
+	}
+}
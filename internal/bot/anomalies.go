@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/anomaly"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const expectAnomalyPrefix = "anomaly:"
+
+// CheckAnomalies looks for unusual spending on the days touched by newly added
+// transactions and pushes an alert for each new finding. Registered with data.OnAdd.
+func (b *Bot) CheckAnomalies(added []data.Transaction) {
+	found := anomaly.Detect(b.data.GetAllTransactions(), added, time.Now().In(b.location), b.planner.Settings().IsFixed)
+	for _, a := range b.anomalyStore.Filter(found) {
+		if !b.markAlerted(fmt.Sprintf("anomaly:%s:%.2f", a.Key(), a.Amount)) {
+			continue
+		}
+		if err := b.anomalyStore.AddPending(a, time.Now().In(b.location)); err != nil {
+			log.Printf("Failed to save pending anomaly %s: %v", a.Key(), err)
+		}
+
+		markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Mark as expected", expectAnomalyPrefix+a.Key()),
+		))
+		b.notifyWithMarkup(describeAnomaly(a), markup)
+	}
+}
+
+// expectAnomaly suppresses alerts similar to the anomaly with the given key.
+func (b *Bot) expectAnomaly(cb *tgbotapi.CallbackQuery, key string) {
+	a, ok := b.anomalyStore.Pending(key)
+	if !ok {
+		b.answerCallback(cb, "Alert expired")
+		return
+	}
+	if err := b.anomalyStore.Expect(a, time.Now().In(b.location)); err != nil {
+		log.Printf("Failed to suppress anomaly %s: %v", key, err)
+		b.answerCallback(cb, "❌ Failed to save")
+		return
+	}
+	b.answerCallback(cb, "✅ Won't alert about this again")
+	b.api.Send(tgbotapi.NewEditMessageReplyMarkup(cb.Message.Chat.ID, cb.Message.MessageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+}
+
+func describeAnomaly(a anomaly.Anomaly) string {
+	switch a.Kind {
+	case anomaly.DaySpike:
+		return fmt.Sprintf("📊 Unusual day: %.2f RUB spent on %s, about %.1f× the usual %.2f RUB",
+			a.Amount, a.Date, a.Amount/a.Baseline, a.Baseline)
+	case anomaly.CategorySpike:
+		return fmt.Sprintf("📊 Unusual %s spending: %.2f RUB on %s, about %.1f× the usual %.2f RUB",
+			a.Subject, a.Amount, a.Date, a.Amount/a.Baseline, a.Baseline)
+	case anomaly.Duplicate:
+		merchant := strings.SplitN(a.Subject, "|", 2)[0]
+		return fmt.Sprintf("👯 Possible duplicate: %d × %.2f RUB at %s (%s) on %s",
+			a.Count, a.Amount, merchant, a.Category, a.Date)
+	}
+	return fmt.Sprintf("📊 Unusual spending on %s: %.2f RUB", a.Date, a.Amount)
+}
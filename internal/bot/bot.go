@@ -1,8 +1,10 @@
 package bot
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,36 +12,66 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/alert"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/anomaly"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/budget"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/category"
 	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/envelope"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/goals"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/idempotency"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/receipt"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/token"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type Bot struct {
-	api      *tgbotapi.BotAPI
-	data     *data.Data
-	location *time.Location
-	// Runtime-only monthly budget override. If not set, values are taken from .env
-	monthlyBudgetOverride    float64
-	hasMonthlyBudgetOverride bool
+	api       *tgbotapi.BotAPI
+	data      *data.Data
+	templates *recurring.Store
+	planner   *budget.Planner
+	envelopes *envelope.Store
+	goals     *goals.Store
+	// Anomalies marked as expected, and the alerted ones awaiting a press
+	anomalyStore *anomaly.Store
+	location     *time.Location
+	// Chats that receive pushed alerts (NOTIFY_CHAT_IDS)
+	notifyChatIDs []int64
+	// Keys of the alerts already pushed, see markAlerted
+	alerts *alert.Store
+	// API tokens managed with /token
+	tokens *token.Store
+	// Keys of Mini App submissions already applied
+	idempotency *idempotency.Store
+	// Managed categories, see /category
+	categories *category.Store
+	// Receipt photos and PDFs attached to transactions, see /receipt
+	receipts *receipt.Store
+	fiscalMu sync.Mutex
+	// Receipts read from a QR code awaiting a category, by fiscalID
+	pendingFiscal map[string]pendingFiscal
 }
 
 type TransactionData struct {
-	Date        string  `json:"date"`
-	Category    string  `json:"category"`
-	Description string  `json:"description"`
-	Amount      float64 `json:"amount"`
+	Date        string       `json:"date"`
+	Category    string       `json:"category"`
+	Description string       `json:"description"`
+	Amount      float64      `json:"amount"`
+	Fixed       bool         `json:"fixed"`
+	Payer       string       `json:"payer"`
+	Tags        []string     `json:"tags"`
+	Splits      []data.Split `json:"splits"`
+	// Time is an optional RFC 3339 timestamp; the date is then its day in b.location
+	Time string `json:"time"`
+	// IdempotencyKey is generated by the Mini App per submission, see HandleWebAppData
+	IdempotencyKey string `json:"idempotency_key"`
 }
 
-type Transaction struct {
-	Date        string
-	Category    string
-	Description string
-	Amount      float64
-}
-
-func New(api *tgbotapi.BotAPI, data *data.Data) *Bot {
+func New(api *tgbotapi.BotAPI, data *data.Data, templates *recurring.Store, planner *budget.Planner, envelopes *envelope.Store, goalStore *goals.Store, anomalies *anomaly.Store, alerts *alert.Store, tokens *token.Store, keys *idempotency.Store, categories *category.Store, receipts *receipt.Store) *Bot {
 	tz := os.Getenv("DAILY_REPORT_TIMEZONE")
 	if tz == "" {
 		tz = "UTC"
@@ -50,12 +82,30 @@ func New(api *tgbotapi.BotAPI, data *data.Data) *Bot {
 		loc = time.UTC
 	}
 	return &Bot{
-		api:      api,
-		data:     data,
-		location: loc,
+		api:           api,
+		data:          data,
+		templates:     templates,
+		planner:       planner,
+		envelopes:     envelopes,
+		goals:         goalStore,
+		anomalyStore:  anomalies,
+		location:      loc,
+		notifyChatIDs: parseChatIDs(os.Getenv("NOTIFY_CHAT_IDS")),
+		alerts:        alerts,
+		tokens:        tokens,
+		idempotency:   keys,
+		categories:    categories,
+		receipts:      receipts,
+		pendingFiscal: map[string]pendingFiscal{},
 	}
 }
 
+// Location is the time zone of the reports (DAILY_REPORT_TIMEZONE), where the
+// day of an expense with a time is taken.
+func (b *Bot) Location() *time.Location {
+	return b.location
+}
+
 func (b *Bot) Start() {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
@@ -63,10 +113,20 @@ func (b *Bot) Start() {
 	updates := b.api.GetUpdatesChan(u)
 
 	for update := range updates {
+		if update.CallbackQuery != nil {
+			b.handleCallback(update.CallbackQuery)
+			continue
+		}
 		if update.Message == nil {
 			continue
 		}
 
+		// Expenses sent with tg.sendData arrive as web_app_data service messages
+		if update.Message.WebAppData != nil {
+			b.handleWebAppMessage(update.Message)
+			continue
+		}
+
 		log.Printf("[%s] %s", update.Message.From.UserName, update.Message.Text)
 
 		switch update.Message.Command() {
@@ -76,50 +136,120 @@ func (b *Bot) Start() {
 			b.handleDailyReport(update.Message)
 		case "saldo":
 			b.handleSaldo(update.Message)
+		case "month":
+			b.handleMonth(update.Message)
+		case "cycle":
+			b.handleCycle(update.Message)
+		case "year":
+			b.handleYear(update.Message)
+		case "heatmap":
+			b.handleHeatmap(update.Message)
 		case "budget":
 			b.handleBudget(update.Message)
+		case "recurring":
+			b.handleRecurring(update.Message)
+		case "fixed":
+			b.handleFixed(update.Message)
+		case "profile":
+			b.handleProfile(update.Message)
+		case "envelopes":
+			b.handleEnvelopes(update.Message)
+		case "move":
+			b.handleMove(update.Message)
+		case "goals":
+			b.handleGoals(update.Message)
+		case "subscriptions":
+			b.handleSubscriptions(update.Message)
+		case "token":
+			b.handleToken(update.Message)
+		case "category", "categories":
+			b.handleCategory(update.Message)
+		case "receipt", "receipts":
+			b.handleReceipt(update.Message)
 		case "csv":
 			b.handleCSVUpload(update.Message)
 		case "export":
 			b.handleExport(update.Message)
 		case "help":
 			b.handleHelp(update.Message)
+		case "":
+			// Plain text is an expense entry; documents are handled below
+			if update.Message.Text != "" {
+				b.handleEntry(update.Message)
+			}
 		default:
 			b.handleUnknownCommand(update.Message)
 		}
 
-		// Handle file uploads
-		if update.Message.Document != nil {
+		// Photos and files sent in reply to an expense confirmation are its
+		// receipts; other photos are read for a fiscal receipt QR code, and
+		// other documents are CSV imports
+		if id := repliedTransactionID(update.Message); id != "" && (update.Message.Photo != nil || update.Message.Document != nil) {
+			b.handleReceiptUpload(update.Message, id)
+		} else if update.Message.Photo != nil || isImageDocument(update.Message.Document) {
+			b.handleReceiptPhoto(update.Message)
+		} else if update.Message.Document != nil {
 			b.handleFileUpload(update.Message)
 		}
 	}
 }
 
+// handleCallback dispatches inline keyboard button presses.
+func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
+	if cb.Message == nil {
+		b.answerCallback(cb, "")
+		return
+	}
+	switch {
+	case strings.HasPrefix(cb.Data, trackSubscriptionPrefix):
+		b.trackSubscription(cb, strings.TrimPrefix(cb.Data, trackSubscriptionPrefix))
+	case strings.HasPrefix(cb.Data, expectAnomalyPrefix):
+		b.expectAnomaly(cb, strings.TrimPrefix(cb.Data, expectAnomalyPrefix))
+	case strings.HasPrefix(cb.Data, fiscalPrefix):
+		b.addFiscal(cb, strings.TrimPrefix(cb.Data, fiscalPrefix))
+	default:
+		b.answerCallback(cb, "")
+	}
+}
+
 func (b *Bot) handleStart(msg *tgbotapi.Message) {
 	// Read monthly budget (runtime override if set, otherwise from environment)
-	monthlyBudget := b.getMonthlyBudget()
-	// Compute current month's daily allowance based on timezone
+	monthlyBudget := b.planner.MonthlyBudget()
+	// Compute current pay cycle's daily allowance based on timezone
 	now := time.Now().In(b.location)
-	lastOfMonth := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, b.location)
-	daysInMonth := lastOfMonth.Day()
-	dailyAllowance := monthlyBudget / float64(daysInMonth)
+	cycleStart, nextCycle := b.planner.Cycle(now)
+	cycleBudget := b.planner.CycleBudget(cycleStart, nextCycle, monthlyBudget)
+	dailyAllowance := cycleBudget / nextCycle.Sub(cycleStart).Hours() * 24
+	period := fmt.Sprintf("%s — %s", cycleStart.Format("Jan 2"), nextCycle.AddDate(0, 0, -1).Format("Jan 2"))
 
 	text := fmt.Sprintf(`Welcome to the Goofy Ahh Expenses Tracker! 🎉
 
 Budget settings:
 • Monthly budget: %.2f RUB
-• Daily allowance this month (%s): %.2f RUB
+• Pay cycles: %s
+• Cycle budget (%s): %.2f RUB
+• Average daily allowance: %.2f RUB
 
 Available commands:
 /start  — Show this message
 /report — Daily spending summary (use /report YYYY-MM-DD for a specific day)
 /saldo  — Today's saldo/allowance (also /saldo YYYY-MM-DD)
 /budget — Show or set monthly budget (e.g. /budget 15000, /budget reset)
+/recurring — List, add, pause or delete recurring charges
+/fixed  — Fixed costs reserved from this period's budget
+/profile — Allowance profile (even, weekend, custom day weights)
+/envelopes — Envelope balances (BUDGET_MODE=envelope)
+/move   — Move money between envelopes (e.g. /move 500 cafes groceries)
+/goals  — Savings goals (e.g. /goals add vacation 60000 2027-06-01)
+/subscriptions — Recurring payments spotted in your history
+/token  — API tokens for scripts (e.g. /token new write Shortcut)
+/category — Manage categories: add, edit, archive, merge
+/receipt — Receipts attached to expenses (reply to a confirmation with a photo to attach one)
 /csv    — Upload your CSV file
 /export — Download full CSV
 /help   — Help
 
-To add expenses, use the mini app by clicking the button below.`, monthlyBudget, now.Format("Jan 2006"), dailyAllowance)
+To add expenses, use the mini app by clicking the button below, or send a message like "350 dining lunch #kazan", or a photo of a receipt QR code.`, monthlyBudget, b.planner.Cycles().Describe(), period, cycleBudget, dailyAllowance)
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
@@ -152,58 +282,27 @@ func (b *Bot) handleDailyReport(msg *tgbotapi.Message) {
 		dateStr = selectedDate.Format("2006-01-02")
 	}
 
-	// Today's transactions and total
-	transactions := b.data.GetTransactionsByDate(dateStr)
-	var todayTotal float64
-	for _, tx := range transactions {
-		todayTotal += tx.Amount
-	}
+	// Budget status within the pay cycle (salary day); fixed costs are reserved up front
+	st := b.planner.Status(selectedDate, b.planner.MonthlyBudget())
 
-	// Monthly budget (runtime override if set, else from env)
-	monthlyBudget := b.getMonthlyBudget()
-
-    // Compute pay-cycle boundaries (salary day)
-    cycleStart, nextCycleStart := b.getCycleStartAndNext(selectedDate)
-    daysInCycle := int(nextCycleStart.Sub(cycleStart).Hours()/24)
-    if daysInCycle <= 0 { daysInCycle = 1 }
-    dayIndex := int(selectedDate.Sub(cycleStart).Hours()/24) + 1
-    if dayIndex < 1 { dayIndex = 1 }
-
-    // Sum spent in cycle up to and including selected date
-    var spentThroughToday float64
-    for _, tx := range b.data.GetAllTransactions() {
-        d, err := time.ParseInLocation("2006-01-02", tx.Date, b.location)
-        if err != nil { continue }
-        if (d.Equal(cycleStart) || d.After(cycleStart)) && (d.Equal(selectedDate) || d.Before(selectedDate)) {
-            spentThroughToday += tx.Amount
-        }
-    }
-
-    // Even distribution across cycle
-    allowedCumulative := monthlyBudget * (float64(dayIndex) / float64(daysInCycle))
-    saldoToday := allowedCumulative - spentThroughToday
-
-    // Tomorrow's allowance (dynamic) within cycle
-    remainingDaysAfterToday := int(nextCycleStart.Sub(selectedDate).Hours()/24) - 1
-    var tomorrowAllowance float64
-    if remainingDaysAfterToday > 0 {
-        remainingBudgetAfterToday := monthlyBudget - spentThroughToday
-        if remainingBudgetAfterToday < 0 { remainingBudgetAfterToday = 0 }
-        tomorrowAllowance = remainingBudgetAfterToday / float64(remainingDaysAfterToday)
-    }
-
-    var report strings.Builder
-    periodStart := cycleStart.Format("2006-01-02")
-    periodEnd := nextCycleStart.AddDate(0,0,-1).Format("2006-01-02")
-    report.WriteString(fmt.Sprintf("📊 %s\n", dateStr))
-    report.WriteString(fmt.Sprintf("📅 Period: %s — %s\n", periodStart, periodEnd))
-	report.WriteString(fmt.Sprintf("💰 Today: %.2f RUB\n", todayTotal))
-	report.WriteString(fmt.Sprintf("🎯 Saldo today: %.2f RUB\n", saldoToday))
-	if remainingDaysAfterToday > 0 {
-		report.WriteString(fmt.Sprintf("➡️ Tomorrow: %.2f RUB\n", tomorrowAllowance))
-	}
-	if saldoToday < 0 {
-		report.WriteString("⚠️ Over track for the month.")
+	var report strings.Builder
+	periodStart := st.CycleStart.Format("2006-01-02")
+	periodEnd := st.NextCycleStart.AddDate(0, 0, -1).Format("2006-01-02")
+	report.WriteString(fmt.Sprintf("📊 %s\n", dateStr))
+	report.WriteString(fmt.Sprintf("📅 Period: %s — %s\n", periodStart, periodEnd))
+	report.WriteString(fmt.Sprintf("💰 Today: %.2f RUB%s\n", st.TodayTotal, fixedNote(st.TodayFixed)))
+	writeFixedSummary(&report, st)
+	report.WriteString(fmt.Sprintf("🎯 Saldo today: %.2f RUB\n", st.Saldo))
+	if st.RemainingDays > 0 {
+		report.WriteString(fmt.Sprintf("➡️ Tomorrow: %.2f RUB%s\n", st.Tomorrow, profileNote(st)))
+	}
+	b.writeCommitted(&report, selectedDate, st.NextCycleStart)
+	writeForecast(&report, b.planner.Forecast(selectedDate, b.planner.MonthlyBudget()))
+	if b.planner.Settings().Envelopes() {
+		b.writeEnvelopes(&report, selectedDate)
+	}
+	if st.Saldo < 0 {
+		report.WriteString("⚠️ Over track for the pay cycle.")
 	} else {
 		report.WriteString("✅ On track.")
 	}
@@ -212,17 +311,11 @@ func (b *Bot) handleDailyReport(msg *tgbotapi.Message) {
 	message := tgbotapi.NewMessage(msg.Chat.ID, report.String())
 	b.api.Send(message)
 
-	// Also send full CSV export with all expenses across all months, sorted by date desc
-	all := b.getAllTransactionsSortedDesc()
-	var sb strings.Builder
-	sb.WriteString("Date,Category,Description,Amount\n")
-	for _, tx := range all {
-		sb.WriteString(fmt.Sprintf("%s,%s,%s,%.2f\n", tx.Date, tx.Category, strings.ReplaceAll(tx.Description, ",", " "), tx.Amount))
-	}
-	doc := tgbotapi.FileBytes{Name: "expenses.csv", Bytes: []byte(sb.String())}
-	msgDoc := tgbotapi.NewDocument(msg.Chat.ID, doc)
-	b.api.Send(msgDoc)
+	// Charts of the cycle so far
+	b.sendCharts(msg.Chat.ID, b.cycleCharts(st)...)
 
+	// Also send full CSV export with all expenses across all months, sorted by date desc
+	b.sendExport(msg.Chat.ID)
 }
 
 // handleBudget allows runtime override of monthly budget without changing .env
@@ -237,8 +330,8 @@ func (b *Bot) handleBudget(msg *tgbotapi.Message) {
 	// Show current
 	if len(parts) == 1 || (len(parts) == 2 && parts[1] == "show") {
 		source := "env (.env)"
-		val := b.getMonthlyBudget()
-		if b.hasMonthlyBudgetOverride {
+		val := b.planner.MonthlyBudget()
+		if b.planner.MonthlyBudgetOverridden() {
 			source = "runtime override (resets on restart)"
 		}
 		reply := fmt.Sprintf("Current monthly budget: %.2f RUB\nSource: %s\n\nTo change: /budget <amount> (e.g., /budget 15000)\nTo reset to .env: /budget reset", val, source)
@@ -248,9 +341,8 @@ func (b *Bot) handleBudget(msg *tgbotapi.Message) {
 
 	// Reset
 	if len(parts) == 2 && strings.EqualFold(parts[1], "reset") {
-		b.hasMonthlyBudgetOverride = false
-		b.monthlyBudgetOverride = 0
-		reply := fmt.Sprintf("✅ Reset. Using .env MONTHLY_BUDGET_RUB = %.2f RUB", b.getMonthlyBudget())
+		b.planner.SetMonthlyBudget(0)
+		reply := fmt.Sprintf("✅ Reset. Using .env MONTHLY_BUDGET_RUB = %.2f RUB", b.planner.MonthlyBudget())
 		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, reply))
 		return
 	}
@@ -264,8 +356,7 @@ func (b *Bot) handleBudget(msg *tgbotapi.Message) {
 			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Invalid amount. Use: /budget 15000"))
 			return
 		}
-		b.monthlyBudgetOverride = val
-		b.hasMonthlyBudgetOverride = true
+		b.planner.SetMonthlyBudget(val)
 		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Monthly budget set to %.2f RUB (runtime override)", val)))
 		return
 	}
@@ -303,12 +394,34 @@ Commands:
 • /report YYYY-MM-DD - Get spending summary for a specific date
 • /saldo - Show today's saldo/allowance
 • /saldo YYYY-MM-DD - Saldo for a specific date
+• /month [YYYY-MM] - Month summary: categories, comparisons, top merchants, largest expenses
+• /cycle [N] - Same for a pay cycle, N cycles back (0 is the current one)
+• /year [YYYY] - Same for a year
+• Add a category or #tags to a summary to narrow it down, e.g. /month 2025-08 food #kazan
+• /heatmap [YYYY-MM|YYYY] - When you spend: by weekday and hour of day, from expenses with a time
 • /budget - Show current monthly budget and how it's sourced
 • /budget <amount> - Set runtime budget override (resets on restart)
 • /budget reset - Reset override to use .env value
+• /recurring - List recurring charges (rent, subscriptions)
+• /recurring add monthly:5 25000 rent [description] - Add a recurring charge (also weekly:mon, every:14)
+• /recurring pause|resume|delete <id> - Manage a recurring charge
+• /fixed - Fixed costs (rent, bills) reserved from this period's budget
+• /profile [name|reset] - Show or switch the allowance profile (weights weekends and holidays)
+• /envelopes [set|delete] - Envelope balances and setup (BUDGET_MODE=envelope)
+• /move <amount> <from> <to> - Move money between envelopes
+• /goals [add|put|delete] - Savings goals funded by manual contributions and cycle surplus
+• /subscriptions - Suspected subscriptions found in your history, with one-tap tracking
+• /token [new|revoke] - API tokens (read, write or admin) for scripts and shortcuts
+• /category [add|set|alias|archive|delete|merge] - Managed categories with emoji, color, parent and aliases
+• /receipt [id|delete id] - Receipt photos and PDFs attached to expenses
 • /csv - Upload your expense data
 • /help - This help message
 
+Adding expenses:
+• Send "<amount> <category> [description] [#tags]", e.g. 450 food/cafes latte #kazan
+• Reply to an "Expense added" message with a photo or PDF to attach the receipt
+• Send a photo of a Russian fiscal receipt: its QR code fills in the date, time and total, you pick the category; each receipt can be entered once
+
 Features:
 • Track daily expenses
 • Calculate daily budget
@@ -341,132 +454,59 @@ func (b *Bot) handleSaldo(msg *tgbotapi.Message) {
 		dateStr = selectedDate.Format("2006-01-02")
 	}
 
-	// Today's total
-	todayTx := b.data.GetTransactionsByDate(dateStr)
-	var todayTotal float64
-	for _, tx := range todayTx {
-		todayTotal += tx.Amount
-	}
-
 	// Monthly budget (runtime override if set, else from env)
-	monthlyBudget := b.getMonthlyBudget()
-
-    // Cycle stats
-    cycleStart, nextCycleStart := b.getCycleStartAndNext(selectedDate)
-    daysInCycle := int(nextCycleStart.Sub(cycleStart).Hours()/24)
-    if daysInCycle <= 0 { daysInCycle = 1 }
-    dayIndex := int(selectedDate.Sub(cycleStart).Hours()/24) + 1
-    if dayIndex < 1 { dayIndex = 1 }
-
-    var spentThroughToday float64
-    for _, tx := range b.data.GetAllTransactions() {
-        d, err := time.ParseInLocation("2006-01-02", tx.Date, b.location)
-        if err != nil { continue }
-        if (d.Equal(cycleStart) || d.After(cycleStart)) && (d.Equal(selectedDate) || d.Before(selectedDate)) {
-            spentThroughToday += tx.Amount
-        }
-    }
-
-    allowedCumulative := monthlyBudget * (float64(dayIndex) / float64(daysInCycle))
-    saldoToday := allowedCumulative - spentThroughToday
-
-    remainingDaysAfterToday := int(nextCycleStart.Sub(selectedDate).Hours()/24) - 1
-    var tomorrowAllowance float64
-    if remainingDaysAfterToday > 0 {
-        remainingBudgetAfterToday := monthlyBudget - spentThroughToday
-        if remainingBudgetAfterToday < 0 { remainingBudgetAfterToday = 0 }
-        tomorrowAllowance = remainingBudgetAfterToday / float64(remainingDaysAfterToday)
-    }
+	st := b.planner.Status(selectedDate, b.planner.MonthlyBudget())
 
 	// Compose concise response
-    var sb strings.Builder
-    periodStart := cycleStart.Format("2006-01-02")
-    periodEnd := nextCycleStart.AddDate(0,0,-1).Format("2006-01-02")
-    sb.WriteString(fmt.Sprintf("📅 %s\n", dateStr))
-    sb.WriteString(fmt.Sprintf("📅 Period: %s — %s\n", periodStart, periodEnd))
-	sb.WriteString(fmt.Sprintf("💳 Spent today: %.2f RUB\n", todayTotal))
-    sb.WriteString(fmt.Sprintf("🎯 Allowed so far (cycle): %.2f RUB\n", allowedCumulative))
-	sb.WriteString(fmt.Sprintf("💸 Saldo today: %.2f RUB\n", saldoToday))
-	if remainingDaysAfterToday > 0 {
-		sb.WriteString(fmt.Sprintf("➡️ Tomorrow allowance: %.2f RUB", tomorrowAllowance))
-	}
+	var sb strings.Builder
+	periodStart := st.CycleStart.Format("2006-01-02")
+	periodEnd := st.NextCycleStart.AddDate(0, 0, -1).Format("2006-01-02")
+	sb.WriteString(fmt.Sprintf("📅 %s\n", dateStr))
+	sb.WriteString(fmt.Sprintf("📅 Period: %s — %s\n", periodStart, periodEnd))
+	sb.WriteString(fmt.Sprintf("💳 Spent today: %.2f RUB%s\n", st.TodayTotal, fixedNote(st.TodayFixed)))
+	writeFixedSummary(&sb, st)
+	sb.WriteString(fmt.Sprintf("🎯 Allowed so far (cycle): %.2f RUB%s\n", st.Allowed, profileNote(st)))
+	sb.WriteString(fmt.Sprintf("💸 Saldo today: %.2f RUB\n", st.Saldo))
+	if st.RemainingDays > 0 {
+		sb.WriteString(fmt.Sprintf("➡️ Tomorrow allowance: %.2f RUB%s\n", st.Tomorrow, profileNote(st)))
+	}
+	b.writeCommitted(&sb, selectedDate, st.NextCycleStart)
 
 	b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, sb.String()))
 }
 func (b *Bot) handleExport(msg *tgbotapi.Message) {
 	// stream current CSV data back to the user, sorted by date desc
-	all := b.getAllTransactionsSortedDesc()
-	var sb strings.Builder
-	sb.WriteString("Date,Category,Description,Amount\n")
-	for _, tx := range all {
-		sb.WriteString(fmt.Sprintf("%s,%s,%s,%.2f\n", tx.Date, tx.Category, strings.ReplaceAll(tx.Description, ",", " "), tx.Amount))
+	b.sendExport(msg.Chat.ID)
+}
+
+// sendExport sends all transactions as a CSV document in the same format as the data file.
+func (b *Bot) sendExport(chatID int64) {
+	var buf bytes.Buffer
+	if err := data.WriteCSV(&buf, b.getAllTransactionsSortedDesc()); err != nil {
+		log.Printf("Failed to build CSV export: %v", err)
+		b.api.Send(tgbotapi.NewMessage(chatID, "❌ Failed to build CSV export"))
+		return
 	}
-	doc := tgbotapi.FileBytes{Name: "expenses.csv", Bytes: []byte(sb.String())}
-	msgDoc := tgbotapi.NewDocument(msg.Chat.ID, doc)
-	b.api.Send(msgDoc)
+	doc := tgbotapi.FileBytes{Name: "expenses.csv", Bytes: buf.Bytes()}
+	b.api.Send(tgbotapi.NewDocument(chatID, doc))
 }
 
 // getAllTransactionsSortedDesc returns all transactions sorted by date descending (newest first)
 func (b *Bot) getAllTransactionsSortedDesc() []data.Transaction {
 	all := b.data.GetAllTransactions()
-	sort.Slice(all, func(i, j int) bool {
-		// parse to time for robust sort; fallback to string compare on error
-		ti, errI := time.Parse("2006-01-02", all[i].Date)
-		tj, errJ := time.Parse("2006-01-02", all[j].Date)
-		if errI == nil && errJ == nil {
-			return ti.After(tj)
-		}
-		return all[i].Date > all[j].Date
-	})
+	sort.SliceStable(all, func(i, j int) bool { return data.Chronological(all[i], all[j]) > 0 })
 	return all
 }
 
-// getCycleStartAndNext returns the start of the current salary cycle and the next cycle start.
-// Cycle starts on SALARY_DAY (1..28, default 15). Example: if SALARY_DAY=15 and selectedDate=2025-08-09,
-// cycleStart=2025-07-15, nextCycleStart=2025-08-15.
-func (b *Bot) getCycleStartAndNext(selectedDate time.Time) (time.Time, time.Time) {
-    salaryDay := 15
-    if s := os.Getenv("SALARY_DAY"); s != "" {
-        if v, err := strconv.Atoi(s); err == nil && v >= 1 && v <= 28 {
-            salaryDay = v
-        }
-    }
-
-    year, month, day := selectedDate.Date()
-    // Determine current cycle start
-    var cycleStart time.Time
-    if day >= salaryDay {
-        cycleStart = time.Date(year, month, salaryDay, 0, 0, 0, 0, b.location)
-    } else {
-        prev := selectedDate.AddDate(0, -1, 0)
-        cycleStart = time.Date(prev.Year(), prev.Month(), salaryDay, 0, 0, 0, 0, b.location)
-    }
-    // Next cycle start is salaryDay of next month from cycleStart
-    next := cycleStart.AddDate(0, 1, 0)
-    return cycleStart, next
-}
-
-// getMonthlyBudget returns runtime override if present, otherwise the .env value (default 12000)
-func (b *Bot) getMonthlyBudget() float64 {
-	if b.hasMonthlyBudgetOverride && b.monthlyBudgetOverride > 0 {
-		return b.monthlyBudgetOverride
-	}
-	monthlyBudget := 12000.0
-	if mbStr := os.Getenv("MONTHLY_BUDGET_RUB"); mbStr != "" {
-		if v, err := strconv.ParseFloat(mbStr, 64); err == nil && v > 0 {
-			monthlyBudget = v
-		}
-	}
-	return monthlyBudget
-}
-
 func (b *Bot) handleUnknownCommand(msg *tgbotapi.Message) {
 	text := `❓ Unknown command. Type /help for available commands.`
 	message := tgbotapi.NewMessage(msg.Chat.ID, text)
 	b.api.Send(message)
 }
 
-// HandleWebAppData processes data from the Telegram Mini App
+// HandleWebAppData processes data from the Telegram Mini App, sent with tg.sendData
+// or forwarded by the web server. A submission with an idempotency_key is applied
+// once within idempotency.Window: repeats neither save nor confirm again.
 func (b *Bot) HandleWebAppData(chatID int64, payload string) error {
 	var txData TransactionData
 	if err := json.Unmarshal([]byte(payload), &txData); err != nil {
@@ -474,7 +514,13 @@ func (b *Bot) HandleWebAppData(chatID int64, payload string) error {
 	}
 
 	// Validate the transaction data
-	if txData.Date == "" {
+	var at time.Time
+	if txData.Time != "" {
+		var err error
+		if at, err = time.Parse(data.TimeLayout, txData.Time); err != nil {
+			return fmt.Errorf("invalid time, expected RFC 3339")
+		}
+	} else if txData.Date == "" {
 		return fmt.Errorf("date is required")
 	}
 	if txData.Category == "" {
@@ -484,31 +530,76 @@ func (b *Bot) HandleWebAppData(chatID int64, payload string) error {
 		return fmt.Errorf("amount must be positive")
 	}
 
-	// Create transaction
-	tx := Transaction(txData)
-
-	// Add to database using the data package's AddTransaction method
-	// We'll pass the fields directly to avoid type conversion issues
-	if err := b.data.AddTransaction(data.Transaction{
-		Date:        tx.Date,
-		Category:    tx.Category,
-		Description: tx.Description,
-		Amount:      tx.Amount,
-	}); err != nil {
-		return fmt.Errorf("failed to save transaction: %w", err)
+	tx := data.Transaction{
+		Date:        txData.Date,
+		Category:    txData.Category,
+		Description: txData.Description,
+		Amount:      txData.Amount,
+		Fixed:       txData.Fixed,
+		Payer:       txData.Payer,
+		Tags:        txData.Tags,
+		Splits:      txData.Splits,
+		Time:        at,
+	}
+	if err := tx.ValidateSplits(); err != nil {
+		return err
+	}
+	save := func() (string, error) {
+		stored, err := b.data.CreateTransaction(tx)
+		if err != nil {
+			return "", fmt.Errorf("failed to save transaction: %w", err)
+		}
+		b.confirmTransaction(chatID, stored)
+		result, err := json.Marshal(stored)
+		return string(result), err
 	}
+	if txData.IdempotencyKey == "" {
+		_, err := save()
+		return err
+	}
+	_, replayed, err := b.idempotency.Do(txData.IdempotencyKey, time.Now(), save)
+	if replayed {
+		log.Printf("Ignoring repeated web app submission %s", txData.IdempotencyKey)
+	}
+	return err
+}
 
-	// Send confirmation message
-	text := fmt.Sprintf("✅ Expense added!\n\n📅 Date: %s\n🏷️ Category: %s", tx.Date, tx.Category)
+// confirmTransaction tells the chat that an expense was added.
+func (b *Bot) confirmTransaction(chatID int64, tx data.Transaction) {
+	label := tx.Category
+	if c, ok := b.categories.Resolve(tx.Category); ok {
+		label = fmt.Sprintf("%s (%s)", c.Label(), b.categories.Path(c.ID))
+	}
+	date := tx.Date
+	if !tx.Time.IsZero() {
+		date += tx.Time.In(b.location).Format(" 15:04")
+	}
+	text := fmt.Sprintf("✅ Expense added!\n\n📅 Date: %s\n🏷️ Category: %s", date, label)
 	if tx.Description != "" {
 		text += fmt.Sprintf("\n📝 Description: %s", tx.Description)
 	}
+	if len(tx.Tags) > 0 {
+		text += "\n🔖 Tags: #" + strings.Join(tx.Tags, " #")
+	}
 	text += fmt.Sprintf("\n💰 Amount: %.2f RUB", tx.Amount)
+	for _, s := range tx.Splits {
+		text += fmt.Sprintf("\n   ↳ %s: %.2f RUB", s.Category, s.Amount)
+	}
+	if tx.Fixed {
+		text += "\n📌 Fixed cost (reserved from the cycle budget)"
+	}
+	text += fmt.Sprintf("\n%s %s\n\n📎 Reply with a photo or PDF to attach the receipt.", transactionIDLabel, tx.ID)
 
 	message := tgbotapi.NewMessage(chatID, text)
 	b.api.Send(message)
+}
 
-	return nil
+// handleWebAppMessage processes a web_app_data service message sent by tg.sendData.
+func (b *Bot) handleWebAppMessage(msg *tgbotapi.Message) {
+	if err := b.HandleWebAppData(msg.Chat.ID, msg.WebAppData.Data); err != nil {
+		log.Printf("Failed to process web app data: %v", err)
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to add expense: "+err.Error()))
+	}
 }
 
 func (b *Bot) handleFileUpload(msg *tgbotapi.Message) {
@@ -554,54 +645,42 @@ func (b *Bot) handleFileUpload(msg *tgbotapi.Message) {
 	}
 
 	// Validate header
-	expectedHeader := []string{"Date", "Category", "Description", "Amount"}
-	if len(records[0]) != 4 || !compareStringSlices(records[0], expectedHeader) {
-		response := tgbotapi.NewMessage(msg.Chat.ID, "❌ CSV header must be: Date,Category,Description,Amount")
+	columns, err := data.ParseHeader(records[0])
+	if err != nil {
+		response := tgbotapi.NewMessage(msg.Chat.ID, "❌ CSV header must be: Date,Category,Description,Amount (optionally followed by Fixed, Payer, ID, Tags, Splits, Fiscal and Time)")
 		b.api.Send(response)
 		return
 	}
 
 	// Process transactions
-	var transactions []Transaction
-	var errors []string
+	var transactions []data.Transaction
+	var invalid []string
 	var totalAmount float64
 
 	for i, record := range records[1:] {
-		if len(record) != 4 {
-			errors = append(errors, fmt.Sprintf("Line %d: Invalid number of fields", i+2))
-			continue
-		}
-
-		amount, err := strconv.ParseFloat(record[3], 64)
+		tx, err := columns.Parse(record, i+2)
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("Line %d: Invalid amount '%s'", i+2, record[3]))
+			invalid = append(invalid, err.Error())
 			continue
 		}
 
-		if amount <= 0 {
-			errors = append(errors, fmt.Sprintf("Line %d: Amount must be positive", i+2))
+		if tx.Amount <= 0 {
+			invalid = append(invalid, fmt.Sprintf("Line %d: Amount must be positive", i+2))
 			continue
 		}
 
-		tx := Transaction{
-			Date:        record[0],
-			Category:    record[1],
-			Description: record[2],
-			Amount:      amount,
-		}
-
 		transactions = append(transactions, tx)
-		totalAmount += amount
+		totalAmount += tx.Amount
 	}
 
 	// If there are validation errors, send them
-	if len(errors) > 0 {
+	if len(invalid) > 0 {
 		errorMsg := "❌ CSV validation failed:\n\n"
-		for _, err := range errors[:10] { // Limit to first 10 errors
+		for _, err := range invalid[:min(10, len(invalid))] { // Limit to first 10 errors
 			errorMsg += "• " + err + "\n"
 		}
-		if len(errors) > 10 {
-			errorMsg += fmt.Sprintf("\n... and %d more errors", len(errors)-10)
+		if len(invalid) > 10 {
+			errorMsg += fmt.Sprintf("\n... and %d more errors", len(invalid)-10)
 		}
 		response := tgbotapi.NewMessage(msg.Chat.ID, errorMsg)
 		b.api.Send(response)
@@ -609,23 +688,14 @@ func (b *Bot) handleFileUpload(msg *tgbotapi.Message) {
 	}
 
 	// Add all valid transactions
-	for _, tx := range transactions {
-		if err := b.data.AddTransaction(struct {
-			Date        string
-			Category    string
-			Description string
-			Amount      float64
-		}{
-			Date:        tx.Date,
-			Category:    tx.Category,
-			Description: tx.Description,
-			Amount:      tx.Amount,
-		}); err != nil {
-			log.Printf("Failed to save transaction: %v", err)
-			response := tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to save transactions")
-			b.api.Send(response)
-			return
-		}
+	if err := b.data.AddTransactions(transactions); errors.Is(err, data.ErrDuplicateReceipt) {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ The file repeats a fiscal receipt that has already been entered"))
+		return
+	} else if err != nil {
+		log.Printf("Failed to save transactions: %v", err)
+		response := tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to save transactions")
+		b.api.Send(response)
+		return
 	}
 
 	// Send success message
@@ -644,15 +714,3 @@ func (b *Bot) SendDailyReport() error {
 	log.Println("Daily report would be sent at this time")
 	return nil
 }
-
-func compareStringSlices(a, b []string) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i, v := range a {
-		if v != b[i] {
-			return false
-		}
-	}
-	return true
-}
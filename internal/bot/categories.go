@@ -0,0 +1,221 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/category"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const categoryUsage = `Usage:
+/category [all] — list categories (all includes archived ones)
+/category add [parent/]<id> <name> — add a category, e.g. dining/cafes Кафе
+/category set <id> name|emoji|color|parent <value> — change a field, "-" clears it
+/category alias <id> <spelling> — resolve another spelling to the category
+/category unalias <id> <spelling>
+/category archive|unarchive <id> — hide from or show in the Mini App
+/category delete <id> — delete an unused category
+/category merge <source,...> <target> — fold categories or spellings into target and rewrite history
+
+Example: /category merge Продукты,food groceries`
+
+// handleCategory manages the category list.
+// Usage:
+//
+//	/category                         -> list active categories
+//	/category add coffee Coffee       -> add a category
+//	/category set coffee parent dining
+//	/category merge food,еда groceries -> merge and rewrite history
+func (b *Bot) handleCategory(msg *tgbotapi.Message) {
+	parts := strings.Fields(msg.Text)
+	if len(parts) == 1 || (len(parts) == 2 && (parts[1] == "list" || parts[1] == "all")) {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, b.formatCategoryList(len(parts) == 2 && parts[1] == "all")))
+		return
+	}
+	reply := func(text string) { b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, text)) }
+
+	switch strings.ToLower(parts[1]) {
+	case "add", "new":
+		if len(parts) < 4 {
+			reply(categoryUsage)
+			return
+		}
+		// A path such as dining/cafes adds cafes below dining
+		id, parent := parts[2], ""
+		if i := strings.LastIndex(id, "/"); i >= 0 {
+			id, parent = id[i+1:], b.categories.Normalize(id[:i])
+		}
+		c, err := b.categories.Create(category.Category{ID: id, Name: strings.Join(parts[3:], " "), Parent: parent})
+		if err != nil {
+			reply("❌ " + err.Error())
+			return
+		}
+		reply(fmt.Sprintf("✅ Category %s added: %s", b.categories.Path(c.ID), c.Label()))
+	case "set":
+		if len(parts) < 5 {
+			reply(categoryUsage)
+			return
+		}
+		value := strings.Join(parts[4:], " ")
+		if value == "-" {
+			value = ""
+		}
+		b.updateCategory(msg.Chat.ID, parts[2], func(c *category.Category) error {
+			switch strings.ToLower(parts[3]) {
+			case "name":
+				c.Name = value
+			case "emoji":
+				c.Emoji = value
+			case "color":
+				c.Color = value
+			case "parent":
+				c.Parent = value
+			default:
+				return fmt.Errorf("unknown field %q: expected name, emoji, color or parent", parts[3])
+			}
+			return nil
+		})
+	case "alias":
+		if len(parts) < 4 {
+			reply(categoryUsage)
+			return
+		}
+		b.updateCategory(msg.Chat.ID, parts[2], func(c *category.Category) error {
+			c.Aliases = append(c.Aliases, strings.Join(parts[3:], " "))
+			return nil
+		})
+	case "unalias":
+		if len(parts) < 4 {
+			reply(categoryUsage)
+			return
+		}
+		alias := strings.ToLower(strings.Join(parts[3:], " "))
+		b.updateCategory(msg.Chat.ID, parts[2], func(c *category.Category) error {
+			for i, a := range c.Aliases {
+				if a == alias {
+					c.Aliases = append(c.Aliases[:i], c.Aliases[i+1:]...)
+					return nil
+				}
+			}
+			return fmt.Errorf("%q is not an alias of %s", alias, c.ID)
+		})
+	case "archive", "unarchive":
+		if len(parts) != 3 {
+			reply(categoryUsage)
+			return
+		}
+		archived := strings.ToLower(parts[1]) == "archive"
+		b.updateCategory(msg.Chat.ID, parts[2], func(c *category.Category) error {
+			c.Archived = archived
+			return nil
+		})
+	case "delete", "remove":
+		if len(parts) != 3 {
+			reply(categoryUsage)
+			return
+		}
+		id := strings.ToLower(parts[2])
+		if n := b.categoryUse(id); n > 0 {
+			reply(fmt.Sprintf("❌ %s is used by %d transactions. Merge it into another category or archive it instead.", id, n))
+			return
+		}
+		err := b.categories.Delete(id)
+		switch {
+		case errors.Is(err, category.ErrNotFound):
+			reply(fmt.Sprintf("❌ Category %s not found. Use /category all to see IDs.", id))
+		case errors.Is(err, category.ErrHasChildren):
+			reply(fmt.Sprintf("❌ %s has subcategories; move or delete them first.", id))
+		case err != nil:
+			reply("❌ Failed to delete category: " + err.Error())
+		default:
+			reply(fmt.Sprintf("✅ Category %s deleted", id))
+		}
+	case "merge":
+		if len(parts) != 4 {
+			reply(categoryUsage)
+			return
+		}
+		n, err := b.categories.Merge(b.data, strings.Split(parts[2], ","), parts[3], b.envelopes, b.templates)
+		if errors.Is(err, category.ErrNotFound) {
+			reply(fmt.Sprintf("❌ Category %s not found. Use /category all to see IDs.", parts[3]))
+			return
+		}
+		if err != nil {
+			reply("❌ Failed to merge: " + err.Error())
+			return
+		}
+		reply(fmt.Sprintf("✅ Merged %s into %s, %d transactions rewritten", parts[2], strings.ToLower(parts[3]), n))
+	default:
+		reply(categoryUsage)
+	}
+}
+
+// updateCategory applies change to the category with the given ID and reports the result.
+func (b *Bot) updateCategory(chatID int64, id string, change func(*category.Category) error) {
+	c, err := b.categories.Get(id)
+	if errors.Is(err, category.ErrNotFound) {
+		b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Category %s not found. Use /category all to see IDs.", id)))
+		return
+	}
+	if err == nil {
+		err = change(&c)
+	}
+	if err == nil {
+		c, err = b.categories.Update(c)
+	}
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+	b.api.Send(tgbotapi.NewMessage(chatID, "✅ "+formatCategory(c)))
+}
+
+// categoryUse counts the transactions in category id, by any of its spellings,
+// including those with a split line in it.
+func (b *Bot) categoryUse(id string) int {
+	n := 0
+	for _, tx := range b.data.GetAllTransactions() {
+		for _, p := range tx.Parts() {
+			if b.categories.Normalize(p.Category) == id {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+func (b *Bot) formatCategoryList(all bool) string {
+	categories := b.categories.List(all)
+	if len(categories) == 0 {
+		return "No categories yet.\n\n" + categoryUsage
+	}
+	depth := map[string]int{}
+	var sb strings.Builder
+	sb.WriteString("🏷️ Categories:\n")
+	for _, c := range categories {
+		if c.Parent != "" {
+			depth[c.ID] = depth[c.Parent] + 1
+		}
+		sb.WriteString(strings.Repeat("    ", depth[c.ID]) + formatCategory(c) + "\n")
+	}
+	return sb.String()
+}
+
+// formatCategory describes a category on one line, e.g.
+// "🛒 Groceries (groceries) #59a14f, also: продукты".
+func formatCategory(c category.Category) string {
+	line := fmt.Sprintf("%s (%s)", c.Label(), c.ID)
+	if c.Color != "" {
+		line += " " + c.Color
+	}
+	if c.Archived {
+		line += " [archived]"
+	}
+	if len(c.Aliases) > 0 {
+		line += ", also: " + strings.Join(c.Aliases, ", ")
+	}
+	return line
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/budget"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/chart"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/report"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendCharts renders charts as PNG and sends them as one album.
+func (b *Bot) sendCharts(chatID int64, charts ...chart.Chart) {
+	var media []interface{}
+	for i, ch := range charts {
+		img, err := chart.PNG(ch)
+		if err != nil {
+			log.Printf("Failed to render chart: %v", err)
+			return
+		}
+		media = append(media, tgbotapi.NewInputMediaPhoto(tgbotapi.FileBytes{Name: fmt.Sprintf("chart-%d.png", i+1), Bytes: img}))
+	}
+	if len(media) == 0 {
+		return
+	}
+	if _, err := b.api.SendMediaGroup(tgbotapi.NewMediaGroup(chatID, media)); err != nil {
+		log.Printf("Failed to send charts: %v", err)
+	}
+}
+
+// cycleCharts draws the pay cycle of st through st.Date: discretionary spend against
+// the allowance, daily discretionary spend and all spending by category.
+func (b *Bot) cycleCharts(st budget.Status) []chart.Chart {
+	settings := b.planner.Settings()
+	prof := b.planner.Profile()
+	fromStr, dateStr := st.CycleStart.Format("2006-01-02"), st.Date.Format("2006-01-02")
+	daily := map[string]float64{}
+	categories := map[string]float64{}
+	for _, tx := range b.data.GetAllTransactions() {
+		if tx.Date < fromStr || tx.Date > dateStr {
+			continue
+		}
+		for _, p := range tx.Parts() {
+			categories[strings.ToLower(p.Category)] += p.Amount
+		}
+		if !settings.IsFixed(tx) {
+			daily[tx.Date] += tx.Amount
+		}
+	}
+
+	var spent, allowed, bars []chart.Point
+	var cumulative float64
+	for d := st.CycleStart; d.Before(st.NextCycleStart); d = d.AddDate(0, 0, 1) {
+		label := d.Format("2006-01-02")
+		allowed = append(allowed, chart.Point{Label: label, Value: st.Discretionary * prof.Share(st.CycleStart, d, st.NextCycleStart)})
+		if label <= dateStr {
+			cumulative += daily[label]
+			spent = append(spent, chart.Point{Label: label, Value: cumulative})
+			bars = append(bars, chart.Point{Label: label, Value: daily[label]})
+		}
+	}
+
+	period := fmt.Sprintf("%s — %s", fromStr, st.NextCycleStart.AddDate(0, 0, -1).Format("2006-01-02"))
+	return []chart.Chart{
+		chart.Cumulative{Title: "Spent vs allowance, " + period, Spent: spent, Budget: allowed},
+		chart.Bars{Title: "Daily spend, " + period, Bars: bars, Limit: st.Discretionary / float64(st.DaysInCycle)},
+		chart.Pie{Title: "By category, " + period, Slices: categoryPoints(categories)},
+	}
+}
+
+// reportCharts draws a period summary: all spending by category and per day, or per
+// month for a year.
+func (b *Bot) reportCharts(r report.Report) []chart.Chart {
+	categories := map[string]float64{}
+	for _, c := range r.Categories {
+		categories[c.Category] = c.Amount
+	}
+
+	from, errFrom := time.Parse("2006-01-02", r.From)
+	to, errTo := time.Parse("2006-01-02", r.To)
+	if errFrom != nil || errTo != nil {
+		return []chart.Chart{chart.Pie{Title: "By category, " + r.Label, Slices: categoryPoints(categories)}}
+	}
+	layout, step, name := "2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }, "Daily spend, "
+	if r.Kind == report.KindYear {
+		layout, step, name = "2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }, "Monthly spend, "
+	}
+	totals := map[string]float64{}
+	for _, tx := range b.data.GetAllTransactions() {
+		if tx.Date >= r.From && tx.Date <= r.To && len(tx.Date) >= len(layout) {
+			totals[tx.Date[:len(layout)]] += tx.Amount
+		}
+	}
+	var bars []chart.Point
+	for d := from; !d.After(to); d = step(d) {
+		bars = append(bars, chart.Point{Label: d.Format(layout), Value: totals[d.Format(layout)]})
+	}
+
+	return []chart.Chart{
+		chart.Pie{Title: "By category, " + r.Label, Slices: categoryPoints(categories)},
+		chart.Bars{Title: name + r.Label, Bars: bars},
+	}
+}
+
+func categoryPoints(totals map[string]float64) []chart.Point {
+	points := make([]chart.Point, 0, len(totals))
+	for name, amount := range totals {
+		points = append(points, chart.Point{Label: name, Value: amount})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Label < points[j].Label })
+	return points
+}
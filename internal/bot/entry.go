@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const entryUsage = `To add an expense, send: <amount> <category> [description] [#tags]
+Examples:
+350 dining lunch with Lev
+1200 продукты Пятёрочка #kazan
+450 food/cafes latte #trip #kazan
+
+To split a receipt, give category:amount lines instead of one category; the
+last line may leave its amount out to take the rest:
+1200 groceries:800 household:400 Auchan
+1200 groceries:800 household: Auchan
+
+Categories may be paths like food/cafes; see /category for the managed ones.`
+
+// handleEntry adds an expense typed as a plain chat message, dated today.
+func (b *Bot) handleEntry(msg *tgbotapi.Message) {
+	tx, err := parseEntry(msg.Text)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❓ Could not read an expense: "+err.Error()+"\n\n"+entryUsage))
+		return
+	}
+	tx.Time = time.Now().In(b.location).Truncate(time.Second)
+	if msg.From != nil {
+		tx.Payer = msg.From.FirstName
+	}
+	stored, err := b.data.CreateTransaction(tx)
+	if err != nil {
+		log.Printf("Failed to save chat entry: %v", err)
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to add expense. Please try again."))
+		return
+	}
+	b.confirmTransaction(msg.Chat.ID, stored)
+}
+
+// parseEntry reads "<amount> <category> [description] [#tags]"; the #tags may
+// appear anywhere and the amount may use a decimal comma.
+func parseEntry(text string) (data.Transaction, error) {
+	text, tags := data.ExtractTags(text)
+	parts := strings.Fields(text)
+	if len(parts) < 2 {
+		return data.Transaction{}, errors.New("expected an amount and a category")
+	}
+	amount, err := strconv.ParseFloat(strings.Replace(parts[0], ",", ".", 1), 64)
+	if err != nil || amount <= 0 {
+		return data.Transaction{}, fmt.Errorf("%q is not a positive amount", parts[0])
+	}
+	tx := data.Transaction{Category: parts[1], Amount: amount, Tags: tags}
+	rest := parts[2:]
+	if strings.Contains(parts[1], ":") {
+		if tx.Splits, rest, err = parseSplitLines(parts[1:], amount); err != nil {
+			return data.Transaction{}, err
+		}
+		tx.Category = tx.Splits[0].Category
+	}
+	tx.Description = strings.Join(rest, " ")
+	return tx, tx.ValidateSplits()
+}
+
+// parseSplitLines reads the leading category:amount words of parts and returns
+// the split lines and the words after them. An empty amount on the last line
+// takes what is left of total.
+func parseSplitLines(parts []string, total float64) ([]data.Split, []string, error) {
+	var splits []data.Split
+	sum, n := 0.0, 0
+	for ; n < len(parts); n++ {
+		i := strings.LastIndex(parts[n], ":")
+		if i <= 0 {
+			break
+		}
+		line := data.Split{Category: parts[n][:i]}
+		if v := parts[n][i+1:]; v != "" {
+			amount, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%q is not a split amount", v)
+			}
+			line.Amount = amount
+		} else {
+			line.Amount = math.Round((total-sum)*100) / 100
+		}
+		sum += line.Amount
+		splits = append(splits, line)
+		if parts[n][i+1:] == "" {
+			n++
+			break
+		}
+	}
+	return splits, parts[n:], nil
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseEntryTags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		text            string
+		wantCategory    string
+		wantDescription string
+		wantTags        []string
+	}{
+		{"no tags", "350 dining lunch with Lev", "dining", "lunch with Lev", nil},
+		{"tags mixed with the description", "1200 продукты #Kazan Пятёрочка #trip у дома", "продукты", "Пятёрочка у дома", []string{"kazan", "trip"}},
+		{"tag before the category", "450 #kazan food/cafes latte", "food/cafes", "latte", []string{"kazan"}},
+		{"lone #", "350 dining lunch # 2", "dining", "lunch # 2", nil},
+		{"duplicate tags", "350 dining #kazan lunch #KAZAN #kazan!", "dining", "lunch", []string{"kazan"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := parseEntry(tt.text)
+			if err != nil {
+				t.Fatalf("parseEntry(%q) error = %v", tt.text, err)
+			}
+			if tx.Amount <= 0 || tx.Category != tt.wantCategory || tx.Description != tt.wantDescription {
+				t.Errorf("parseEntry(%q) = %.2f %q %q, want %q %q", tt.text, tx.Amount, tx.Category, tx.Description, tt.wantCategory, tt.wantDescription)
+			}
+			if got, want := strings.Join(tx.Tags, ","), strings.Join(tt.wantTags, ","); got != want {
+				t.Errorf("parseEntry(%q) tags = %q, want %q", tt.text, got, want)
+			}
+		})
+	}
+
+	// Tags alone are not a category.
+	if _, err := parseEntry("350 #kazan"); err == nil {
+		t.Error(`parseEntry("350 #kazan") succeeded, want an error`)
+	}
+}
+
+func TestParseSplitLines(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		text            string
+		wantSplits      string // category:amount lines, joined by spaces
+		wantDescription string
+		wantErr         string
+	}{
+		{name: "amounts given", text: "1200 groceries:800 household:400 Auchan", wantSplits: "groceries:800 household:400", wantDescription: "Auchan"},
+		{name: "last line takes the rest", text: "1200 groceries:800,50 household: Auchan у дома", wantSplits: "groceries:800.5 household:399.5", wantDescription: "Auchan у дома"},
+		{name: "category paths", text: "1000 food/cafes:300 food/groceries:700", wantSplits: "food/cafes:300 food/groceries:700"},
+		{name: "less than the total", text: "1200 groceries:800 household:300 Auchan", wantErr: "add up"},
+		{name: "more than the total", text: "1200 groceries:800 household:500", wantErr: "add up"},
+		{name: "nothing left for the last line", text: "1000 groceries:800 household:400 pets:", wantErr: "positive"},
+		{name: "bad amount", text: "1200 groceries:800 household:четыреста", wantErr: "not a split amount"},
+		{name: "single line", text: "1200 groceries:1200 Auchan", wantErr: "two lines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := parseEntry(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseEntry(%q) error = %v, want one containing %q", tt.text, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEntry(%q) error = %v", tt.text, err)
+			}
+			var lines []string
+			for _, s := range tx.Splits {
+				lines = append(lines, s.Category+":"+strconv.FormatFloat(s.Amount, 'f', -1, 64))
+			}
+			if got := strings.Join(lines, " "); got != tt.wantSplits || tx.Description != tt.wantDescription {
+				t.Errorf("parseEntry(%q) = %q, %q; want %q, %q", tt.text, got, tx.Description, tt.wantSplits, tt.wantDescription)
+			}
+			if tx.Category != tx.Splits[0].Category {
+				t.Errorf("category = %q, want the first line's %q", tx.Category, tx.Splits[0].Category)
+			}
+		})
+	}
+}
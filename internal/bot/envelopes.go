@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/envelope"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const envelopesUsage = `Usage:
+/envelopes — balances of all envelopes
+/envelopes set <name> <amount per cycle> [categories...]
+/envelopes delete <name>
+/move <amount> <from> <to> — move money between envelopes
+
+Without categories the envelope name is its category.
+Example: /envelopes set cafes 3000 dining coffee`
+
+const envelopeModeOff = "Envelope budgeting is off. Set BUDGET_MODE=envelope to enable it."
+
+// envelopeSummary returns envelope balances on date using the current monthly budget.
+func (b *Bot) envelopeSummary(date time.Time) envelope.Summary {
+	start, next := b.planner.Cycle(date)
+	return b.envelopes.Summary(b.data.GetAllTransactions(), b.planner.Cycle, date, b.planner.CycleBudget(start, next, b.planner.MonthlyBudget()))
+}
+
+// handleEnvelopes shows and manages envelopes.
+// Usage:
+//
+//	/envelopes                            -> balances
+//	/envelopes set cafes 3000 dining      -> add or update an envelope
+//	/envelopes delete cafes               -> remove an envelope
+func (b *Bot) handleEnvelopes(msg *tgbotapi.Message) {
+	if !b.planner.Settings().Envelopes() {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, envelopeModeOff))
+		return
+	}
+	parts := strings.Fields(msg.Text)
+	today := time.Now().In(b.location)
+	if len(parts) == 1 {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, formatEnvelopes(b.envelopeSummary(today))))
+		return
+	}
+
+	switch strings.ToLower(parts[1]) {
+	case "set", "add":
+		if len(parts) < 4 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, envelopesUsage))
+			return
+		}
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(parts[3], ",", "."), 64)
+		if err != nil || amount < 0 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Invalid amount. Example: /envelopes set groceries 6000"))
+			return
+		}
+		e, err := b.envelopes.Set(envelope.Envelope{
+			Name:       parts[2],
+			Allocation: amount,
+			Categories: parts[4:],
+			Start:      today.Format("2006-01-02"),
+		})
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to save envelope: "+err.Error()))
+			return
+		}
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Envelope %s: %.2f RUB per cycle", e.Name, e.Allocation)))
+	case "delete":
+		if len(parts) != 3 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, envelopesUsage))
+			return
+		}
+		if err := b.envelopes.Delete(parts[2]); err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ "+err.Error()))
+			return
+		}
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Envelope %s deleted", strings.ToLower(parts[2]))))
+	default:
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, envelopesUsage))
+	}
+}
+
+// handleMove moves money between envelopes: /move 500 cafes groceries
+func (b *Bot) handleMove(msg *tgbotapi.Message) {
+	if !b.planner.Settings().Envelopes() {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, envelopeModeOff))
+		return
+	}
+	parts := strings.Fields(msg.Text)
+	if len(parts) != 4 {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "Usage: /move <amount> <from> <to>\nExample: /move 500 cafes groceries"))
+		return
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(parts[1], ",", "."), 64)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Invalid amount. Example: /move 500 cafes groceries"))
+		return
+	}
+	today := time.Now().In(b.location)
+	err = b.envelopes.Move(envelope.Move{Date: today.Format("2006-01-02"), Amount: amount, From: parts[2], To: parts[3]})
+	if errors.Is(err, envelope.ErrNotFound) {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ %v. Use /envelopes to see envelopes.", err)))
+		return
+	}
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ "+err.Error()))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("✅ Moved %.2f RUB from %s to %s\n", amount, strings.ToLower(parts[2]), strings.ToLower(parts[3])))
+	for _, e := range b.envelopeSummary(today).Envelopes {
+		if e.Name == strings.ToLower(parts[2]) || e.Name == strings.ToLower(parts[3]) {
+			sb.WriteString(fmt.Sprintf("  • %s: %.2f RUB\n", e.Name, e.Balance))
+		}
+	}
+	b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, sb.String()))
+}
+
+// writeEnvelopes appends short envelope balances for the daily report.
+func (b *Bot) writeEnvelopes(sb *strings.Builder, date time.Time) {
+	sum := b.envelopeSummary(date)
+	if len(sum.Envelopes) == 0 {
+		return
+	}
+	sb.WriteString("✉️ Envelopes:\n")
+	for _, e := range sum.Envelopes {
+		sb.WriteString(fmt.Sprintf("  • %s: %.2f RUB\n", e.Name, e.Balance))
+	}
+	writeEnvelopeTotals(sb, sum)
+}
+
+func formatEnvelopes(sum envelope.Summary) string {
+	if len(sum.Envelopes) == 0 {
+		return "No envelopes yet.\n\n" + envelopesUsage
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("✉️ Envelopes (%s — %s):\n", sum.CycleStart.Format("2006-01-02"), sum.NextCycleStart.AddDate(0, 0, -1).Format("2006-01-02")))
+	for _, e := range sum.Envelopes {
+		mark := ""
+		if e.Balance < 0 {
+			mark = " ⚠️"
+		}
+		sb.WriteString(fmt.Sprintf("• %s: %.2f RUB left (spent %.2f this cycle, +%.2f per cycle)%s\n", e.Name, e.Balance, e.CycleSpent, e.Allocation, mark))
+	}
+	writeEnvelopeTotals(&sb, sum)
+	return sb.String()
+}
+
+// writeEnvelopeTotals appends unallocated budget and unassigned spending, if any.
+func writeEnvelopeTotals(sb *strings.Builder, sum envelope.Summary) {
+	if sum.Unallocated != 0 {
+		sb.WriteString(fmt.Sprintf("🧮 Unallocated this cycle: %.2f of %.2f RUB\n", sum.Unallocated, sum.Budget))
+	}
+	if sum.Unassigned > 0 {
+		sb.WriteString(fmt.Sprintf("❔ Spent outside envelopes this cycle: %.2f RUB\n", sum.Unassigned))
+	}
+}
@@ -0,0 +1,154 @@
+package bot
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/fiscal"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const fiscalPrefix = "fiscal:"
+
+// fiscalButtonsPerRow and maxFiscalCategories lay out the category picker.
+const (
+	fiscalButtonsPerRow = 3
+	maxFiscalCategories = 30
+)
+
+// pendingFiscal is a receipt read from a photo, awaiting its category.
+type pendingFiscal struct {
+	receipt fiscal.Receipt
+	photoID string // Telegram file ID, attached to the expense once added
+	payer   string
+}
+
+// fiscalID shortens a receipt key for callback data, which is limited to 64 bytes.
+func fiscalID(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])[:10]
+}
+
+// isImageDocument reports whether doc is a photo sent as a file.
+func isImageDocument(doc *tgbotapi.Document) bool {
+	return doc != nil && (doc.MimeType == "image/jpeg" || doc.MimeType == "image/png")
+}
+
+// handleReceiptPhoto reads the QR code of a Russian fiscal receipt from a photo
+// and offers to add its total as an expense once a category is picked.
+func (b *Bot) handleReceiptPhoto(msg *tgbotapi.Message) {
+	fileID := ""
+	if len(msg.Photo) > 0 {
+		fileID = msg.Photo[len(msg.Photo)-1].FileID
+	} else {
+		fileID = msg.Document.FileID
+	}
+	body, err := b.downloadFile(fileID)
+	if err != nil {
+		log.Printf("Failed to download receipt photo: %v", err)
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to download file"))
+		return
+	}
+	r, err := fiscal.Read(body)
+	body.Close()
+	if errors.Is(err, fiscal.ErrNoCode) {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❓ No receipt QR code found. Take a sharper photo of the code, or reply to an \"Expense added\" message to attach the photo as a receipt."))
+		return
+	} else if err != nil {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ "+err.Error()))
+		return
+	}
+	if r.Kind != fiscal.Purchase {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ This receipt is a refund or a payout, not a purchase; enter it by hand if needed."))
+		return
+	}
+	if tx, ok := b.data.FindFiscal(r.Key()); ok {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⚠️ This receipt was already entered: %s %s %.2f RUB\n%s %s", tx.Date, tx.Category, tx.Amount, transactionIDLabel, tx.ID)))
+		return
+	}
+
+	p := pendingFiscal{receipt: r, photoID: fileID}
+	if msg.From != nil {
+		p.payer = msg.From.FirstName
+	}
+	id := fiscalID(r.Key())
+	b.fiscalMu.Lock()
+	b.pendingFiscal[id] = p
+	b.fiscalMu.Unlock()
+
+	text := fmt.Sprintf("🧾 Receipt of %s\n💰 Amount: %.2f RUB\nФН %s, ФД %s, ФП %s\n\nPick a category to add it:",
+		r.Time.Format("2006-01-02 15:04"), r.Sum, r.FN, r.FD, r.FP)
+	message := tgbotapi.NewMessage(msg.Chat.ID, text)
+	message.ReplyMarkup = b.fiscalKeyboard(id)
+	b.api.Send(message)
+}
+
+// fiscalKeyboard lists the active categories, and a cancel button.
+func (b *Bot) fiscalKeyboard(id string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for i, c := range b.categories.List(false) {
+		if i == maxFiscalCategories {
+			break
+		}
+		cbData := fiscalPrefix + id + ":" + c.ID
+		if len(cbData) > 64 {
+			continue
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(c.Label(), cbData))
+		if len(row) == fiscalButtonsPerRow {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("✖️ Cancel", fiscalPrefix+id+":")))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// addFiscal adds the pending receipt named in the callback data "<id>:<category>";
+// an empty category cancels it.
+func (b *Bot) addFiscal(cb *tgbotapi.CallbackQuery, payload string) {
+	id, categoryID, _ := strings.Cut(payload, ":")
+	b.fiscalMu.Lock()
+	p, ok := b.pendingFiscal[id]
+	delete(b.pendingFiscal, id)
+	b.fiscalMu.Unlock()
+
+	chatID := cb.Message.Chat.ID
+	b.api.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, cb.Message.MessageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+	if !ok {
+		b.answerCallback(cb, "Receipt expired, send the photo again")
+		return
+	}
+	if categoryID == "" {
+		b.answerCallback(cb, "Cancelled")
+		return
+	}
+
+	stored, err := b.data.CreateTransaction(data.Transaction{
+		Time:     p.receipt.At(b.location),
+		Category: categoryID,
+		Amount:   p.receipt.Sum,
+		Payer:    p.payer,
+		Fiscal:   p.receipt.Key(),
+	})
+	if errors.Is(err, data.ErrDuplicateReceipt) {
+		b.answerCallback(cb, "Already entered")
+		return
+	} else if err != nil {
+		log.Printf("Failed to save receipt expense: %v", err)
+		b.answerCallback(cb, "❌ Failed to save")
+		return
+	}
+	b.answerCallback(cb, "✅ Added")
+	b.confirmTransaction(chatID, stored)
+	b.attachReceipt(chatID, stored, p.photoID, "receipt.jpg", "")
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/budget"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// writeFixedSummary appends the fixed costs reserved from the cycle budget and the
+// amount carried over from previous cycles, if any.
+func writeFixedSummary(sb *strings.Builder, st budget.Status) {
+	if st.Fixed <= 0 && st.Carry == 0 {
+		return
+	}
+	if st.Fixed > 0 {
+		sb.WriteString(fmt.Sprintf("📌 Fixed costs this period: %.2f RUB\n", st.Fixed))
+	}
+	if st.Carry != 0 {
+		sb.WriteString(fmt.Sprintf("🔄 Carried over: %+.2f RUB\n", st.Carry))
+	}
+	sb.WriteString(fmt.Sprintf("🧮 Discretionary budget: %.2f of %.2f RUB\n", st.Discretionary, st.Budget))
+}
+
+func fixedNote(amount float64) string {
+	if amount <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (incl. %.2f fixed)", amount)
+}
+
+// handleFixed lists the fixed costs of the current cycle and how fixed costs are recognized.
+func (b *Bot) handleFixed(msg *tgbotapi.Message) {
+	now := time.Now().In(b.location)
+	st := b.planner.Status(now, b.planner.MonthlyBudget())
+	settings := b.planner.Settings()
+	start := st.CycleStart.Format("2006-01-02")
+	end := st.NextCycleStart.AddDate(0, 0, -1).Format("2006-01-02")
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📌 Fixed costs %s — %s\n", start, end))
+	for _, tx := range b.getAllTransactionsSortedDesc() {
+		if settings.IsFixed(tx) && tx.Date >= start && tx.Date <= end {
+			sb.WriteString(fmt.Sprintf("  • %s %s %.2f RUB\n", tx.Date, txLabel(tx.Description, tx.Category), tx.Amount))
+		}
+	}
+	b.writeCommitted(&sb, now, st.NextCycleStart)
+	sb.WriteString(fmt.Sprintf("Total reserved: %.2f RUB\nDiscretionary budget: %.2f of %.2f RUB\n", st.Fixed, st.Discretionary, st.Budget))
+
+	categories := make([]string, 0, len(settings.FixedCategories))
+	for c := range settings.FixedCategories {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+	if len(categories) > 0 {
+		sb.WriteString("\nFixed categories (FIXED_CATEGORIES): " + strings.Join(categories, ", "))
+	} else {
+		sb.WriteString("\nNo fixed categories configured (FIXED_CATEGORIES).")
+	}
+	sb.WriteString("\nRecurring charges and expenses marked \"Fixed cost\" in the mini app are fixed too.")
+
+	b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, sb.String()))
+}
+
+func txLabel(description, category string) string {
+	if description != "" {
+		return fmt.Sprintf("%s (%s)", description, category)
+	}
+	return category
+}
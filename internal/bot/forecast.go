@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/budget"
+)
+
+// writeForecast appends the projected spend at the end of the cycle.
+func writeForecast(sb *strings.Builder, f budget.Forecast) {
+	if f.RemainingDays <= 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("🔮 Forecast by %s: %.2f RUB (%.2f – %.2f) of %.2f\n",
+		f.NextCycleStart.AddDate(0, 0, -1).Format("2006-01-02"), f.Expected, f.Optimistic, f.Pessimistic, f.Budget))
+	if f.Over() {
+		sb.WriteString(fmt.Sprintf("  ⚠️ Expected to exceed the budget by %.2f RUB; keep daily spend under %.2f RUB to stay within it\n",
+			f.Expected-f.Budget, safeRate(f)))
+	}
+}
+
+// safeRate is the discretionary spend per remaining day that keeps the cycle within budget.
+func safeRate(f budget.Forecast) float64 {
+	if f.RemainingDays <= 0 {
+		return 0
+	}
+	return max(f.Budget-f.Spent-f.Committed, 0) / float64(f.RemainingDays)
+}
+
+// checkForecast pushes an early warning once per cycle when the expected spend exceeds the budget.
+func (b *Bot) checkForecast() {
+	f := b.planner.Forecast(time.Now().In(b.location), b.planner.MonthlyBudget())
+	if !f.Over() || f.RemainingDays <= 0 {
+		return
+	}
+	if !b.markAlerted("forecast:" + f.CycleStart.Format("2006-01-02")) {
+		return
+	}
+	b.notify(fmt.Sprintf("🔮 At the current pace this period ends at %.2f RUB (%.2f – %.2f), %.2f over the %.2f RUB budget. Keep daily spend under %.2f RUB for the remaining %d days.",
+		f.Expected, f.Optimistic, f.Pessimistic, f.Expected-f.Budget, f.Budget, safeRate(f), f.RemainingDays))
+}
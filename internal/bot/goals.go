@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/goals"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const goalsUsage = `Usage:
+/goals — progress of all goals
+/goals add <name> <target> <YYYY-MM-DD>
+/goals put <id|name> <amount> — manual contribution (negative to withdraw)
+/goals delete <id>
+
+Example: /goals add vacation 60000 2027-06-01`
+
+// goalProgress returns the progress of all goals on date. Cycle surplus that the
+// rollover policy does not carry over is contributed to the goals.
+func (b *Bot) goalProgress(date time.Time) []goals.Progress {
+	surpluses := goals.SurplusFrom(b.planner.Results(date, b.planner.MonthlyBudget()))
+	return b.goals.Progress(surpluses, b.planner.Cycle, date)
+}
+
+// handleGoals lists and manages savings goals.
+// Usage:
+//
+//	/goals                                -> progress
+//	/goals add vacation 60000 2027-06-01  -> new goal
+//	/goals put vacation 5000              -> manual contribution
+//	/goals delete 1                       -> remove a goal
+func (b *Bot) handleGoals(msg *tgbotapi.Message) {
+	parts := strings.Fields(msg.Text)
+	today := time.Now().In(b.location)
+	if len(parts) == 1 {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, formatGoals(b.goalProgress(today))))
+		return
+	}
+
+	switch strings.ToLower(parts[1]) {
+	case "add":
+		if len(parts) != 5 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, goalsUsage))
+			return
+		}
+		target, err := strconv.ParseFloat(strings.ReplaceAll(parts[3], ",", "."), 64)
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Invalid target. Example: /goals add vacation 60000 2027-06-01"))
+			return
+		}
+		g, err := b.goals.Add(goals.Goal{Name: parts[2], Target: target, Deadline: parts[4], Created: today.Format("2006-01-02")})
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to save goal: "+err.Error()))
+			return
+		}
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Goal #%d %s: %.2f RUB by %s", g.ID, g.Name, g.Target, g.Deadline)))
+	case "put":
+		if len(parts) != 4 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, goalsUsage))
+			return
+		}
+		g, err := b.goals.Find(parts[2])
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ Goal %s not found. Use /goals to see goals.", parts[2])))
+			return
+		}
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(parts[3], ",", "."), 64)
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Invalid amount. Example: /goals put vacation 5000"))
+			return
+		}
+		if err := b.goals.Contribute(goals.Contribution{Date: today.Format("2006-01-02"), GoalID: g.ID, Amount: amount}); err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to save contribution: "+err.Error()))
+			return
+		}
+		for _, p := range b.goalProgress(today) {
+			if p.ID == g.ID {
+				b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ %s: %.2f of %.2f RUB saved", p.Name, p.Saved, p.Target)))
+			}
+		}
+	case "delete":
+		if len(parts) != 3 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, goalsUsage))
+			return
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(parts[2], "#"))
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Invalid ID. Use /goals to see IDs."))
+			return
+		}
+		err = b.goals.Delete(id)
+		if errors.Is(err, goals.ErrNotFound) {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ Goal #%d not found", id)))
+			return
+		}
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to delete goal: "+err.Error()))
+			return
+		}
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Goal #%d deleted", id)))
+	default:
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, goalsUsage))
+	}
+}
+
+func formatGoals(progress []goals.Progress) string {
+	if len(progress) == 0 {
+		return "No goals yet.\n\n" + goalsUsage
+	}
+	var sb strings.Builder
+	sb.WriteString("🎯 Goals:\n")
+	for _, p := range progress {
+		sb.WriteString(fmt.Sprintf("\n#%d %s — %.2f of %.2f RUB (%.0f%%) by %s\n", p.ID, p.Name, p.Saved, p.Target, 100*p.Saved/p.Target, p.Deadline))
+		if p.FromSurplus > 0 {
+			sb.WriteString(fmt.Sprintf("  %.2f manual, %.2f from cycle surplus\n", p.Manual, p.FromSurplus))
+		}
+		switch {
+		case p.Done:
+			sb.WriteString("  ✅ Reached\n")
+		case p.Projected.IsZero():
+			sb.WriteString(fmt.Sprintf("  Needs %.2f RUB per cycle (%d cycles left); no pace yet\n", p.PerCycle, p.CyclesLeft))
+		default:
+			mark := "✅ on track"
+			if !p.OnTrack {
+				mark = "⚠️ behind"
+			}
+			sb.WriteString(fmt.Sprintf("  Needs %.2f RUB per cycle (%d cycles left); at %.2f per cycle done by %s, %s\n",
+				p.PerCycle, p.CyclesLeft, p.AvgPerCycle, p.Projected.Format("2006-01-02"), mark))
+		}
+	}
+	return sb.String()
+}
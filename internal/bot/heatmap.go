@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/chart"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/report"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// topHours is the number of busiest hours listed by /heatmap.
+const topHours = 3
+
+const heatmapUsage = "Usage: /heatmap [YYYY-MM|YYYY] [category] [#tag]\nExample: /heatmap 2025-08 dining"
+
+// handleHeatmap shows when spending happens, by weekday and hour of day:
+// /heatmap [YYYY-MM|YYYY] [category...] [#tag...], the current month by default.
+func (b *Bot) handleHeatmap(msg *tgbotapi.Message) {
+	p := report.Month(time.Now().In(b.location))
+	arg, filter := b.reportFilter(msg.CommandArguments())
+	if arg != "" {
+		if t, err := time.ParseInLocation("2006-01", arg, b.location); err == nil {
+			p = report.Month(t)
+		} else if t, err := time.ParseInLocation("2006", arg, b.location); err == nil {
+			p = report.Year(t)
+		} else {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, heatmapUsage))
+			return
+		}
+	}
+	txs, names, categories := b.matching(filter)
+	h := report.BuildHeatmap(txs, p, b.location, categories)
+	text := formatHeatmap(h)
+	if len(names) > 0 {
+		text = "🔎 " + strings.Join(names, ", ") + "\n" + text
+	}
+	b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, text))
+	if h.Count > 0 {
+		b.sendCharts(msg.Chat.ID, heatmapChart(h))
+	}
+}
+
+func formatHeatmap(h report.Heatmap) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🕒 Spending by time of day, %s (%s — %s, %s)\n", h.Label, h.From, h.To, h.Timezone))
+	if h.Count == 0 {
+		sb.WriteString("No expenses with a time in this period.\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("💸 %.2f RUB in %d expenses with a time\n", h.Total, h.Count))
+	}
+	if h.Untimed > 0 {
+		sb.WriteString(fmt.Sprintf("ℹ️ %d expenses without a time are left out\n", h.Untimed))
+	}
+	if h.Peak == nil {
+		return sb.String()
+	}
+	sb.WriteString(fmt.Sprintf("🔥 Busiest: %s %02d:00–%02d:00, %.2f RUB in %d\n", h.Peak.Weekday, h.Peak.Hour, (h.Peak.Hour+1)%24, h.Peak.Amount, h.Peak.Count))
+
+	sb.WriteString("\n📅 By weekday:\n")
+	for i, amount := range h.Weekdays {
+		sb.WriteString(fmt.Sprintf("• %s: %.2f RUB\n", report.Weekdays[i], amount))
+	}
+
+	hours := make([]int, 0, len(h.Hours))
+	for hour, amount := range h.Hours {
+		if amount > 0 {
+			hours = append(hours, hour)
+		}
+	}
+	sort.SliceStable(hours, func(i, j int) bool { return h.Hours[hours[i]] > h.Hours[hours[j]] })
+	sb.WriteString("\n⏰ Top hours:\n")
+	for _, hour := range hours[:min(len(hours), topHours)] {
+		sb.WriteString(fmt.Sprintf("• %02d:00: %.2f RUB\n", hour, h.Hours[hour]))
+	}
+	return sb.String()
+}
+
+func heatmapChart(h report.Heatmap) chart.Heatmap {
+	ch := chart.Heatmap{Title: "Spending by weekday and hour, " + h.Label, Rows: report.Weekdays[:]}
+	for hour := 0; hour < 24; hour++ {
+		ch.Columns = append(ch.Columns, fmt.Sprintf("%02d", hour))
+	}
+	for _, hours := range h.Cells {
+		row := make([]float64, len(hours))
+		for hour, c := range hours {
+			row[hour] = c.Amount
+		}
+		ch.Values = append(ch.Values, row)
+	}
+	return ch
+}
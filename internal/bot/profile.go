@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/budget"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleProfile shows or switches the allowance profile that weights the days of a cycle.
+// Usage:
+//
+//	/profile          -> show current profile, its weights and available profiles
+//	/profile weekend  -> set runtime override (resets on restart)
+//	/profile reset    -> revert to ALLOWANCE_PROFILE
+func (b *Bot) handleProfile(msg *tgbotapi.Message) {
+	parts := strings.Fields(msg.Text)
+	settings := b.planner.Settings()
+
+	if len(parts) == 2 {
+		name := strings.ToLower(parts[1])
+		if name == "reset" {
+			name = ""
+		}
+		if err := b.planner.SetProfile(name); err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ %v. Available: %s", err, strings.Join(settings.ProfileNames(), ", "))))
+			return
+		}
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Allowance profile: %s", b.planner.Profile().Name)))
+		return
+	}
+	if len(parts) > 2 {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "Usage: /profile | /profile <name> | /profile reset"))
+		return
+	}
+
+	p := b.planner.Profile()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("⚖️ Allowance profile: %s (default: %s)\n\n", p.Name, settings.Profile))
+	for _, wd := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		sb.WriteString(fmt.Sprintf("%s: %.2f\n", wd.String()[:3], p.Weekdays[wd]))
+	}
+	if p.Holiday > 0 {
+		sb.WriteString(fmt.Sprintf("Holidays: %.2f (%d days in HOLIDAYS_FILE)\n", p.Holiday, len(settings.Calendar.Holidays)))
+	}
+	sb.WriteString(fmt.Sprintf("\nAvailable: %s\nTo change: /profile <name>, to reset: /profile reset", strings.Join(settings.ProfileNames(), ", ")))
+	b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, sb.String()))
+}
+
+// profileNote returns a short marker for non-even profiles shown next to allowances.
+func profileNote(st budget.Status) string {
+	if st.Profile == "" || st.Profile == budget.ProfileEven {
+		return ""
+	}
+	return fmt.Sprintf(" (%s profile)", st.Profile)
+}
@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/receipt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// transactionIDLabel precedes the transaction ID in confirmations, so a reply
+// to one can be linked to its transaction even after a restart.
+const transactionIDLabel = "🆔"
+
+const receiptUsage = `Usage:
+/receipt — expenses with receipts
+/receipt <id> — send the receipts of an expense (or reply to its confirmation)
+/receipt delete <id> — remove the receipts of an expense
+
+To attach a receipt, reply to the "Expense added" message with a photo or PDF.`
+
+// repliedTransactionID returns the ID of the transaction whose confirmation msg
+// replies to, or "".
+func repliedTransactionID(msg *tgbotapi.Message) string {
+	if msg.ReplyToMessage == nil {
+		return ""
+	}
+	return transactionIDIn(msg.ReplyToMessage.Text)
+}
+
+func transactionIDIn(text string) string {
+	_, rest, ok := strings.Cut(text, transactionIDLabel)
+	if !ok {
+		return ""
+	}
+	if fields := strings.Fields(rest); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// handleReceiptUpload attaches the photo or document of msg to a transaction.
+// Of a photo, Telegram offers several sizes; the largest is kept.
+func (b *Bot) handleReceiptUpload(msg *tgbotapi.Message, id string) {
+	tx, err := b.data.GetTransaction(id)
+	if err != nil {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ That expense no longer exists."))
+		return
+	}
+	var fileID, name, contentType string
+	if len(msg.Photo) > 0 {
+		fileID, name = msg.Photo[len(msg.Photo)-1].FileID, "photo.jpg"
+	} else {
+		fileID, name, contentType = msg.Document.FileID, msg.Document.FileName, msg.Document.MimeType
+	}
+
+	b.attachReceipt(msg.Chat.ID, tx, fileID, name, contentType)
+}
+
+// attachReceipt downloads a file sent to the bot and attaches it to tx.
+func (b *Bot) attachReceipt(chatID int64, tx data.Transaction, fileID, name, contentType string) {
+	body, err := b.downloadFile(fileID)
+	if err != nil {
+		log.Printf("Failed to download receipt: %v", err)
+		b.api.Send(tgbotapi.NewMessage(chatID, "❌ Failed to download file"))
+		return
+	}
+	defer body.Close()
+
+	r, err := b.receipts.Attach(tx.ID, name, contentType, body, time.Now().In(b.location))
+	switch {
+	case errors.Is(err, receipt.ErrUnsupported), errors.Is(err, receipt.ErrTooLarge):
+		b.api.Send(tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	case err != nil:
+		log.Printf("Failed to store receipt: %v", err)
+		b.api.Send(tgbotapi.NewMessage(chatID, "❌ Failed to save receipt"))
+		return
+	}
+	n := len(b.receipts.List(tx.ID))
+	b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("📎 Receipt attached to %s %s %.2f RUB (%s, %d in total). Show it with /receipt %s", tx.Date, tx.Category, tx.Amount, r.FileName(), n, tx.ID)))
+}
+
+// handleReceipt sends or removes the receipts of an expense.
+// Usage:
+//
+//	/receipt                  -> latest expenses with receipts
+//	/receipt 1a2b3c4d5e6f     -> send its receipts; also as a reply to a confirmation
+//	/receipt delete 1a2b3c    -> remove its receipts
+func (b *Bot) handleReceipt(msg *tgbotapi.Message) {
+	parts := strings.Fields(msg.Text)
+	switch {
+	case len(parts) == 1:
+		if id := repliedTransactionID(msg); id != "" {
+			b.sendReceipts(msg.Chat.ID, id)
+			return
+		}
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, b.formatReceiptList()))
+	case len(parts) == 2 && parts[1] != "delete":
+		b.sendReceipts(msg.Chat.ID, parts[1])
+	case len(parts) == 3 && parts[1] == "delete":
+		n := len(b.receipts.List(parts[2]))
+		if n == 0 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ That expense has no receipts."))
+			return
+		}
+		if err := b.receipts.DetachAll(parts[2]); err != nil {
+			log.Printf("Failed to remove receipts: %v", err)
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to remove receipts"))
+			return
+		}
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("🗑️ Removed %d receipt(s).", n)))
+	default:
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, receiptUsage))
+	}
+}
+
+// sendReceipts sends the files attached to a transaction, photos as photos.
+func (b *Bot) sendReceipts(chatID int64, id string) {
+	receipts := b.receipts.List(id)
+	if len(receipts) == 0 {
+		b.api.Send(tgbotapi.NewMessage(chatID, "📭 No receipts for that expense.\n\n"+receiptUsage))
+		return
+	}
+	for _, r := range receipts {
+		f, err := os.Open(b.receipts.Path(r))
+		if err != nil {
+			log.Printf("Failed to open receipt %s: %v", r.Hash, err)
+			b.api.Send(tgbotapi.NewMessage(chatID, "❌ Receipt file is missing: "+r.FileName()))
+			continue
+		}
+		file := tgbotapi.FileReader{Name: r.FileName(), Reader: f}
+		switch r.ContentType {
+		case "image/jpeg", "image/png":
+			b.api.Send(tgbotapi.NewPhoto(chatID, file))
+		default:
+			b.api.Send(tgbotapi.NewDocument(chatID, file))
+		}
+		f.Close()
+	}
+}
+
+// downloadFile fetches a file sent to the bot; the caller closes it.
+func (b *Bot) downloadFile(fileID string) (io.ReadCloser, error) {
+	file, err := b.api.GetFile(tgbotapi.FileConfig{FileID: fileID})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.Get(file.Link(b.api.Token))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download failed: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// maxReceiptList bounds the expenses listed by /receipt.
+const maxReceiptList = 10
+
+func (b *Bot) formatReceiptList() string {
+	counts := b.receipts.Counts()
+	var txs []data.Transaction
+	for _, tx := range b.getAllTransactionsSortedDesc() {
+		if counts[tx.ID] > 0 {
+			txs = append(txs, tx)
+		}
+	}
+	if len(txs) == 0 {
+		return "📭 No receipts yet.\n\n" + receiptUsage
+	}
+	var sb strings.Builder
+	sb.WriteString("📎 Expenses with receipts:\n")
+	for i, tx := range txs {
+		if i == maxReceiptList {
+			sb.WriteString(fmt.Sprintf("… and %d more\n", len(txs)-i))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("• %s %s %.2f RUB (%d) — /receipt %s\n", tx.Date, tx.Category, tx.Amount, counts[tx.ID], tx.ID))
+	}
+	return sb.String()
+}
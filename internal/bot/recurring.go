@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const recurringUsage = `Usage:
+/recurring — list recurring charges
+/recurring add <schedule> <amount> <category> [description]
+/recurring pause <id> | resume <id> | delete <id>
+
+Schedules: monthly:5 (day of month), weekly:mon, every:14 (days)
+Example: /recurring add monthly:1 30000 rent Apartment`
+
+// handleRecurring lists and manages recurring charge templates.
+// Usage:
+//
+//	/recurring                                    -> list templates
+//	/recurring add monthly:1 30000 rent Apartment -> add a template starting today
+//	/recurring pause 2 | resume 2 | delete 2      -> manage a template by ID
+func (b *Bot) handleRecurring(msg *tgbotapi.Message) {
+	parts := strings.Fields(msg.Text)
+	if len(parts) == 1 || (len(parts) == 2 && parts[1] == "list") {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, b.formatRecurringList()))
+		return
+	}
+
+	today := time.Now().In(b.location)
+	switch strings.ToLower(parts[1]) {
+	case "add":
+		if len(parts) < 5 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, recurringUsage))
+			return
+		}
+		schedule, err := recurring.ParseSchedule(parts[2])
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ "+err.Error()))
+			return
+		}
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(parts[3], ",", "."), 64)
+		if err != nil || amount <= 0 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Invalid amount. Example: /recurring add monthly:1 30000 rent"))
+			return
+		}
+		t, err := b.templates.Add(recurring.Template{
+			Schedule:    schedule,
+			Start:       today.Format("2006-01-02"),
+			Category:    parts[4],
+			Description: strings.Join(parts[5:], " "),
+			Amount:      amount,
+		})
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to save recurring charge: "+err.Error()))
+			return
+		}
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Recurring charge #%d added: %s, %.2f RUB %s", t.ID, t.Schedule.Describe(), t.Amount, t.Category)))
+	case "pause", "resume", "delete":
+		if len(parts) != 3 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, recurringUsage))
+			return
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(parts[2], "#"))
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Invalid ID. Use /recurring to see IDs."))
+			return
+		}
+		action := strings.ToLower(parts[1])
+		if action == "delete" {
+			err = b.templates.Delete(id)
+		} else {
+			err = b.templates.SetPaused(id, action == "pause", today)
+		}
+		if errors.Is(err, recurring.ErrNotFound) {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ Recurring charge #%d not found", id)))
+			return
+		}
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to update recurring charge: "+err.Error()))
+			return
+		}
+		past := map[string]string{"pause": "paused", "resume": "resumed", "delete": "deleted"}[action]
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Recurring charge #%d %s", id, past)))
+	default:
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, recurringUsage))
+	}
+}
+
+func (b *Bot) formatRecurringList() string {
+	templates := b.templates.List()
+	if len(templates) == 0 {
+		return "No recurring charges yet.\n\n" + recurringUsage
+	}
+	var sb strings.Builder
+	sb.WriteString("🔁 Recurring charges:\n")
+	for _, t := range templates {
+		status := ""
+		if t.Paused {
+			status = " ⏸ paused"
+		}
+		sb.WriteString(fmt.Sprintf("#%d %s — %.2f RUB, %s%s\n", t.ID, recurringLabel(t), t.Amount, t.Schedule.Describe(), status))
+	}
+	return sb.String()
+}
+
+// writeCommitted appends recurring charges that are still due in the current cycle after selectedDate.
+func (b *Bot) writeCommitted(sb *strings.Builder, selectedDate, nextCycleStart time.Time) {
+	charges := b.templates.Upcoming(selectedDate.AddDate(0, 0, 1), nextCycleStart.AddDate(0, 0, -1))
+	if len(charges) == 0 {
+		return
+	}
+	var total float64
+	for _, c := range charges {
+		total += c.Template.Amount
+	}
+	sb.WriteString(fmt.Sprintf("📌 Committed until period end: %.2f RUB\n", total))
+	for _, c := range charges {
+		sb.WriteString(fmt.Sprintf("  • %s %s %.2f RUB\n", c.Date.Format("Jan 2"), recurringLabel(c.Template), c.Template.Amount))
+	}
+}
+
+func recurringLabel(t recurring.Template) string {
+	return txLabel(t.Description, t.Category)
+}
@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/report"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleMonth shows the summary of a calendar month: /month [YYYY-MM] [category...] [#tag...]
+func (b *Bot) handleMonth(msg *tgbotapi.Message) {
+	date := time.Now().In(b.location)
+	arg, filter := b.reportFilter(msg.CommandArguments())
+	if arg != "" {
+		t, err := time.ParseInLocation("2006-01", arg, b.location)
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "Usage: /month [YYYY-MM] [category] [#tag]\nExample: /month 2025-08 food #kazan"))
+			return
+		}
+		date = t
+	}
+	b.sendReport(msg.Chat.ID, report.KindMonth, date, filter)
+}
+
+// handleCycle shows the summary of a pay cycle: /cycle [N] [category...] [#tag...], N cycles
+// back from the current one.
+func (b *Bot) handleCycle(msg *tgbotapi.Message) {
+	date := time.Now().In(b.location)
+	arg, filter := b.reportFilter(msg.CommandArguments())
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || n > report.MaxCyclesBack {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("Usage: /cycle [N] [category] [#tag] — N cycles back, 0 is the current one, at most %d\nExample: /cycle 1", report.MaxCyclesBack)))
+			return
+		}
+		for i := 0; i < n; i++ {
+			start, _ := b.planner.Cycle(date)
+			date = start.AddDate(0, 0, -1)
+		}
+	}
+	b.sendReport(msg.Chat.ID, report.KindCycle, date, filter)
+}
+
+// handleYear shows the summary of a calendar year: /year [YYYY] [category...] [#tag...]
+func (b *Bot) handleYear(msg *tgbotapi.Message) {
+	date := time.Now().In(b.location)
+	arg, filter := b.reportFilter(msg.CommandArguments())
+	if arg != "" {
+		t, err := time.ParseInLocation("2006", arg, b.location)
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "Usage: /year [YYYY] [category] [#tag]\nExample: /year 2025"))
+			return
+		}
+		date = t
+	}
+	b.sendReport(msg.Chat.ID, report.KindYear, date, filter)
+}
+
+// reportFilter splits the arguments of a summary command into the period (the
+// first argument starting with a digit) and a filter of the other words as
+// categories, including their subcategories, and the #tags.
+func (b *Bot) reportFilter(args string) (string, data.Query) {
+	text, tags := data.ExtractTags(args)
+	q := data.Query{Tags: tags}
+	period := ""
+	for _, word := range strings.Fields(text) {
+		if period == "" && word[0] >= '0' && word[0] <= '9' {
+			period = word
+			continue
+		}
+		q.Categories = append(q.Categories, word)
+	}
+	return period, q
+}
+
+// matching returns the transactions passing filter, its categories including
+// their subcategories, the names of the filter for display, and the expanded
+// categories, which the totals count split lines by.
+func (b *Bot) matching(filter data.Query) (txs []data.Transaction, names, categories []string) {
+	var expanded []string
+	for _, c := range filter.Categories {
+		expanded = append(expanded, b.categories.Expand(c)...)
+	}
+	names = append([]string(nil), filter.Categories...)
+	for _, t := range filter.Tags {
+		names = append(names, "#"+t)
+	}
+	filter.Categories = expanded
+	for _, tx := range b.data.GetAllTransactions() {
+		if filter.Match(tx) {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, names, expanded
+}
+
+func (b *Bot) sendReport(chatID int64, kind string, date time.Time, filter data.Query) {
+	cur, prev, lastYear := report.Periods(kind, b.planner.Cycle, date)
+	txs, names, categories := b.matching(filter)
+	r := report.Build(txs, cur, prev, lastYear, categories)
+	text := formatReport(r)
+	if len(names) > 0 {
+		text = "🔎 " + strings.Join(names, ", ") + "\n" + text
+	}
+	b.api.Send(tgbotapi.NewMessage(chatID, text))
+	if r.Count > 0 {
+		b.sendCharts(chatID, b.reportCharts(r)...)
+	}
+}
+
+func formatReport(r report.Report) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📅 %s (%s — %s)\n", r.Label, r.From, r.To))
+	if r.Count == 0 {
+		sb.WriteString("No expenses in this period.\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("💸 Total: %.2f RUB in %d expenses\n", r.Total, r.Count))
+	}
+	writeComparison(&sb, "Previous", r.Previous)
+	writeComparison(&sb, "A year ago", r.LastYear)
+	if r.Count == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString("\n📂 By category:\n")
+	for _, c := range r.Categories {
+		if c.Amount == 0 {
+			continue
+		}
+		line := fmt.Sprintf("• %s: %.2f RUB (%.1f%%)", c.Category, c.Amount, c.Share)
+		if c.Previous > 0 {
+			line += fmt.Sprintf(", %+.0f%% vs previous", (c.Amount-c.Previous)/c.Previous*100)
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	sb.WriteString("\n🏪 Top merchants:\n")
+	for _, m := range r.Merchants {
+		sb.WriteString(fmt.Sprintf("• %s: %.2f RUB (%d)\n", m.Merchant, m.Amount, m.Count))
+	}
+
+	sb.WriteString("\n🔝 Largest expenses:\n")
+	for _, e := range r.Largest {
+		label := e.Description
+		if label == "" {
+			label = e.Category
+		}
+		sb.WriteString(fmt.Sprintf("• %s %s: %.2f RUB\n", e.Date, label, e.Amount))
+	}
+	return sb.String()
+}
+
+func writeComparison(sb *strings.Builder, name string, c *report.Comparison) {
+	if c == nil {
+		return
+	}
+	if c.Total == 0 {
+		sb.WriteString(fmt.Sprintf("↔️ %s (%s): no expenses\n", name, c.Label))
+		return
+	}
+	sb.WriteString(fmt.Sprintf("↔️ %s (%s): %.2f RUB, %+.2f (%+.1f%%)\n", name, c.Label, c.Total, c.Diff, c.Change))
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const trackSubscriptionPrefix = "sub:"
+
+// detectSubscriptions returns suspected subscriptions that are not yet covered by a recurring template.
+func (b *Bot) detectSubscriptions() []recurring.Suspect {
+	templates := b.templates.List()
+	var res []recurring.Suspect
+	for _, s := range recurring.Detect(b.data.GetAllTransactions(), time.Now().In(b.location)) {
+		tracked := false
+		for _, t := range templates {
+			if s.Tracks(t) {
+				tracked = true
+				break
+			}
+		}
+		if !tracked {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// handleSubscriptions lists suspected subscriptions found in the history, each with
+// a button that turns it into a recurring template.
+func (b *Bot) handleSubscriptions(msg *tgbotapi.Message) {
+	suspects := b.detectSubscriptions()
+	if len(suspects) == 0 {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "🔎 No untracked recurring payments found in your history."))
+		return
+	}
+
+	var sb strings.Builder
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var monthly float64
+	sb.WriteString("🔎 Suspected subscriptions:\n")
+	for i, s := range suspects {
+		monthly += s.MonthlyCost
+		sb.WriteString(fmt.Sprintf("\n%d. %s (%s) — %.2f RUB, %s\n", i+1, suspectLabel(s), s.Category, s.Amount, s.Schedule.Describe()))
+		sb.WriteString(fmt.Sprintf("   ≈ %.2f RUB/month, %d charges, next expected %s\n", s.MonthlyCost, s.Count, s.NextExpected.Format("2006-01-02")))
+		if s.Missing {
+			sb.WriteString("   ⚠️ Expected charge is missing\n")
+		}
+		if s.PriceUp {
+			sb.WriteString(fmt.Sprintf("   📈 Price went up from %.2f RUB\n", s.PreviousAmount))
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("➕ Track %d. %s", i+1, suspectLabel(s)), trackSubscriptionPrefix+s.Key),
+		))
+	}
+	sb.WriteString(fmt.Sprintf("\n💸 Total: ≈ %.2f RUB/month", monthly))
+
+	message := tgbotapi.NewMessage(msg.Chat.ID, sb.String())
+	message.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.api.Send(message)
+}
+
+// trackSubscription creates a recurring template from the suspect with the given key.
+func (b *Bot) trackSubscription(cb *tgbotapi.CallbackQuery, key string) {
+	for _, s := range b.detectSubscriptions() {
+		if s.Key != key {
+			continue
+		}
+		t, err := b.templates.Add(s.Template())
+		if err != nil {
+			log.Printf("Failed to track subscription %s: %v", key, err)
+			b.answerCallback(cb, "❌ Failed to save recurring charge")
+			return
+		}
+		b.answerCallback(cb, "✅ Tracked")
+		b.api.Send(tgbotapi.NewMessage(cb.Message.Chat.ID, fmt.Sprintf("✅ Recurring charge #%d added: %s, %.2f RUB %s", t.ID, t.Schedule.Describe(), t.Amount, t.Category)))
+		return
+	}
+	b.answerCallback(cb, "Already tracked or no longer detected")
+}
+
+// checkSubscriptions pushes an alert for every suspected subscription whose charge is
+// overdue or whose price went up. Each event is reported once, see markAlerted.
+func (b *Bot) checkSubscriptions() {
+	for _, s := range b.detectSubscriptions() {
+		if s.Missing {
+			key := fmt.Sprintf("missing:%s:%s", s.Key, s.NextExpected.Format("2006-01-02"))
+			if b.markAlerted(key) {
+				b.notify(fmt.Sprintf("⚠️ Expected charge is missing: %s (%s), %.2f RUB was due %s. Cancelled, or not entered yet?",
+					suspectLabel(s), s.Category, s.Amount, s.NextExpected.Format("2006-01-02")))
+			}
+		}
+		if s.PriceUp {
+			key := fmt.Sprintf("price:%s:%s", s.Key, s.LastDate.Format("2006-01-02"))
+			if b.markAlerted(key) {
+				b.notify(fmt.Sprintf("📈 Price went up: %s (%s) %.2f → %.2f RUB (+%.2f RUB/month)",
+					suspectLabel(s), s.Category, s.PreviousAmount, s.Amount, s.MonthlyCost*(1-s.PreviousAmount/s.Amount)))
+			}
+		}
+	}
+}
+
+func suspectLabel(s recurring.Suspect) string {
+	if s.Description != "" {
+		return s.Description
+	}
+	return s.Merchant
+}
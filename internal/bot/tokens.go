@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/token"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const tokenUsage = `Usage:
+/token — list your API tokens
+/token new <read|write|admin> [name] — create a token (private chat only)
+/token revoke <id>
+
+Only users listed in NOTIFY_CHAT_IDS may create tokens; they see and may revoke
+everyone's, the others their own.
+
+Send it as "Authorization: Bearer <token>" to /expenses/api/v1.
+Example: /token new write iPhone shortcut`
+
+// handleToken lists, creates and revokes API tokens. Creating one needs a token
+// admin, see tokenAdmin; the others only see and revoke the tokens they own.
+// Usage:
+//
+//	/token                        -> list tokens
+//	/token new write Shortcut     -> create a token; the secret is shown once
+//	/token revoke 2               -> revoke a token by ID
+func (b *Bot) handleToken(msg *tgbotapi.Message) {
+	var user int64
+	if msg.From != nil {
+		user = msg.From.ID
+	}
+	parts := strings.Fields(msg.Text)
+	if len(parts) == 1 || (len(parts) == 2 && parts[1] == "list") {
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, b.formatTokenList(user)))
+		return
+	}
+
+	switch strings.ToLower(parts[1]) {
+	case "new", "create":
+		if len(parts) < 3 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, tokenUsage))
+			return
+		}
+		// The secret would be readable by every member of a group.
+		if !msg.Chat.IsPrivate() {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Create tokens in a private chat with the bot."))
+			return
+		}
+		if !b.tokenAdmin(user) {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ Only users listed in NOTIFY_CHAT_IDS may create tokens; your user ID is %d.", user)))
+			return
+		}
+		scope, err := token.ParseScope(parts[2])
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ "+err.Error()))
+			return
+		}
+		t, secret, err := b.tokens.Create(strings.Join(parts[3:], " "), scope, user, time.Now().In(b.location))
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to create token: "+err.Error()))
+			return
+		}
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Token #%d %q (%s):\n\n%s\n\nIt is shown only once, store it now. Revoke with /token revoke %d", t.ID, t.Name, t.Scope, secret, t.ID)))
+	case "revoke", "delete":
+		if len(parts) != 3 {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, tokenUsage))
+			return
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(parts[2], "#"))
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Invalid ID. Use /token to see IDs."))
+			return
+		}
+		// Tokens of others are reported as missing rather than revealing them.
+		if t, ok := b.findToken(id); ok && t.Owner != user && !b.tokenAdmin(user) {
+			err = token.ErrNotFound
+		} else {
+			err = b.tokens.Revoke(id)
+		}
+		if errors.Is(err, token.ErrNotFound) {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("❌ Token #%d not found", id)))
+			return
+		}
+		if err != nil {
+			b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Failed to revoke token: "+err.Error()))
+			return
+		}
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Token #%d revoked", id)))
+	default:
+		b.api.Send(tgbotapi.NewMessage(msg.Chat.ID, tokenUsage))
+	}
+}
+
+// tokenAdmin reports whether user may create tokens and manage everyone's: the
+// users listed in NOTIFY_CHAT_IDS, whose private chat ID is their user ID.
+func (b *Bot) tokenAdmin(user int64) bool {
+	return user != 0 && slices.Contains(b.notifyChatIDs, user)
+}
+
+// findToken returns the token with the given ID.
+func (b *Bot) findToken(id int) (token.Token, bool) {
+	for _, t := range b.tokens.List() {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return token.Token{}, false
+}
+
+// formatTokenList lists the tokens user owns, or every token for a token admin.
+func (b *Bot) formatTokenList(user int64) string {
+	admin := b.tokenAdmin(user)
+	var tokens []token.Token
+	for _, t := range b.tokens.List() {
+		if admin || t.Owner == user {
+			tokens = append(tokens, t)
+		}
+	}
+	if len(tokens) == 0 {
+		return "No API tokens yet.\n\n" + tokenUsage
+	}
+	var sb strings.Builder
+	sb.WriteString("🔑 API tokens:\n")
+	for _, t := range tokens {
+		used := "never used"
+		if t.LastUsed != "" {
+			used = "last used " + t.LastUsed
+		}
+		sb.WriteString(fmt.Sprintf("#%d %s — %s, created %s, %s", t.ID, t.Name, t.Scope, t.Created, used))
+		if admin && t.Owner != user {
+			sb.WriteString(fmt.Sprintf(", owner %d", t.Owner))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
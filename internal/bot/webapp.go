@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// initDataMaxAge is how long the initData of a Mini App launch is accepted.
+const initDataMaxAge = 24 * time.Hour
+
+var errInitData = errors.New("invalid Telegram init data, reopen the app from the bot")
+
+// VerifyInitData checks the initData Telegram passes to the Mini App, signed
+// with the bot token, and returns the ID of the user who opened it.
+func (b *Bot) VerifyInitData(initData string) (int64, error) {
+	return verifyInitData(initData, b.api.Token, time.Now())
+}
+
+// verifyInitData implements VerifyInitData, see
+// https://core.telegram.org/bots/webapps#validating-data-received-via-the-mini-app
+func verifyInitData(initData, botToken string, now time.Time) (int64, error) {
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return 0, errInitData
+	}
+	sum, err := hex.DecodeString(values.Get("hash"))
+	if err != nil || len(sum) == 0 {
+		return 0, errInitData
+	}
+	values.Del("hash")
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + values.Get(k)
+	}
+
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return 0, errInitData
+	}
+
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil || now.Sub(time.Unix(authDate, 0)) > initDataMaxAge {
+		return 0, errors.New("the Mini App session expired, reopen the app from the bot")
+	}
+	var user struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(values.Get("user")), &user); err != nil || user.ID == 0 {
+		return 0, errInitData
+	}
+	return user.ID, nil
+}
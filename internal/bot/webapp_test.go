@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signInitData builds initData as Telegram does for the given fields.
+func signInitData(botToken string, values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + values.Get(k)
+	}
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+	values.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return values.Encode()
+}
+
+func TestVerifyInitData(t *testing.T) {
+	t.Parallel()
+
+	const botToken = "123456:secret"
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	fields := func(authDate time.Time) url.Values {
+		return url.Values{
+			"auth_date": {strconv.FormatInt(authDate.Unix(), 10)},
+			"query_id":  {"AAHdF6IQAAAAAN0XohDhrOrc"},
+			"user":      {`{"id":279058397,"first_name":"Vlad"}`},
+		}
+	}
+	valid := signInitData(botToken, fields(now.Add(-time.Hour)))
+
+	if user, err := verifyInitData(valid, botToken, now); err != nil || user != 279058397 {
+		t.Errorf("verifyInitData(valid) = %d, %v; want 279058397", user, err)
+	}
+
+	forged, _ := url.ParseQuery(valid)
+	forged.Set("user", `{"id":1,"first_name":"Mallory"}`)
+	tests := []struct {
+		name     string
+		initData string
+		botToken string
+	}{
+		{"missing", "", botToken},
+		{"other bot", valid, "654321:other"},
+		{"forged user", forged.Encode(), botToken},
+		{"expired", signInitData(botToken, fields(now.Add(-initDataMaxAge-time.Minute))), botToken},
+		{"no user", signInitData(botToken, url.Values{"auth_date": {strconv.FormatInt(now.Unix(), 10)}}), botToken},
+	}
+	for _, tt := range tests {
+		if user, err := verifyInitData(tt.initData, tt.botToken, now); err == nil {
+			t.Errorf("%s: verifyInitData() = %d, want an error", tt.name, user)
+		}
+	}
+}
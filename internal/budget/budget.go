@@ -0,0 +1,372 @@
+package budget
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+)
+
+const dateLayout = "2006-01-02"
+
+// Budget modes, see BUDGET_MODE.
+const (
+	ModeEven     = "even"     // one discretionary budget spread over the cycle
+	ModeEnvelope = "envelope" // the cycle budget is allocated into named envelopes
+)
+
+// Settings holds the budget configuration read once at startup.
+type Settings struct {
+	Mode            string          // BUDGET_MODE, default "even"
+	MonthlyBudget   float64         // MONTHLY_BUDGET_RUB, default 12000
+	SalaryDay       int             // SALARY_DAY, 1..28, default 15; used when PAYDAYS is not set
+	Cycles          Cycles          // PAYDAYS, PAYDAY_SHIFT, CYCLE_BOUNDARIES
+	Rollover        Rollover        // ROLLOVER_POLICY, ROLLOVER_CAP
+	FixedCategories map[string]bool // FIXED_CATEGORIES, lower-cased category names
+	// ResolveCategory maps any spelling of a category to its ID, so that
+	// FIXED_CATEGORIES match aliases and merged names too; nil compares names only
+	ResolveCategory func(string) string
+	Calendar        Calendar // HOLIDAYS_FILE
+	Profiles        map[string]Profile
+	Profile         string // ALLOWANCE_PROFILE, default "even"
+}
+
+// FromEnv reads budget settings from the environment.
+func FromEnv() Settings {
+	s := Settings{
+		Mode:            ModeEven,
+		MonthlyBudget:   12000,
+		SalaryDay:       15,
+		FixedCategories: map[string]bool{},
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("MONTHLY_BUDGET_RUB"), 64); err == nil && v > 0 {
+		s.MonthlyBudget = v
+	}
+	switch mode := strings.ToLower(os.Getenv("BUDGET_MODE")); mode {
+	case "", ModeEven:
+	case ModeEnvelope:
+		s.Mode = mode
+	default:
+		log.Printf("Unknown BUDGET_MODE %q, using %q", mode, ModeEven)
+	}
+	if v, err := strconv.Atoi(os.Getenv("SALARY_DAY")); err == nil && v >= 1 && v <= 28 {
+		s.SalaryDay = v
+	}
+	for _, c := range strings.Split(os.Getenv("FIXED_CATEGORIES"), ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			s.FixedCategories[c] = true
+		}
+	}
+
+	if path := os.Getenv("HOLIDAYS_FILE"); path != "" {
+		cal, err := LoadCalendar(path)
+		if err != nil {
+			log.Printf("Failed to load HOLIDAYS_FILE %q, ignoring holidays: %v", path, err)
+		}
+		s.Calendar = cal
+	}
+	s.Cycles = Cycles{Paydays: []Payday{{Day: s.SalaryDay}}, Shift: ShiftNone, calendar: s.Calendar}
+	if v := os.Getenv("PAYDAYS"); v != "" {
+		if paydays, err := ParsePaydays(v); err == nil {
+			s.Cycles.Paydays = paydays
+		} else {
+			log.Printf("Invalid PAYDAYS %q, using SALARY_DAY=%d: %v", v, s.SalaryDay, err)
+		}
+	}
+	if shift, err := ParseShift(os.Getenv("PAYDAY_SHIFT")); err == nil {
+		s.Cycles.Shift = shift
+	} else {
+		log.Printf("Invalid PAYDAY_SHIFT, not shifting paydays: %v", err)
+	}
+	if v := os.Getenv("CYCLE_BOUNDARIES"); v != "" {
+		if boundaries, err := ParseBoundaries(v); err == nil {
+			s.Cycles.Boundaries = boundaries
+		} else {
+			log.Printf("Invalid CYCLE_BOUNDARIES %q, ignoring: %v", v, err)
+		}
+	}
+
+	s.Rollover = Rollover{Policy: RolloverNone, Cap: s.MonthlyBudget / 2}
+	if policy, err := ParseRollover(os.Getenv("ROLLOVER_POLICY")); err == nil {
+		s.Rollover.Policy = policy
+	} else {
+		log.Printf("Invalid ROLLOVER_POLICY, not carrying over: %v", err)
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("ROLLOVER_CAP"), 64); err == nil && v >= 0 {
+		s.Rollover.Cap = v
+	}
+
+	s.Profiles = map[string]Profile{
+		ProfileEven:    evenProfile(),
+		ProfileWeekend: weekendProfile(s.Calendar),
+	}
+	if w := os.Getenv("DAY_WEIGHTS"); w != "" {
+		if p, err := ParseWeights(w, s.Calendar); err == nil {
+			s.Profiles[ProfileCustom] = p
+		} else {
+			log.Printf("Invalid DAY_WEIGHTS %q, ignoring: %v", w, err)
+		}
+	}
+	s.Profile = ProfileEven
+	if name := strings.ToLower(os.Getenv("ALLOWANCE_PROFILE")); name != "" {
+		if _, ok := s.Profiles[name]; ok {
+			s.Profile = name
+		} else {
+			log.Printf("Unknown ALLOWANCE_PROFILE %q, using %q", name, ProfileEven)
+		}
+	}
+	return s
+}
+
+// Envelopes reports whether envelope budgeting is enabled.
+func (s Settings) Envelopes() bool {
+	return s.Mode == ModeEnvelope
+}
+
+// IsFixed reports whether tx is a fixed cost, either marked individually or by its category.
+func (s Settings) IsFixed(tx data.Transaction) bool {
+	return tx.Fixed || s.FixedCategory(tx.Category)
+}
+
+// FixedCategory reports whether category is listed in FIXED_CATEGORIES, under
+// this or, with ResolveCategory, any other spelling.
+func (s Settings) FixedCategory(category string) bool {
+	if s.FixedCategories[strings.ToLower(category)] {
+		return true
+	}
+	if s.ResolveCategory == nil {
+		return false
+	}
+	id := strings.ToLower(s.ResolveCategory(category))
+	for c := range s.FixedCategories {
+		if strings.ToLower(s.ResolveCategory(c)) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// cycles returns the configured cycle model, falling back to SalaryDay.
+func (s Settings) cycles() Cycles {
+	c := s.Cycles
+	if len(c.Paydays) == 0 && s.SalaryDay > 0 {
+		c.Paydays = []Payday{{Day: s.SalaryDay}}
+	}
+	return c
+}
+
+// Status is the state of the budget on a given day of a pay cycle.
+//
+// Fixed costs are reserved from the cycle budget up front; only the remaining
+// discretionary budget is spread over the days of the cycle according to the
+// allowance profile (evenly by default).
+type Status struct {
+	Date           time.Time
+	CycleStart     time.Time
+	NextCycleStart time.Time
+	DaysInCycle    int
+	DayIndex       int // 1-based day of the cycle
+	RemainingDays  int // days left in the cycle after Date
+	Profile        string
+
+	Budget        float64 // cycle budget
+	Fixed         float64 // fixed costs of the cycle: entered plus still scheduled
+	Carry         float64 // carried over from previous cycles, see Rollover
+	Discretionary float64 // Budget - Fixed + Carry, never negative
+
+	TodayTotal float64 // everything spent on Date
+	TodayFixed float64 // fixed costs on Date
+	Spent      float64 // discretionary spend from cycle start through Date
+	Allowed    float64 // discretionary spend allowed through Date
+	Saldo      float64 // Allowed - Spent
+	Tomorrow   float64 // allowance for the next day given what is left
+}
+
+// Planner computes budget figures from the ledger and the recurring templates.
+type Planner struct {
+	data      *data.Data
+	templates *recurring.Store
+	settings  Settings
+
+	mu      sync.Mutex
+	profile string  // runtime allowance profile override, empty if not set
+	monthly float64 // runtime monthly budget override, 0 if not set
+}
+
+func NewPlanner(ledger *data.Data, templates *recurring.Store, settings Settings) *Planner {
+	return &Planner{data: ledger, templates: templates, settings: settings}
+}
+
+// Settings returns the configured budget settings.
+func (p *Planner) Settings() Settings {
+	return p.settings
+}
+
+// Profile returns the allowance profile in use: the runtime override if set,
+// otherwise ALLOWANCE_PROFILE.
+func (p *Planner) Profile() Profile {
+	p.mu.Lock()
+	name := p.profile
+	p.mu.Unlock()
+	if prof, ok := p.settings.Profiles[name]; ok {
+		return prof
+	}
+	if prof, ok := p.settings.Profiles[p.settings.Profile]; ok {
+		return prof
+	}
+	return evenProfile()
+}
+
+// SetProfile overrides the allowance profile until restart; an empty name resets
+// it to ALLOWANCE_PROFILE.
+func (p *Planner) SetProfile(name string) error {
+	name = strings.ToLower(name)
+	if _, ok := p.settings.Profiles[name]; name != "" && !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	p.mu.Lock()
+	p.profile = name
+	p.mu.Unlock()
+	return nil
+}
+
+// MonthlyBudget returns the monthly budget in use: the runtime override if set,
+// otherwise MONTHLY_BUDGET_RUB.
+func (p *Planner) MonthlyBudget() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.monthly > 0 {
+		return p.monthly
+	}
+	return p.settings.MonthlyBudget
+}
+
+// MonthlyBudgetOverridden reports whether a runtime monthly budget is set.
+func (p *Planner) MonthlyBudgetOverridden() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.monthly > 0
+}
+
+// SetMonthlyBudget overrides the monthly budget until restart; 0 resets it to
+// MONTHLY_BUDGET_RUB.
+func (p *Planner) SetMonthlyBudget(v float64) error {
+	if v < 0 {
+		return fmt.Errorf("monthly budget must not be negative")
+	}
+	p.mu.Lock()
+	p.monthly = v
+	p.mu.Unlock()
+	return nil
+}
+
+// Cycle returns the start of the pay cycle containing date and the next cycle start,
+// in date's location. Example: with SALARY_DAY=15 and date 2025-08-09 the cycle is
+// 2025-07-15 .. 2025-08-15; with PAYDAYS=5,20 it is 2025-08-05 .. 2025-08-20.
+func (p *Planner) Cycle(date time.Time) (time.Time, time.Time) {
+	return p.settings.cycles().Cycle(date)
+}
+
+// Cycles returns the pay cycle model in use.
+func (p *Planner) Cycles() Cycles {
+	return p.settings.cycles()
+}
+
+// CycleBudget returns the budget of the cycle [start, next) for the given monthly budget.
+func (p *Planner) CycleBudget(start, next time.Time, monthly float64) float64 {
+	return p.settings.cycles().Budget(start, next, monthly)
+}
+
+// FixedIn returns the fixed costs dated within [from, to]: fixed transactions already in
+// the ledger plus recurring charges that are scheduled but not materialized yet.
+func (p *Planner) FixedIn(from, to time.Time) float64 {
+	return fixedIn(p.data.GetAllTransactions(), p.templates.Upcoming(from, to), p.settings, from, to)
+}
+
+// Status computes the budget status for date; the monthly budget is split between
+// the cycles of the month, see CycleBudget.
+func (p *Planner) Status(date time.Time, monthly float64) Status {
+	start, next := p.Cycle(date)
+	upcoming := p.templates.Upcoming(start, next.AddDate(0, 0, -1))
+	carry := p.Carry(start, monthly)
+	return calculate(p.data.GetAllTransactions(), upcoming, p.settings, p.Profile(), date, start, next, p.CycleBudget(start, next, monthly), carry)
+}
+
+func calculate(txs []data.Transaction, upcoming []recurring.Charge, s Settings, prof Profile, date, start, next time.Time, budget, carry float64) Status {
+	st := Status{
+		Date:           date,
+		CycleStart:     start,
+		NextCycleStart: next,
+		DaysInCycle:    days(start, next),
+		DayIndex:       days(start, date) + 1,
+		RemainingDays:  days(date, next) - 1,
+		Profile:        prof.Name,
+		Budget:         budget,
+		Carry:          carry,
+	}
+	if st.DaysInCycle <= 0 {
+		st.DaysInCycle = 1
+	}
+	if st.DayIndex < 1 {
+		st.DayIndex = 1
+	}
+
+	last := next.AddDate(0, 0, -1)
+	st.Fixed = fixedIn(txs, upcoming, s, start, last)
+	dateStr := date.Format(dateLayout)
+	for _, tx := range txs {
+		d, err := time.ParseInLocation(dateLayout, tx.Date, date.Location())
+		if err != nil {
+			continue
+		}
+		fixed := s.IsFixed(tx)
+		if tx.Date == dateStr {
+			st.TodayTotal += tx.Amount
+			if fixed {
+				st.TodayFixed += tx.Amount
+			}
+		}
+		if !fixed && !d.Before(start) && !d.After(date) {
+			st.Spent += tx.Amount
+		}
+	}
+
+	st.Discretionary = max(max(budget-st.Fixed, 0)+carry, 0)
+	st.Allowed = st.Discretionary * prof.Share(start, date, next)
+	st.Saldo = st.Allowed - st.Spent
+	if st.RemainingDays > 0 {
+		remaining := st.Discretionary - st.Spent
+		if remaining < 0 {
+			remaining = 0
+		}
+		tomorrow := date.AddDate(0, 0, 1)
+		st.Tomorrow = remaining * prof.Share(tomorrow, tomorrow, next)
+	}
+	return st
+}
+
+func fixedIn(txs []data.Transaction, upcoming []recurring.Charge, s Settings, from, to time.Time) float64 {
+	var total float64
+	fromStr, toStr := from.Format(dateLayout), to.Format(dateLayout)
+	for _, tx := range txs {
+		if s.IsFixed(tx) && tx.Date >= fromStr && tx.Date <= toStr {
+			total += tx.Amount
+		}
+	}
+	for _, c := range upcoming {
+		total += c.Template.Amount
+	}
+	return total
+}
+
+// days returns the number of calendar days from a to b.
+func days(a, b time.Time) int {
+	return int(math.Round(b.Sub(a).Hours() / 24))
+}
@@ -0,0 +1,150 @@
+package budget
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestCalculate(t *testing.T) {
+	t.Parallel()
+
+	settings := Settings{MonthlyBudget: 31000, SalaryDay: 1, FixedCategories: map[string]bool{"utilities": true}}
+	start, next := date("2025-08-01"), date("2025-09-01")
+
+	tests := []struct {
+		name        string
+		txs         []data.Transaction
+		upcoming    []recurring.Charge
+		date        string
+		wantFixed   float64
+		wantSpent   float64
+		wantAllowed float64
+		wantSaldo   float64
+	}{
+		{
+			name:        "even spread without fixed costs",
+			txs:         []data.Transaction{{Date: "2025-08-01", Category: "groceries", Amount: 500}},
+			date:        "2025-08-01",
+			wantSpent:   500,
+			wantAllowed: 1000,
+			wantSaldo:   500,
+		},
+		{
+			name: "fixed transaction reserved up front",
+			txs: []data.Transaction{
+				{Date: "2025-08-01", Category: "rent", Amount: 15500, Fixed: true},
+				{Date: "2025-08-01", Category: "groceries", Amount: 300},
+			},
+			date:        "2025-08-01",
+			wantFixed:   15500,
+			wantSpent:   300,
+			wantAllowed: 500,
+			wantSaldo:   200,
+		},
+		{
+			name: "fixed category and later fixed charge in the cycle",
+			txs: []data.Transaction{
+				{Date: "2025-08-02", Category: "Utilities", Amount: 3100},
+				{Date: "2025-08-20", Category: "rent", Amount: 12400, Fixed: true},
+				{Date: "2025-07-31", Category: "groceries", Amount: 999},
+			},
+			date:        "2025-08-02",
+			wantFixed:   15500,
+			wantAllowed: 1000,
+			wantSaldo:   1000,
+		},
+		{
+			name:        "scheduled recurring charge counts as fixed",
+			upcoming:    []recurring.Charge{{Date: date("2025-08-10"), Template: recurring.Template{Amount: 15500}}},
+			date:        "2025-08-10",
+			wantFixed:   15500,
+			wantAllowed: 5000,
+			wantSaldo:   5000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			st := calculate(tt.txs, tt.upcoming, settings, evenProfile(), date(tt.date), start, next, settings.MonthlyBudget, 0)
+			for _, c := range []struct {
+				field     string
+				got, want float64
+			}{
+				{"Fixed", st.Fixed, tt.wantFixed},
+				{"Spent", st.Spent, tt.wantSpent},
+				{"Allowed", st.Allowed, tt.wantAllowed},
+				{"Saldo", st.Saldo, tt.wantSaldo},
+			} {
+				if math.Abs(c.got-c.want) > 0.001 {
+					t.Errorf("%s = %.2f, want %.2f", c.field, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCycle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		salaryDay int
+		date      string
+		wantStart string
+		wantNext  string
+	}{
+		{"before salary day", 15, "2025-08-09", "2025-07-15", "2025-08-15"},
+		{"on salary day", 15, "2025-08-15", "2025-08-15", "2025-09-15"},
+		{"year boundary", 10, "2025-01-05", "2024-12-10", "2025-01-10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := NewPlanner(nil, nil, Settings{SalaryDay: tt.salaryDay})
+			start, next := p.Cycle(date(tt.date))
+			if start.Format(dateLayout) != tt.wantStart || next.Format(dateLayout) != tt.wantNext {
+				t.Errorf("Cycle(%s) = %s .. %s, want %s .. %s", tt.date, start.Format(dateLayout), next.Format(dateLayout), tt.wantStart, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestFixedCategory(t *testing.T) {
+	t.Parallel()
+
+	aliases := map[string]string{"коммуналка": "utilities", "квартплата": "utilities"}
+	resolve := func(c string) string {
+		if id, ok := aliases[strings.ToLower(c)]; ok {
+			return id
+		}
+		return c
+	}
+	settings := Settings{FixedCategories: map[string]bool{"коммуналка": true, "rent": true}}
+	if settings.FixedCategory("utilities") {
+		t.Error("FixedCategory(utilities) without a resolver = true, want names compared only")
+	}
+	settings.ResolveCategory = resolve
+	for _, c := range []string{"utilities", "Квартплата", "Rent"} {
+		if !settings.FixedCategory(c) {
+			t.Errorf("FixedCategory(%q) = false, want true", c)
+		}
+	}
+	if settings.FixedCategory("groceries") {
+		t.Error("FixedCategory(groceries) = true, want false")
+	}
+}
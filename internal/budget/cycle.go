@@ -0,0 +1,233 @@
+package budget
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// averageMonthDays is used to prorate the monthly budget over explicit cycle boundaries.
+const averageMonthDays = 365.25 / 12
+
+// Payday is a monthly payday rule: a fixed day of the month (clamped to the month
+// length), the last calendar day or the last business day of the month.
+type Payday struct {
+	Day          int
+	Last         bool
+	LastBusiness bool
+}
+
+func (p Payday) String() string {
+	switch {
+	case p.LastBusiness:
+		return "last-business"
+	case p.Last:
+		return "last"
+	}
+	return strconv.Itoa(p.Day)
+}
+
+// Shift says where a payday falling on a weekend or holiday moves.
+type Shift string
+
+const (
+	ShiftNone     Shift = "none"
+	ShiftPrevious Shift = "previous" // to the previous business day
+	ShiftNext     Shift = "next"     // to the next business day
+)
+
+// Cycles defines pay cycles: every payday starts a new cycle. Explicit boundaries,
+// when configured, take precedence for the dates they cover.
+type Cycles struct {
+	Paydays    []Payday
+	Shift      Shift
+	Boundaries []time.Time // sorted cycle start dates (UTC); the last one only ends a cycle
+	calendar   Calendar
+}
+
+// ParsePaydays parses PAYDAYS, e.g. "5,20", "15,last" or "last-business".
+func ParsePaydays(s string) ([]Payday, error) {
+	var res []Payday
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch part {
+		case "":
+			continue
+		case "last":
+			res = append(res, Payday{Last: true})
+			continue
+		case "last-business", "lastbusiness":
+			res = append(res, Payday{LastBusiness: true})
+			continue
+		}
+		d, err := strconv.Atoi(part)
+		if err != nil || d < 1 || d > 31 {
+			return nil, fmt.Errorf("invalid payday %q: expected 1..31, last or last-business", part)
+		}
+		res = append(res, Payday{Day: d})
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no paydays given")
+	}
+	return res, nil
+}
+
+// ParseShift parses PAYDAY_SHIFT.
+func ParseShift(s string) (Shift, error) {
+	switch sh := Shift(strings.ToLower(strings.TrimSpace(s))); sh {
+	case "", ShiftNone:
+		return ShiftNone, nil
+	case ShiftPrevious, ShiftNext:
+		return sh, nil
+	}
+	return "", fmt.Errorf("invalid payday shift %q: expected none, previous or next", s)
+}
+
+// ParseBoundaries parses CYCLE_BOUNDARIES, a comma separated list of YYYY-MM-DD
+// cycle start dates. At least two dates are needed to form a cycle.
+func ParseBoundaries(s string) ([]time.Time, error) {
+	var res []time.Time
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.Parse(dateLayout, part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cycle boundary %q: expected YYYY-MM-DD", part)
+		}
+		res = append(res, d)
+	}
+	if len(res) == 1 {
+		return nil, fmt.Errorf("at least two cycle boundaries are needed")
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Before(res[j]) })
+	return res, nil
+}
+
+// Cycle returns the start of the cycle containing date and the next cycle start,
+// in date's location.
+func (c Cycles) Cycle(date time.Time) (time.Time, time.Time) {
+	loc := date.Location()
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	if start, next, ok := c.explicit(day); ok {
+		return start, next
+	}
+
+	// Shifted paydays may cross a month boundary, so look two months around date.
+	var dates []time.Time
+	for m := -2; m <= 2; m++ {
+		first := time.Date(day.Year(), day.Month()+time.Month(m), 1, 0, 0, 0, 0, loc)
+		for _, p := range c.paydays() {
+			dates = append(dates, c.payday(first, p))
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	var start, next time.Time
+	for _, d := range dates {
+		if !d.After(day) {
+			start = d
+		} else if next.IsZero() {
+			next = d
+		}
+	}
+	// A payday cycle next to the explicit boundaries ends at the first one or
+	// starts at the last one, so the cycles never overlap.
+	for _, b := range c.Boundaries {
+		b = time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, loc)
+		if !b.After(day) && b.After(start) {
+			start = b
+		} else if b.After(day) && b.Before(next) {
+			next = b
+		}
+	}
+	return start, next
+}
+
+// Budget returns the budget of the cycle [start, next) given the monthly budget:
+// an equal share per payday, or prorated by length for explicit boundaries and
+// the payday cycles cut short by them.
+func (c Cycles) Budget(start, next time.Time, monthly float64) float64 {
+	if _, _, ok := c.explicit(start); ok || c.boundary(start) || c.boundary(next) {
+		return monthly * float64(days(start, next)) / averageMonthDays
+	}
+	return monthly / float64(len(c.paydays()))
+}
+
+// Describe returns a short human readable description of the cycle model.
+func (c Cycles) Describe() string {
+	names := make([]string, 0, len(c.paydays()))
+	for _, p := range c.paydays() {
+		names = append(names, p.String())
+	}
+	s := "paydays " + strings.Join(names, ", ")
+	if c.Shift != "" && c.Shift != ShiftNone {
+		s += fmt.Sprintf(" (weekends and holidays shift to the %s business day)", c.Shift)
+	}
+	if len(c.Boundaries) > 1 {
+		s += fmt.Sprintf("; explicit boundaries %s .. %s", c.Boundaries[0].Format(dateLayout), c.Boundaries[len(c.Boundaries)-1].Format(dateLayout))
+	}
+	return s
+}
+
+func (c Cycles) paydays() []Payday {
+	if len(c.Paydays) == 0 {
+		return []Payday{{Day: 15}}
+	}
+	return c.Paydays
+}
+
+// explicit finds the configured boundaries around day, if any.
+func (c Cycles) explicit(day time.Time) (time.Time, time.Time, bool) {
+	key := day.Format(dateLayout)
+	for i := 1; i < len(c.Boundaries); i++ {
+		start, next := c.Boundaries[i-1], c.Boundaries[i]
+		if key >= start.Format(dateLayout) && key < next.Format(dateLayout) {
+			loc := day.Location()
+			return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc),
+				time.Date(next.Year(), next.Month(), next.Day(), 0, 0, 0, 0, loc), true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// boundary reports whether day is one of the explicit boundaries.
+func (c Cycles) boundary(day time.Time) bool {
+	key := day.Format(dateLayout)
+	for _, b := range c.Boundaries {
+		if b.Format(dateLayout) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// payday returns the date of payday p in the month starting at first.
+func (c Cycles) payday(first time.Time, p Payday) time.Time {
+	last := first.AddDate(0, 1, -1)
+	if p.LastBusiness {
+		d := last
+		for c.calendar.IsDayOff(d) {
+			d = d.AddDate(0, 0, -1)
+		}
+		return d
+	}
+	d := last
+	if !p.Last {
+		d = first.AddDate(0, 0, min(p.Day, last.Day())-1)
+	}
+	step := 0
+	switch c.Shift {
+	case ShiftPrevious:
+		step = -1
+	case ShiftNext:
+		step = 1
+	}
+	for step != 0 && c.calendar.IsDayOff(d) {
+		d = d.AddDate(0, 0, step)
+	}
+	return d
+}
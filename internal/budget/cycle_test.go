@@ -0,0 +1,110 @@
+package budget
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCyclesCycle(t *testing.T) {
+	t.Parallel()
+
+	newYearsEve := Calendar{Holidays: map[string]bool{"2025-12-31": true}}
+	boundaries := []time.Time{date("2025-01-10"), date("2025-02-07"), date("2025-03-07")}
+
+	tests := []struct {
+		name      string
+		cycles    Cycles
+		date      string
+		wantStart string
+		wantNext  string
+	}{
+		{"two paydays", Cycles{Paydays: []Payday{{Day: 5}, {Day: 20}}}, "2025-08-12", "2025-08-05", "2025-08-20"},
+		{"two paydays, second half", Cycles{Paydays: []Payday{{Day: 5}, {Day: 20}}}, "2025-08-25", "2025-08-20", "2025-09-05"},
+		{"weekend payday moves to Friday", Cycles{Paydays: []Payday{{Day: 5}, {Day: 20}}, Shift: ShiftPrevious}, "2025-10-04", "2025-10-03", "2025-10-20"},
+		{"shift across the previous cycle", Cycles{Paydays: []Payday{{Day: 5}, {Day: 20}}, Shift: ShiftPrevious}, "2025-10-02", "2025-09-19", "2025-10-03"},
+		{"weekend payday moves to Monday", Cycles{Paydays: []Payday{{Day: 15}}, Shift: ShiftNext}, "2025-11-16", "2025-10-15", "2025-11-17"},
+		{"day clamped to month end", Cycles{Paydays: []Payday{{Day: 31}}}, "2025-03-01", "2025-02-28", "2025-03-31"},
+		{"last business day", Cycles{Paydays: []Payday{{LastBusiness: true}}, calendar: newYearsEve}, "2025-11-29", "2025-11-28", "2025-12-30"},
+		{"explicit boundaries", Cycles{Paydays: []Payday{{Day: 15}}, Boundaries: boundaries}, "2025-02-01", "2025-01-10", "2025-02-07"},
+		{"outside explicit boundaries", Cycles{Paydays: []Payday{{Day: 15}}, Boundaries: boundaries}, "2025-03-20", "2025-03-15", "2025-04-15"},
+		{"payday cycle after the last boundary", Cycles{Paydays: []Payday{{Day: 15}}, Boundaries: boundaries}, "2025-03-10", "2025-03-07", "2025-03-15"},
+		{"payday cycle before the first boundary", Cycles{Paydays: []Payday{{Day: 15}}, Boundaries: boundaries}, "2024-12-20", "2024-12-15", "2025-01-10"},
+		{"payday after the last boundary", Cycles{Paydays: []Payday{{Day: 15}}, Boundaries: []time.Time{date("2025-01-10"), date("2025-02-10")}}, "2025-02-12", "2025-02-10", "2025-02-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start, next := tt.cycles.Cycle(date(tt.date))
+			if start.Format(dateLayout) != tt.wantStart || next.Format(dateLayout) != tt.wantNext {
+				t.Errorf("Cycle(%s) = %s .. %s, want %s .. %s", tt.date, start.Format(dateLayout), next.Format(dateLayout), tt.wantStart, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestCyclesBudget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		cycles Cycles
+		start  string
+		next   string
+		want   float64
+	}{
+		{"single payday", Cycles{Paydays: []Payday{{Day: 15}}}, "2025-08-15", "2025-09-15", 12000},
+		{"two paydays", Cycles{Paydays: []Payday{{Day: 5}, {Day: 20}}}, "2025-08-05", "2025-08-20", 6000},
+		{"explicit boundaries prorated", Cycles{Boundaries: []time.Time{date("2025-01-10"), date("2025-02-07")}}, "2025-01-10", "2025-02-07", 12000 * 28 / averageMonthDays},
+		{"payday cycle cut by a boundary prorated", Cycles{Boundaries: []time.Time{date("2025-01-10"), date("2025-02-07")}}, "2025-02-07", "2025-02-15", 12000 * 8 / averageMonthDays},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.cycles.Budget(date(tt.start), date(tt.next), 12000); math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("Budget() = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePaydays(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "5,20", want: "5,20"},
+		{in: " 15 , last ", want: "15,last"},
+		{in: "last-business", want: "last-business"},
+		{in: "0", wantErr: true},
+		{in: "32", wantErr: true},
+		{in: "friday", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParsePaydays(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePaydays(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			names := make([]string, 0, len(got))
+			for _, p := range got {
+				names = append(names, p.String())
+			}
+			if s := strings.Join(names, ","); s != tt.want {
+				t.Errorf("ParsePaydays(%q) = %s, want %s", tt.in, s, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package budget
+
+import "time"
+
+// Day is the discretionary spend of a day against its allowance.
+type Day struct {
+	Date      time.Time
+	Spent     float64 // discretionary spend, fixed costs excluded
+	Allowance float64 // the day's share of the cycle's discretionary budget
+}
+
+// Days returns every day from from through to with its discretionary spend and
+// allowance. The allowance spreads the discretionary budget of the day's cycle
+// (budget minus fixed costs plus carry) by the allowance profile, like the budget
+// line of the graph.
+func (p *Planner) Days(from, to time.Time, monthly float64) []Day {
+	spent := map[string]float64{}
+	for _, tx := range p.data.GetAllTransactions() {
+		if !p.settings.IsFixed(tx) {
+			spent[tx.Date] += tx.Amount
+		}
+	}
+
+	prof := p.Profile()
+	var res []Day
+	var start, next time.Time
+	var discretionary float64
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if d.Equal(from) || !d.Before(next) {
+			start, next = p.Cycle(d)
+			fixed := p.FixedIn(start, next.AddDate(0, 0, -1))
+			discretionary = max(max(p.CycleBudget(start, next, monthly)-fixed, 0)+p.Carry(start, monthly), 0)
+		}
+		res = append(res, Day{
+			Date:      d,
+			Spent:     spent[d.Format(dateLayout)],
+			Allowance: allowanceOn(prof, discretionary, d, start, next),
+		})
+	}
+	return res
+}
+
+// allowanceOn returns the share of discretionary that the profile gives to day d
+// of the cycle [start, next).
+func allowanceOn(prof Profile, discretionary float64, d, start, next time.Time) float64 {
+	return discretionary * (prof.Share(start, d, next) - prof.Share(start, d.AddDate(0, 0, -1), next))
+}
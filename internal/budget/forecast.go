@@ -0,0 +1,147 @@
+package budget
+
+import (
+	"sort"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+)
+
+// forecastHistory is the number of previous cycles used for same-cycle patterns.
+const forecastHistory = 6
+
+// Forecast is the projected total spend of a cycle at NextCycleStart, as a band.
+type Forecast struct {
+	Date           time.Time
+	CycleStart     time.Time
+	NextCycleStart time.Time
+	RemainingDays  int
+
+	Budget    float64 // cycle budget plus carry
+	Spent     float64 // everything spent from cycle start through Date, fixed costs included
+	Committed float64 // fixed costs due after Date: entered ahead or scheduled
+
+	Pace    float64 // discretionary spend per day so far
+	History int     // previous cycles that contributed a same-cycle rate
+
+	// Discretionary spend per remaining day behind each forecast.
+	LowRate, Rate, HighRate float64
+	// Total spend of the cycle.
+	Optimistic, Expected, Pessimistic float64
+}
+
+// Over reports whether the expected spend exceeds the budget.
+func (f Forecast) Over() bool {
+	return f.Expected > f.Budget
+}
+
+// Forecast projects the spend of the cycle containing date.
+func (p *Planner) Forecast(date time.Time, monthly float64) Forecast {
+	start, next := p.Cycle(date)
+	last := next.AddDate(0, 0, -1)
+	var upcoming []recurring.Charge
+	if after := date.AddDate(0, 0, 1); !after.After(last) {
+		upcoming = p.templates.Upcoming(after, last)
+	}
+	budget := p.CycleBudget(start, next, monthly) + p.Carry(start, monthly)
+	return forecast(p.data.GetAllTransactions(), upcoming, p.settings, date, budget)
+}
+
+// forecast combines three signals: the pace of discretionary spending so far, the
+// rate at which the rest of the previous cycles was spent from the same day on,
+// and the fixed costs that are already known. The pace is trusted more the further
+// the cycle has progressed.
+func forecast(txs []data.Transaction, upcoming []recurring.Charge, s Settings, date time.Time, budget float64) Forecast {
+	cycles := s.cycles()
+	start, next := cycles.Cycle(date)
+	f := Forecast{
+		Date:           date,
+		CycleStart:     start,
+		NextCycleStart: next,
+		RemainingDays:  max(days(date, next)-1, 0),
+		Budget:         budget,
+	}
+	dayIndex := max(days(start, date)+1, 1)
+	cycleDays := max(days(start, next), 1)
+
+	dateStr := date.Format(dateLayout)
+	startStr, lastStr := start.Format(dateLayout), next.AddDate(0, 0, -1).Format(dateLayout)
+	first := ""
+	var discretionary float64
+	for _, tx := range txs {
+		if first == "" || tx.Date < first {
+			first = tx.Date
+		}
+		if tx.Date < startStr || tx.Date > lastStr {
+			continue
+		}
+		fixed := s.IsFixed(tx)
+		switch {
+		case tx.Date <= dateStr:
+			f.Spent += tx.Amount
+			if !fixed {
+				discretionary += tx.Amount
+			}
+		case fixed:
+			f.Committed += tx.Amount
+		}
+	}
+	for _, c := range upcoming {
+		f.Committed += c.Template.Amount
+	}
+	f.Pace = discretionary / float64(dayIndex)
+
+	// Same-cycle pattern: how fast the rest of earlier cycles went, from the same day on.
+	var rates []float64
+	ps, pn := cycles.Cycle(start.AddDate(0, 0, -1))
+	for k := 0; k < forecastHistory && first != "" && pn.Format(dateLayout) > first; k++ {
+		from := ps.AddDate(0, 0, dayIndex)
+		if n := days(from, pn); n > 0 {
+			fromStr, toStr := from.Format(dateLayout), pn.AddDate(0, 0, -1).Format(dateLayout)
+			var spent float64
+			for _, tx := range txs {
+				if !s.IsFixed(tx) && tx.Date >= fromStr && tx.Date <= toStr {
+					spent += tx.Amount
+				}
+			}
+			rates = append(rates, spent/float64(n))
+		}
+		ps, pn = cycles.Cycle(ps.AddDate(0, 0, -1))
+	}
+	f.History = len(rates)
+
+	f.Rate = f.Pace
+	if len(rates) > 0 {
+		w := float64(dayIndex) / float64(cycleDays)
+		f.Rate = w*f.Pace + (1-w)*median(rates)
+	}
+	f.LowRate, f.HighRate = f.Rate, f.Rate
+	for _, r := range append(rates, f.Pace) {
+		f.LowRate, f.HighRate = min(f.LowRate, r), max(f.HighRate, r)
+	}
+	// Too little history to know the spread: assume ±20%.
+	if len(rates) < 2 {
+		f.LowRate, f.HighRate = min(f.LowRate, f.Rate*0.8), max(f.HighRate, f.Rate*1.2)
+	}
+
+	known := f.Spent + f.Committed
+	remaining := float64(f.RemainingDays)
+	f.Optimistic = known + f.LowRate*remaining
+	f.Expected = known + f.Rate*remaining
+	f.Pessimistic = known + f.HighRate*remaining
+	return f
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,72 @@
+package budget
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+)
+
+func TestForecast(t *testing.T) {
+	t.Parallel()
+
+	// 100 RUB of groceries on each of the first ten days of August.
+	var august []data.Transaction
+	for d := 1; d <= 10; d++ {
+		august = append(august, data.Transaction{Date: fmt.Sprintf("2025-08-%02d", d), Category: "groceries", Amount: 100})
+	}
+
+	tests := []struct {
+		name            string
+		txs             []data.Transaction
+		upcoming        []recurring.Charge
+		wantOptimistic  float64
+		wantExpected    float64
+		wantPessimistic float64
+	}{
+		{
+			name: "pace and known fixed costs",
+			txs: append([]data.Transaction{
+				{Date: "2025-08-01", Category: "rent", Amount: 5000, Fixed: true},
+				{Date: "2025-08-25", Category: "internet", Amount: 500, Fixed: true},
+			}, august...),
+			upcoming: []recurring.Charge{{Date: date("2025-08-20"), Template: recurring.Template{Amount: 300}}},
+			// 6000 spent + 800 committed + 21 days at 80/100/120
+			wantOptimistic:  8480,
+			wantExpected:    8900,
+			wantPessimistic: 9320,
+		},
+		{
+			name: "same-cycle history",
+			txs: append([]data.Transaction{
+				{Date: "2025-06-20", Category: "groceries", Amount: 1000}, // 50 a day from June 11
+				{Date: "2025-07-15", Category: "groceries", Amount: 4200}, // 200 a day from July 11
+			}, august...),
+			// pace 100 weighted 10/31, median history 125 weighted 21/31
+			wantOptimistic:  1000 + 21*50,
+			wantExpected:    1000 + 21*(10*100+21*125)/31.0,
+			wantPessimistic: 1000 + 21*200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			f := forecast(tt.txs, tt.upcoming, Settings{SalaryDay: 1}, date("2025-08-10"), 9000)
+			for _, c := range []struct {
+				field     string
+				got, want float64
+			}{
+				{"Optimistic", f.Optimistic, tt.wantOptimistic},
+				{"Expected", f.Expected, tt.wantExpected},
+				{"Pessimistic", f.Pessimistic, tt.wantPessimistic},
+			} {
+				if math.Abs(c.got-c.want) > 0.001 {
+					t.Errorf("%s = %.2f, want %.2f", c.field, c.got, c.want)
+				}
+			}
+		})
+	}
+}
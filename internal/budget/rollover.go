@@ -0,0 +1,135 @@
+package budget
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+)
+
+// Rollover says how the result of a finished cycle (discretionary budget plus carry
+// minus spending) is carried into the next cycle.
+type Rollover struct {
+	Policy string  // ROLLOVER_POLICY
+	Cap    float64 // ROLLOVER_CAP, limit of the capped policy in both directions
+}
+
+const (
+	RolloverNone     = "none"     // every cycle starts from zero
+	RolloverFull     = "full"     // savings and overspending are carried in full
+	RolloverCapped   = "capped"   // carried in full, limited to ±Cap
+	RolloverNegative = "negative" // only overspending is carried, as a penalty
+)
+
+// ParseRollover parses ROLLOVER_POLICY.
+func ParseRollover(s string) (string, error) {
+	switch p := strings.ToLower(strings.TrimSpace(s)); p {
+	case "":
+		return RolloverNone, nil
+	case RolloverNone, RolloverFull, RolloverCapped, RolloverNegative:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid rollover policy %q: expected none, full, capped or negative", s)
+}
+
+// Apply returns the part of a cycle result that is carried into the next cycle.
+func (r Rollover) Apply(result float64) float64 {
+	switch r.Policy {
+	case RolloverFull:
+		return result
+	case RolloverCapped:
+		return max(-r.Cap, min(r.Cap, result))
+	case RolloverNegative:
+		return min(result, 0)
+	}
+	return 0
+}
+
+// Enabled reports whether anything is ever carried over.
+func (r Rollover) Enabled() bool {
+	return r.Policy != "" && r.Policy != RolloverNone
+}
+
+// CycleResult is the outcome of a finished cycle.
+type CycleResult struct {
+	Start         time.Time
+	Next          time.Time
+	Discretionary float64 // cycle budget minus fixed costs, never negative
+	Spent         float64 // discretionary spending
+	CarryIn       float64
+	Result        float64 // Discretionary + CarryIn - Spent
+	CarryOut      float64 // part of Result carried into the next cycle
+}
+
+// Carry returns the amount carried into the cycle starting at cycleStart, recomputed
+// from the whole history with the given monthly budget.
+func (p *Planner) Carry(cycleStart time.Time, monthly float64) float64 {
+	if !p.settings.Rollover.Enabled() {
+		return 0
+	}
+	return carryInto(p.data.GetAllTransactions(), p.templates.Upcoming, p.settings, cycleStart, monthly)
+}
+
+// Results returns the results of all cycles finished before the cycle containing date.
+func (p *Planner) Results(date time.Time, monthly float64) []CycleResult {
+	start, _ := p.Cycle(date)
+	return cycleResults(p.data.GetAllTransactions(), p.templates.Upcoming, p.settings, start, monthly)
+}
+
+// carryInto returns the carry out of the last cycle before cycleStart.
+func carryInto(txs []data.Transaction, upcoming func(from, to time.Time) []recurring.Charge, s Settings, cycleStart time.Time, monthly float64) float64 {
+	results := cycleResults(txs, upcoming, s, cycleStart, monthly)
+	if len(results) == 0 {
+		return 0
+	}
+	return results[len(results)-1].CarryOut
+}
+
+// cycleResults walks the cycles from the one holding the first transaction up to
+// cycleStart and carries each cycle result forward. It only depends on the ledger
+// and the settings, so recomputing it always gives the same amounts.
+func cycleResults(txs []data.Transaction, upcoming func(from, to time.Time) []recurring.Charge, s Settings, cycleStart time.Time, monthly float64) []CycleResult {
+	loc := cycleStart.Location()
+	first := ""
+	for _, tx := range txs {
+		if first == "" || tx.Date < first {
+			first = tx.Date
+		}
+	}
+	firstDate, err := time.ParseInLocation(dateLayout, first, loc)
+	if err != nil {
+		return nil
+	}
+
+	cycles := s.cycles()
+	var res []CycleResult
+	var carry float64
+	start, next := cycles.Cycle(firstDate)
+	for start.Before(cycleStart) && next.After(start) {
+		last := next.AddDate(0, 0, -1)
+		var charges []recurring.Charge
+		if upcoming != nil {
+			charges = upcoming(start, last)
+		}
+		r := CycleResult{
+			Start:         start,
+			Next:          next,
+			Discretionary: max(cycles.Budget(start, next, monthly)-fixedIn(txs, charges, s, start, last), 0),
+			CarryIn:       carry,
+		}
+		fromStr, toStr := start.Format(dateLayout), last.Format(dateLayout)
+		for _, tx := range txs {
+			if !s.IsFixed(tx) && tx.Date >= fromStr && tx.Date <= toStr {
+				r.Spent += tx.Amount
+			}
+		}
+		r.Result = r.Discretionary + r.CarryIn - r.Spent
+		r.CarryOut = s.Rollover.Apply(r.Result)
+		carry = r.CarryOut
+		res = append(res, r)
+		start, next = cycles.Cycle(next)
+	}
+	return res
+}
@@ -0,0 +1,46 @@
+package budget
+
+import (
+	"math"
+	"testing"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+func TestCarryInto(t *testing.T) {
+	t.Parallel()
+
+	// June: 3000 budget, 2000 spent -> +1000.
+	// July: 3000 budget, 1000 fixed, 3500 spent -> -1500 before carry.
+	txs := []data.Transaction{
+		{Date: "2025-06-10", Category: "groceries", Amount: 2000},
+		{Date: "2025-07-01", Category: "rent", Amount: 1000, Fixed: true},
+		{Date: "2025-07-05", Category: "groceries", Amount: 3500},
+	}
+
+	tests := []struct {
+		name     string
+		rollover Rollover
+		cycle    string
+		want     float64
+	}{
+		{"none", Rollover{Policy: RolloverNone}, "2025-08-01", 0},
+		{"full", Rollover{Policy: RolloverFull}, "2025-08-01", -500},
+		{"full after the first cycle", Rollover{Policy: RolloverFull}, "2025-07-01", 1000},
+		{"capped", Rollover{Policy: RolloverCapped, Cap: 200}, "2025-08-01", -200},
+		{"capped surplus", Rollover{Policy: RolloverCapped, Cap: 200}, "2025-07-01", 200},
+		{"negative only", Rollover{Policy: RolloverNegative}, "2025-08-01", -1500},
+		{"negative ignores savings", Rollover{Policy: RolloverNegative}, "2025-07-01", 0},
+		{"before history", Rollover{Policy: RolloverFull}, "2025-05-01", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := Settings{SalaryDay: 1, Rollover: tt.rollover}
+			if got := carryInto(txs, nil, s, date(tt.cycle), 3000); math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("carryInto(%s) = %.2f, want %.2f", tt.cycle, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package budget
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Calendar marks public holidays and working weekends (transferred working days),
+// e.g. from the Russian production calendar.
+type Calendar struct {
+	Holidays map[string]bool // YYYY-MM-DD days off on top of weekends
+	Workdays map[string]bool // YYYY-MM-DD weekend days that are working days
+}
+
+// LoadCalendar reads a holiday calendar file, see ParseCalendar.
+func LoadCalendar(path string) (Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Calendar{}, err
+	}
+	defer f.Close()
+	return ParseCalendar(f)
+}
+
+// ParseCalendar parses one day per line:
+//
+//	# comment
+//	2025-01-01            holiday
+//	2025-11-01,workday    working Saturday
+func ParseCalendar(r io.Reader) (Calendar, error) {
+	c := Calendar{Holidays: map[string]bool{}, Workdays: map[string]bool{}}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		date, kind, _ := strings.Cut(text, ",")
+		date, kind = strings.TrimSpace(date), strings.ToLower(strings.TrimSpace(kind))
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return Calendar{}, fmt.Errorf("invalid date on line %d: %q", line, date)
+		}
+		switch kind {
+		case "", "holiday":
+			c.Holidays[date] = true
+		case "workday":
+			c.Workdays[date] = true
+		default:
+			return Calendar{}, fmt.Errorf("invalid day kind on line %d: %q (expected holiday or workday)", line, kind)
+		}
+	}
+	return c, scanner.Err()
+}
+
+// IsDayOff reports whether d is a weekend or a holiday and not a transferred working day.
+func (c Calendar) IsDayOff(d time.Time) bool {
+	key := d.Format(dateLayout)
+	if c.Workdays[key] {
+		return false
+	}
+	return c.Holidays[key] || d.Weekday() == time.Saturday || d.Weekday() == time.Sunday
+}
+
+// Profile assigns a spending weight to every day. The discretionary budget is
+// distributed over the days of a cycle proportionally to their weights.
+type Profile struct {
+	Name     string
+	Weekdays [7]float64 // indexed by time.Weekday
+	Holiday  float64    // weight of public holidays; 0 means holidays are ordinary days
+	calendar Calendar
+}
+
+const (
+	ProfileEven    = "even"
+	ProfileWeekend = "weekend"
+	ProfileCustom  = "custom"
+)
+
+func evenProfile() Profile {
+	return Profile{Name: ProfileEven, Weekdays: [7]float64{1, 1, 1, 1, 1, 1, 1}}
+}
+
+func weekendProfile(cal Calendar) Profile {
+	// Sunday .. Saturday
+	return Profile{Name: ProfileWeekend, Weekdays: [7]float64{1.5, 1, 1, 1, 1, 1.2, 1.5}, Holiday: 1.5, calendar: cal}
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseWeights parses DAY_WEIGHTS, e.g. "fri=1.2,sat=1.6,sun=1.4,holiday=1.5".
+// Unlisted weekdays weigh 1; holidays default to the Sunday weight.
+func ParseWeights(s string, cal Calendar) (Profile, error) {
+	p := evenProfile()
+	p.Name = ProfileCustom
+	p.calendar = cal
+	holidaySet := false
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Profile{}, fmt.Errorf("invalid day weight %q: expected day=weight", part)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 {
+			return Profile{}, fmt.Errorf("invalid day weight %q: weight must be a non-negative number", part)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "holiday" {
+			p.Holiday = w
+			holidaySet = true
+			continue
+		}
+		wd, ok := weekdayNames[name[:min(3, len(name))]]
+		if !ok {
+			return Profile{}, fmt.Errorf("invalid day weight %q: unknown day", part)
+		}
+		p.Weekdays[wd] = w
+	}
+	if !holidaySet {
+		p.Holiday = p.Weekdays[time.Sunday]
+	}
+	return p, nil
+}
+
+// Weight returns the weight of day d. Transferred working weekends weigh like a Monday.
+func (p Profile) Weight(d time.Time) float64 {
+	key := d.Format(dateLayout)
+	switch {
+	case p.calendar.Workdays[key]:
+		return p.Weekdays[time.Monday]
+	case p.Holiday > 0 && p.calendar.Holidays[key]:
+		return p.Holiday
+	}
+	return p.Weekdays[d.Weekday()]
+}
+
+// WeightBetween sums the weights of the days in [from, to).
+func (p Profile) WeightBetween(from, to time.Time) float64 {
+	var total float64
+	for d := from; d.Before(to); d = d.AddDate(0, 0, 1) {
+		total += p.Weight(d)
+	}
+	return total
+}
+
+// Share returns the part of the days in [from, to) that falls into [from, through],
+// by weight. It falls back to an even split when all days weigh zero.
+func (p Profile) Share(from, through, to time.Time) float64 {
+	total := p.WeightBetween(from, to)
+	if total <= 0 {
+		return float64(days(from, through)+1) / float64(max(days(from, to), 1))
+	}
+	return p.WeightBetween(from, through.AddDate(0, 0, 1)) / total
+}
+
+// ProfileNames returns the names of the available profiles in a stable order.
+func (s Settings) ProfileNames() []string {
+	names := make([]string, 0, len(s.Profiles))
+	for name := range s.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
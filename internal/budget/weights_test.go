@@ -0,0 +1,142 @@
+package budget
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseCalendar(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		in           string
+		wantHolidays int
+		wantWorkdays int
+		wantErr      bool
+	}{
+		{"holidays and comments", "# New year\n2025-01-01\n2025-01-02,holiday\n\n", 2, 0, false},
+		{"working saturday", "2025-11-01,workday\n2025-11-04", 1, 1, false},
+		{"bad date", "2025-13-01", 0, 0, true},
+		{"bad kind", "2025-01-01,vacation", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cal, err := ParseCalendar(strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCalendar() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(cal.Holidays) != tt.wantHolidays || len(cal.Workdays) != tt.wantWorkdays {
+				t.Errorf("ParseCalendar() = %d holidays, %d workdays, want %d, %d", len(cal.Holidays), len(cal.Workdays), tt.wantHolidays, tt.wantWorkdays)
+			}
+		})
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	t.Parallel()
+
+	cal := Calendar{Holidays: map[string]bool{"2025-01-01": true}, Workdays: map[string]bool{"2025-11-01": true}}
+	tests := []struct {
+		name    string
+		in      string
+		day     string
+		want    float64
+		wantErr bool
+	}{
+		{"unlisted weekday", "sat=2", "2025-08-04", 1, false},
+		{"listed weekday", "sat=2", "2025-08-02", 2, false},
+		{"holiday defaults to sunday", "sun=1.5", "2025-01-01", 1.5, false},
+		{"explicit holiday", "sun=1.5,holiday=3", "2025-01-01", 3, false},
+		{"working saturday weighs like monday", "sat=2,mon=0.8", "2025-11-01", 0.8, false},
+		{"full day names", "Saturday=1.7", "2025-08-02", 1.7, false},
+		{"missing weight", "sat", "", 0, true},
+		{"negative weight", "sat=-1", "", 0, true},
+		{"unknown day", "xyz=1", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := ParseWeights(tt.in, cal)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWeights(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := p.Weight(date(tt.day)); got != tt.want {
+				t.Errorf("Weight(%s) = %v, want %v", tt.day, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeightedAllowance(t *testing.T) {
+	t.Parallel()
+
+	// A week from Monday to Sunday: five weekdays weigh 1, the weekend 2.5 each, total 10.
+	prof, err := ParseWeights("sat=2.5,sun=2.5", Calendar{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, next := date("2025-08-04"), date("2025-08-11")
+
+	tests := []struct {
+		name         string
+		date         string
+		wantAllowed  float64
+		wantTomorrow float64
+	}{
+		{"monday", "2025-08-04", 100, 1000.0 / 9},
+		{"friday", "2025-08-08", 500, 500},
+		{"saturday", "2025-08-09", 750, 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			st := calculate(nil, nil, Settings{}, prof, date(tt.date), start, next, 1000, 0)
+			if math.Abs(st.Allowed-tt.wantAllowed) > 0.001 {
+				t.Errorf("Allowed = %.2f, want %.2f", st.Allowed, tt.wantAllowed)
+			}
+			if math.Abs(st.Tomorrow-tt.wantTomorrow) > 0.001 {
+				t.Errorf("Tomorrow = %.2f, want %.2f", st.Tomorrow, tt.wantTomorrow)
+			}
+		})
+	}
+}
+
+func TestAllowanceOn(t *testing.T) {
+	t.Parallel()
+
+	weekend, err := ParseWeights("sat=2.5,sun=2.5", Calendar{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, next := date("2025-08-04"), date("2025-08-11")
+
+	tests := []struct {
+		name string
+		prof Profile
+		date string
+		want float64
+	}{
+		{"even first day", evenProfile(), "2025-08-04", 1000.0 / 7},
+		{"even last day", evenProfile(), "2025-08-10", 1000.0 / 7},
+		{"weighted weekday", weekend, "2025-08-06", 100},
+		{"weighted saturday", weekend, "2025-08-09", 250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := allowanceOn(tt.prof, 1000, date(tt.date), start, next); math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("allowanceOn(%s) = %.2f, want %.2f", tt.date, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,523 @@
+package category
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var header = []string{"ID", "Name", "Emoji", "Color", "Parent", "Archived", "Aliases"}
+
+var (
+	// ErrNotFound is returned when a category with the given ID does not exist.
+	ErrNotFound = errors.New("category not found")
+	// ErrHasChildren is returned when deleting a category that still has subcategories.
+	ErrHasChildren = errors.New("category has subcategories")
+	// ErrChanged is returned by a conditional write when the stored category no
+	// longer matches the one the caller saw.
+	ErrChanged = errors.New("category has changed")
+)
+
+var (
+	idPattern    = regexp.MustCompile(`^[\p{Ll}\p{Nd}][\p{Ll}\p{Nd}_-]*$`)
+	colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// Category is a managed expense category. Transactions store its ID; the name,
+// emoji and color are only for display, so they can change without rewriting
+// the ledger.
+type Category struct {
+	ID       string   // stable lower-case slug, e.g. groceries
+	Name     string   // display name
+	Emoji    string   // optional
+	Color    string   // optional, #rrggbb
+	Parent   string   // ID of the parent category, empty for a top-level one
+	Archived bool     // hidden from pickers; existing transactions keep it
+	Aliases  []string // other spellings that resolve to this category, lower-cased
+}
+
+// Label is the emoji and name, e.g. "🛒 Groceries".
+func (c Category) Label() string {
+	if c.Emoji == "" {
+		return c.Name
+	}
+	return c.Emoji + " " + c.Name
+}
+
+// Defaults are the categories of a new store, the ones the Mini App offered
+// before categories were managed.
+var Defaults = []Category{
+	{ID: "groceries", Name: "Супермаркеты", Emoji: "🛒", Aliases: []string{"продукты", "супермаркет"}},
+	{ID: "dining", Name: "Рестораны и кафе", Emoji: "🍽️", Aliases: []string{"кафе", "рестораны", "ресторан"}},
+	{ID: "transport", Name: "Транспорт", Emoji: "🚌"},
+	{ID: "scooters", Name: "Самокаты/прокат", Emoji: "🛴", Aliases: []string{"самокаты", "самокат"}},
+	{ID: "health", Name: "Здоровье и аптеки", Emoji: "🏥", Aliases: []string{"здоровье", "аптека", "аптеки"}},
+	{ID: "entertainment", Name: "Развлечения/отдых", Emoji: "🎬", Aliases: []string{"развлечения", "отдых"}},
+	{ID: "other", Name: "Прочее", Emoji: "📦"},
+}
+
+// Store keeps the managed categories in a CSV file next to the ledger.
+type Store struct {
+	mu         sync.Mutex
+	path       string
+	categories []Category
+}
+
+// New loads the categories; a missing file starts with Defaults.
+func New(path string) (*Store, error) {
+	s := &Store{path: path}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		for _, c := range Defaults {
+			c.Aliases = append([]string(nil), c.Aliases...)
+			s.categories = append(s.categories, c)
+		}
+		return s, s.save()
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if strings.Join(records[0], ",") != strings.Join(header, ",") {
+		return errors.New("categories CSV header does not match expected format")
+	}
+	for i, r := range records[1:] {
+		archived, err := strconv.ParseBool(r[5])
+		if err != nil {
+			return fmt.Errorf("invalid archived flag on line %d: %w", i+2, err)
+		}
+		c := Category{ID: r[0], Name: r[1], Emoji: r[2], Color: r[3], Parent: r[4], Archived: archived}
+		if r[6] != "" {
+			c.Aliases = strings.Split(r[6], "|")
+		}
+		s.categories = append(s.categories, c)
+	}
+	return nil
+}
+
+// save persists the categories; callers must hold s.mu.
+func (s *Store) save() error {
+	file, err := os.Create(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	for _, c := range s.categories {
+		err := writer.Write([]string{c.ID, c.Name, c.Emoji, c.Color, c.Parent, strconv.FormatBool(c.Archived), strings.Join(c.Aliases, "|")})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func key(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// List returns the categories as a tree: each top-level category followed by
+// its subcategories, depth first, in the order they were added.
+func (s *Store) List(includeArchived bool) []Category {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []Category
+	var walk func(parent string)
+	walk = func(parent string) {
+		for _, c := range s.categories {
+			if c.Parent != parent || (c.Archived && !includeArchived) {
+				continue
+			}
+			c.Aliases = append([]string(nil), c.Aliases...)
+			res = append(res, c)
+			walk(c.ID)
+		}
+	}
+	walk("")
+	return res
+}
+
+// Get returns the category with the given ID.
+func (s *Store) Get(id string) (Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.indexOf(key(id)); i >= 0 {
+		c := s.categories[i]
+		c.Aliases = append([]string(nil), c.Aliases...)
+		return c, nil
+	}
+	return Category{}, ErrNotFound
+}
+
+// indexOf returns the index of the category with the given ID, or -1; callers
+// must hold s.mu.
+func (s *Store) indexOf(id string) int {
+	for i, c := range s.categories {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Resolve returns the category that name refers to, by ID, display name or alias,
+// ignoring case. A path such as food/cafes resolves to cafes when food refers to
+// its parent.
+func (s *Store) Resolve(name string) (Category, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.resolve(key(name))
+	if i < 0 {
+		i = s.resolvePath(strings.Split(key(name), "/"))
+	}
+	if i < 0 {
+		return Category{}, false
+	}
+	return s.categories[i], true
+}
+
+// resolvePath returns the index of the category that the last segment refers
+// to when each earlier segment refers to its parent in turn, or -1; callers must
+// hold s.mu.
+func (s *Store) resolvePath(segments []string) int {
+	i := s.resolve(key(segments[len(segments)-1]))
+	for j, c := len(segments)-2, i; j >= 0 && c >= 0; j-- {
+		p := s.resolve(key(segments[j]))
+		if p < 0 || s.categories[c].Parent != s.categories[p].ID {
+			return -1
+		}
+		c = p
+	}
+	return i
+}
+
+// resolve returns the index of the category that k refers to, or -1; callers
+// must hold s.mu.
+func (s *Store) resolve(k string) int {
+	if k == "" {
+		return -1
+	}
+	for i, c := range s.categories {
+		if c.ID == k || key(c.Name) == k {
+			return i
+		}
+		for _, a := range c.Aliases {
+			if a == k {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Normalize returns the ID of the category that name refers to, or name unchanged
+// when it is not a managed category. It is applied to every transaction written to
+// the ledger, so spellings and aliases end up as one category. A path whose start
+// is managed keeps its unmanaged rest below that category, e.g. Продукты/овощи
+// becomes groceries/овощи.
+func (s *Store) Normalize(name string) string {
+	if c, ok := s.Resolve(name); ok {
+		return c.ID
+	}
+	segments := strings.Split(strings.TrimSpace(name), "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for n := len(segments) - 1; n > 0; n-- {
+		if i := s.resolvePath(segments[:n]); i >= 0 {
+			return s.categories[i].ID + "/" + strings.Join(segments[n:], "/")
+		}
+	}
+	return name
+}
+
+// Path returns the IDs from the top-level category down to id joined by /, e.g.
+// dining/cafes, or id itself when it is not a managed category.
+func (s *Store) Path(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := []string{id}
+	// Bounded by the number of categories in case the file holds a cycle.
+	for n := 0; n < len(s.categories); n++ {
+		i := s.indexOf(key(path[0]))
+		if i < 0 || s.categories[i].Parent == "" {
+			break
+		}
+		path = append([]string{s.categories[i].Parent}, path...)
+	}
+	return strings.Join(path, "/")
+}
+
+// Expand returns the categories that a filter for name matches in the ledger,
+// see data.InCategory: name as given, its normalized form and, for a managed
+// category, all its subcategories.
+func (s *Store) Expand(name string) []string {
+	return append([]string{name, s.Normalize(name)}, s.Subtree(name)...)
+}
+
+// Subtree returns the IDs of the category that name refers to and of all its
+// subcategories, or nil when name is not a managed category.
+func (s *Store) Subtree(name string) []string {
+	c, ok := s.Resolve(name)
+	if !ok {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := []string{c.ID}
+	for _, c := range s.categories {
+		if s.isAncestor(ids[0], c.ID) {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// clean lower-cases the ID and aliases and drops duplicate aliases and ones that
+// equal the ID.
+func clean(c Category) Category {
+	c.ID = key(c.ID)
+	c.Name = strings.TrimSpace(c.Name)
+	c.Emoji = strings.TrimSpace(c.Emoji)
+	c.Color = strings.ToLower(strings.TrimSpace(c.Color))
+	c.Parent = key(c.Parent)
+	seen := map[string]bool{c.ID: true}
+	aliases := []string{}
+	for _, a := range c.Aliases {
+		if a = key(a); a != "" && !seen[a] {
+			seen[a] = true
+			aliases = append(aliases, a)
+		}
+	}
+	c.Aliases = aliases
+	return c
+}
+
+// validate checks c against the other categories; callers must hold s.mu.
+// Every ID, name and alias must resolve to a single category.
+func (s *Store) validate(c Category) error {
+	if !idPattern.MatchString(c.ID) {
+		return fmt.Errorf("invalid ID %q: use lower-case letters, digits, - and _", c.ID)
+	}
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.Color != "" && !colorPattern.MatchString(c.Color) {
+		return fmt.Errorf("invalid color %q: expected #rrggbb", c.Color)
+	}
+	if c.Parent != "" {
+		if s.indexOf(c.Parent) < 0 {
+			return fmt.Errorf("parent %q not found", c.Parent)
+		}
+		if c.Parent == c.ID || s.isAncestor(c.ID, c.Parent) {
+			return errors.New("a category cannot be its own ancestor")
+		}
+	}
+	for _, k := range append([]string{c.ID, key(c.Name)}, c.Aliases...) {
+		if i := s.resolve(k); i >= 0 && s.categories[i].ID != c.ID {
+			return fmt.Errorf("%q already refers to category %s", k, s.categories[i].ID)
+		}
+	}
+	return nil
+}
+
+// isAncestor reports whether ancestor is a parent, grandparent and so on of id;
+// callers must hold s.mu.
+func (s *Store) isAncestor(ancestor, id string) bool {
+	// Bounded by the number of categories in case the file holds a cycle.
+	for n := 0; n <= len(s.categories); n++ {
+		i := s.indexOf(id)
+		if i < 0 || s.categories[i].Parent == "" {
+			return false
+		}
+		id = s.categories[i].Parent
+		if id == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// Create adds a category and returns it as stored.
+func (s *Store) Create(c Category) (Category, error) {
+	c = clean(c)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.indexOf(c.ID) >= 0 {
+		return Category{}, fmt.Errorf("category %s already exists", c.ID)
+	}
+	if err := s.validate(c); err != nil {
+		return Category{}, err
+	}
+	s.categories = append(s.categories, c)
+	return c, s.save()
+}
+
+// Update replaces the category with c.ID. The ID itself cannot change; merge
+// into a new category to rename an ID.
+func (s *Store) Update(c Category) (Category, error) {
+	return s.UpdateIf(c, nil)
+}
+
+// UpdateIf is Update, applied only if match accepts the stored category and
+// ErrChanged otherwise; the check and the write happen under one lock. A nil
+// match always accepts.
+func (s *Store) UpdateIf(c Category, match func(Category) bool) (Category, error) {
+	c = clean(c)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexOf(c.ID)
+	if i < 0 {
+		return Category{}, ErrNotFound
+	}
+	if match != nil && !match(s.categories[i]) {
+		return Category{}, ErrChanged
+	}
+	if err := s.validate(c); err != nil {
+		return Category{}, err
+	}
+	s.categories[i] = c
+	return c, s.save()
+}
+
+// Delete removes a category without subcategories. Transactions keep its ID as a
+// plain, unmanaged category; archive or merge it to keep history tidy.
+func (s *Store) Delete(id string) error {
+	return s.DeleteIf(id, nil)
+}
+
+// DeleteIf is Delete, applied only if match accepts the stored category, see
+// UpdateIf.
+func (s *Store) DeleteIf(id string, match func(Category) bool) error {
+	id = key(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.indexOf(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if match != nil && !match(s.categories[i]) {
+		return ErrChanged
+	}
+	for _, c := range s.categories {
+		if c.Parent == id {
+			return ErrHasChildren
+		}
+	}
+	s.categories = append(s.categories[:i], s.categories[i+1:]...)
+	return s.save()
+}
+
+// Ledger is a store referring to categories by name, which Merge rewrites, see
+// data.Data.Recategorize.
+type Ledger interface {
+	Recategorize(rename func(category string) (string, bool)) (int, error)
+}
+
+// Merge folds sources into target and rewrites the ledger, returning how many
+// transactions changed. A source is a managed category, whose ID, name and
+// aliases become aliases of target and whose subcategories move to target, or
+// any other spelling found in the ledger, which becomes an alias. Transactions
+// that resolve to target or a path below it afterwards are rewritten, see Normalize. The categories are
+// saved first, so if rewriting the ledger fails, merging again completes it.
+// The other stores naming categories, e.g. envelopes and recurring templates,
+// are rewritten the same way after the ledger.
+func (s *Store) Merge(ledger Ledger, sources []string, target string, others ...Ledger) (int, error) {
+	target = key(target)
+	s.mu.Lock()
+	ti := s.indexOf(target)
+	if ti < 0 {
+		s.mu.Unlock()
+		return 0, ErrNotFound
+	}
+	merged := s.categories[ti]
+	merged.Aliases = append([]string(nil), merged.Aliases...)
+	remove := map[string]bool{}
+	for _, src := range sources {
+		k := key(src)
+		if k == "" {
+			continue
+		}
+		i := s.resolve(k)
+		if i >= 0 && s.categories[i].ID == target {
+			if k == target {
+				s.mu.Unlock()
+				return 0, errors.New("cannot merge a category into itself")
+			}
+			continue // already an alias
+		}
+		if i < 0 {
+			merged.Aliases = append(merged.Aliases, k)
+			continue
+		}
+		c := s.categories[i]
+		if s.isAncestor(c.ID, target) {
+			s.mu.Unlock()
+			return 0, fmt.Errorf("cannot merge %s into its subcategory %s", c.ID, target)
+		}
+		remove[c.ID] = true
+		merged.Aliases = append(merged.Aliases, append([]string{c.ID, key(c.Name)}, c.Aliases...)...)
+	}
+
+	kept := s.categories[:0:0]
+	for _, c := range s.categories {
+		switch {
+		case remove[c.ID]:
+			continue
+		case c.ID == target:
+			c = clean(merged)
+		case remove[c.Parent]:
+			c.Parent = target
+		}
+		kept = append(kept, c)
+	}
+	s.categories = kept
+	err := s.save()
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	rename := func(category string) (string, bool) {
+		if n := s.Normalize(category); n == target || strings.HasPrefix(n, target+"/") {
+			return n, category != n
+		}
+		return "", false
+	}
+	n, err := ledger.Recategorize(rename)
+	if err != nil {
+		return 0, err
+	}
+	for _, o := range others {
+		if _, err := o.Recategorize(rename); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,222 @@
+package category
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// fakeLedger records the categories that Recategorize rewrites.
+type fakeLedger struct {
+	categories []string
+}
+
+func (l *fakeLedger) Recategorize(rename func(string) (string, bool)) (int, error) {
+	n := 0
+	for i, c := range l.categories {
+		if to, ok := rename(c); ok {
+			l.categories[i] = to
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "categories.csv")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(s.List(false)); got != len(Defaults) {
+		t.Fatalf("new store has %d categories, want the %d defaults", got, len(Defaults))
+	}
+
+	if _, err := s.Create(Category{ID: "Coffee", Name: "Coffee", Emoji: "☕", Color: "#6F4E37", Parent: "dining", Aliases: []string{"Кофе", " cafe latte ", "coffee"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"coffee", "COFFEE", "кофе", "Cafe Latte"} {
+		if got := s.Normalize(name); got != "coffee" {
+			t.Errorf("Normalize(%q) = %q, want coffee", name, got)
+		}
+	}
+	if got := s.Normalize("Продукты"); got != "groceries" {
+		t.Errorf("Normalize(Продукты) = %q, want groceries by the default alias", got)
+	}
+	if got := s.Normalize("books"); got != "books" {
+		t.Errorf("Normalize(books) = %q, want it unchanged", got)
+	}
+
+	invalid := []struct {
+		name string
+		c    Category
+	}{
+		{"bad ID", Category{ID: "two words", Name: "Two"}},
+		{"no name", Category{ID: "nameless"}},
+		{"bad color", Category{ID: "tea", Name: "Tea", Color: "brown"}},
+		{"unknown parent", Category{ID: "tea", Name: "Tea", Parent: "drinks"}},
+		{"alias taken", Category{ID: "tea", Name: "Tea", Aliases: []string{"кофе"}}},
+		{"name taken", Category{ID: "tea", Name: "транспорт"}},
+		{"duplicate ID", Category{ID: "coffee", Name: "Coffee again"}},
+	}
+	for _, tt := range invalid {
+		if _, err := s.Create(tt.c); err == nil {
+			t.Errorf("Create() with %s succeeded", tt.name)
+		}
+	}
+
+	// A category cannot move below its own subcategory.
+	dining, err := s.Get("dining")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dining.Parent = "coffee"
+	if _, err := s.Update(dining); err == nil {
+		t.Error("Update() made a cycle")
+	}
+	if err := s.Delete("dining"); !errors.Is(err, ErrHasChildren) {
+		t.Errorf("Delete(dining) = %v, want ErrHasChildren", err)
+	}
+
+	// The tree lists subcategories after their parent; archived ones only on request.
+	scooters, _ := s.Get("scooters")
+	scooters.Archived = true
+	if _, err := s.Update(scooters); err != nil {
+		t.Fatal(err)
+	}
+	// Conditional writes fail once the category changed since it was read.
+	active := func(c Category) bool { return !c.Archived }
+	if _, err := s.UpdateIf(scooters, active); !errors.Is(err, ErrChanged) {
+		t.Errorf("UpdateIf() of a changed category = %v, want ErrChanged", err)
+	}
+	if err := s.DeleteIf("scooters", active); !errors.Is(err, ErrChanged) {
+		t.Errorf("DeleteIf() of a changed category = %v, want ErrChanged", err)
+	}
+	s, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, c := range s.List(false) {
+		ids = append(ids, c.ID)
+	}
+	want := []string{"groceries", "dining", "coffee", "transport", "health", "entertainment", "other"}
+	if len(ids) != len(want) {
+		t.Fatalf("List(false) = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("List(false) = %v, want %v", ids, want)
+		}
+	}
+	if got := len(s.List(true)); got != len(want)+1 {
+		t.Errorf("List(true) has %d categories, want %d", got, len(want)+1)
+	}
+	if c, _ := s.Get("coffee"); c.Color != "#6f4e37" || len(c.Aliases) != 2 {
+		t.Errorf("coffee after reload = %+v, want lower-cased color and two aliases", c)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(filepath.Join(t.TempDir(), "categories.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(Category{ID: "food", Name: "Food"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(Category{ID: "snacks", Name: "Snacks", Parent: "food"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ledger := &fakeLedger{categories: []string{"groceries", "Food", "food", "snacks", "Продукты", "еда", "transport"}}
+	templates := &fakeLedger{categories: []string{"Food", "transport"}}
+	n, err := s.Merge(ledger, []string{"food", "Еда"}, "groceries", templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"groceries", "groceries", "groceries", "snacks", "groceries", "groceries", "transport"}
+	for i := range want {
+		if ledger.categories[i] != want[i] {
+			t.Fatalf("ledger after merge = %v, want %v", ledger.categories, want)
+		}
+	}
+	if n != 4 {
+		t.Errorf("Merge() rewrote %d transactions, want 4", n)
+	}
+	if templates.categories[0] != "groceries" || templates.categories[1] != "transport" {
+		t.Errorf("other store after merge = %v, want [groceries transport]", templates.categories)
+	}
+	if _, err := s.Get("food"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(food) after merge = %v, want ErrNotFound", err)
+	}
+	if c, _ := s.Get("snacks"); c.Parent != "groceries" {
+		t.Errorf("snacks parent = %q, want groceries", c.Parent)
+	}
+	if got := s.Normalize("FOOD"); got != "groceries" {
+		t.Errorf("Normalize(FOOD) = %q, want groceries", got)
+	}
+
+	if _, err := s.Merge(ledger, []string{"groceries"}, "groceries"); err == nil {
+		t.Error("Merge() into itself succeeded")
+	}
+	if _, err := s.Merge(ledger, []string{"groceries"}, "snacks"); err == nil {
+		t.Error("Merge() into a subcategory succeeded")
+	}
+	if _, err := s.Merge(ledger, []string{"snacks"}, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Merge() into a missing category = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPaths(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(filepath.Join(t.TempDir(), "categories.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(Category{ID: "cafes", Name: "Cafes", Parent: "dining"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(Category{ID: "coffee", Name: "Coffee", Parent: "cafes"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct{ name, want string }{
+		{"dining/cafes", "cafes"},
+		{"Рестораны/CAFES", "cafes"},
+		{"dining/cafes/coffee", "coffee"},
+		{"Самокаты/прокат", "scooters"}, // a display name with a slash
+		{"Продукты/овощи", "groceries/овощи"},
+		{"dining/cafes/bubble tea", "cafes/bubble tea"},
+		{"groceries/cafes", "groceries/cafes"}, // cafes is not below groceries
+		{"books/comics", "books/comics"},
+	}
+	for _, tt := range tests {
+		if got := s.Normalize(tt.name); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if got := s.Path("coffee"); got != "dining/cafes/coffee" {
+		t.Errorf("Path(coffee) = %q, want dining/cafes/coffee", got)
+	}
+	if got := s.Subtree("Рестораны"); len(got) != 3 || got[0] != "dining" {
+		t.Errorf("Subtree(Рестораны) = %v, want dining, cafes and coffee", got)
+	}
+	if got := s.Subtree("books"); got != nil {
+		t.Errorf("Subtree(books) = %v, want nil", got)
+	}
+
+	ledger := &fakeLedger{categories: []string{"food/bakery", "food", "transport"}}
+	if _, err := s.Merge(ledger, []string{"food"}, "groceries"); err != nil {
+		t.Fatal(err)
+	}
+	if ledger.categories[0] != "groceries/bakery" || ledger.categories[1] != "groceries" {
+		t.Errorf("ledger after merge = %v, want food and its paths moved to groceries", ledger.categories)
+	}
+}
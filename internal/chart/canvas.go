@@ -0,0 +1,193 @@
+package chart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+type anchor int
+
+const (
+	anchorStart anchor = iota
+	anchorMiddle
+	anchorEnd
+)
+
+// canvas is the drawing surface shared by the PNG and SVG backends. Coordinates are
+// pixels from the top left corner; angles are radians clockwise from 12 o'clock.
+type canvas interface {
+	rect(x, y, w, h float64, c color.RGBA)
+	line(x1, y1, x2, y2, width float64, c color.RGBA)
+	polyline(xs, ys []float64, width float64, c color.RGBA)
+	wedge(cx, cy, r, from, to float64, c color.RGBA)
+	// text draws s with its baseline at y.
+	text(x, y float64, s string, a anchor, c color.RGBA)
+}
+
+// labelFont is Go Regular, which covers Latin and Cyrillic labels.
+var labelFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(goregular.TTF)
+})
+
+// pngCanvas rasterizes onto an RGBA image.
+type pngCanvas struct {
+	img  *image.RGBA
+	face font.Face
+}
+
+func newPNGCanvas(w, h int) (*pngCanvas, error) {
+	f, err := labelFont()
+	if err != nil {
+		return nil, err
+	}
+	// Faces are not safe for concurrent use, so every canvas gets its own.
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: 12, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	return &pngCanvas{img: img, face: face}, nil
+}
+
+func (p *pngCanvas) rect(x, y, w, h float64, c color.RGBA) {
+	r := image.Rect(int(math.Round(x)), int(math.Round(y)), int(math.Round(x+w)), int(math.Round(y+h)))
+	draw.Draw(p.img, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+func (p *pngCanvas) line(x1, y1, x2, y2, width float64, c color.RGBA) {
+	// Stamp a width×width square at every pixel step along the line.
+	steps := int(math.Ceil(math.Max(math.Abs(x2-x1), math.Abs(y2-y1))))
+	n := max(int(math.Round(width)), 1)
+	for i := 0; i <= steps; i++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		x0 := int(math.Round(x1 + (x2-x1)*t - width/2))
+		y0 := int(math.Round(y1 + (y2-y1)*t - width/2))
+		for dx := 0; dx < n; dx++ {
+			for dy := 0; dy < n; dy++ {
+				p.img.SetRGBA(x0+dx, y0+dy, c)
+			}
+		}
+	}
+}
+
+func (p *pngCanvas) polyline(xs, ys []float64, width float64, c color.RGBA) {
+	for i := 1; i < len(xs); i++ {
+		p.line(xs[i-1], ys[i-1], xs[i], ys[i], width, c)
+	}
+	if len(xs) == 1 {
+		p.line(xs[0], ys[0], xs[0], ys[0], width+2, c)
+	}
+}
+
+func (p *pngCanvas) wedge(cx, cy, r, from, to float64, c color.RGBA) {
+	for py := int(cy - r); py <= int(cy+r); py++ {
+		for px := int(cx - r); px <= int(cx+r); px++ {
+			dx, dy := float64(px)+0.5-cx, float64(py)+0.5-cy
+			if dx*dx+dy*dy > r*r {
+				continue
+			}
+			a := math.Atan2(dx, -dy)
+			if a < 0 {
+				a += 2 * math.Pi
+			}
+			if a >= from && a < to {
+				p.img.SetRGBA(px, py, c)
+			}
+		}
+	}
+}
+
+func (p *pngCanvas) text(x, y float64, s string, a anchor, c color.RGBA) {
+	d := font.Drawer{Dst: p.img, Src: image.NewUniform(c), Face: p.face}
+	w := d.MeasureString(s).Round()
+	switch a {
+	case anchorMiddle:
+		x -= float64(w) / 2
+	case anchorEnd:
+		x -= float64(w)
+	}
+	d.Dot = fixed.P(int(math.Round(x)), int(math.Round(y)))
+	d.DrawString(s)
+}
+
+func (p *pngCanvas) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, p.img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// svgCanvas writes SVG elements.
+type svgCanvas struct {
+	sb strings.Builder
+}
+
+func newSVGCanvas(w, h int) *svgCanvas {
+	s := &svgCanvas{}
+	fmt.Fprintf(&s.sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, w, h, w, h)
+	s.rect(0, 0, float64(w), float64(h), background)
+	return s
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func (s *svgCanvas) rect(x, y, w, h float64, c color.RGBA) {
+	fmt.Fprintf(&s.sb, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`, x, y, w, h, hex(c))
+}
+
+func (s *svgCanvas) line(x1, y1, x2, y2, width float64, c color.RGBA) {
+	fmt.Fprintf(&s.sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="%.1f"/>`, x1, y1, x2, y2, hex(c), width)
+}
+
+func (s *svgCanvas) polyline(xs, ys []float64, width float64, c color.RGBA) {
+	pts := make([]string, len(xs))
+	for i := range xs {
+		pts[i] = fmt.Sprintf("%.1f,%.1f", xs[i], ys[i])
+	}
+	fmt.Fprintf(&s.sb, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%.1f" stroke-linejoin="round"/>`, strings.Join(pts, " "), hex(c), width)
+}
+
+func (s *svgCanvas) wedge(cx, cy, r, from, to float64, c color.RGBA) {
+	if to-from >= 2*math.Pi-1e-9 {
+		fmt.Fprintf(&s.sb, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s"/>`, cx, cy, r, hex(c))
+		return
+	}
+	large := 0
+	if to-from > math.Pi {
+		large = 1
+	}
+	x1, y1 := cx+r*math.Sin(from), cy-r*math.Cos(from)
+	x2, y2 := cx+r*math.Sin(to), cy-r*math.Cos(to)
+	fmt.Fprintf(&s.sb, `<path d="M%.1f,%.1f L%.1f,%.1f A%.1f,%.1f 0 %d 1 %.1f,%.1f Z" fill="%s"/>`, cx, cy, x1, y1, r, r, large, x2, y2, hex(c))
+}
+
+func (s *svgCanvas) text(x, y float64, str string, a anchor, c color.RGBA) {
+	anchors := map[anchor]string{anchorStart: "start", anchorMiddle: "middle", anchorEnd: "end"}
+	fmt.Fprintf(&s.sb, `<text x="%.1f" y="%.1f" fill="%s" font-family="sans-serif" font-size="12" text-anchor="%s">`, x, y, hex(c), anchors[a])
+	xml.EscapeText(&s.sb, []byte(str))
+	s.sb.WriteString(`</text>`)
+}
+
+func (s *svgCanvas) encode() []byte {
+	return []byte(s.sb.String() + `</svg>`)
+}
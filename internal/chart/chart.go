@@ -0,0 +1,346 @@
+// Package chart renders the expense charts as PNG or SVG without a browser.
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Size of every chart in pixels.
+const (
+	Width  = 800
+	Height = 450
+)
+
+// Plot area margins.
+const (
+	marginLeft   = 70
+	marginRight  = 20
+	marginTop    = 50
+	marginBottom = 40
+)
+
+// maxSlices is the number of pie slices shown before the rest is merged into "other".
+const maxSlices = 7
+
+var (
+	background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	foreground = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	grid       = color.RGBA{0xe5, 0xe5, 0xe5, 0xff}
+	muted      = color.RGBA{0x99, 0x99, 0x99, 0xff}
+	spentColor = color.RGBA{0x2b, 0x6c, 0xb0, 0xff}
+	overColor  = color.RGBA{0xd6, 0x45, 0x45, 0xff}
+	palette    = []color.RGBA{
+		{0x2b, 0x6c, 0xb0, 0xff},
+		{0xf0, 0x8a, 0x24, 0xff},
+		{0x38, 0xa1, 0x69, 0xff},
+		{0xd6, 0x45, 0x45, 0xff},
+		{0x80, 0x5a, 0xd5, 0xff},
+		{0x31, 0x97, 0x95, 0xff},
+		{0xd5, 0x3f, 0x8c, 0xff},
+		{0xa0, 0xae, 0xc0, 0xff},
+	}
+)
+
+// Chart is a chart that can be rendered by PNG and SVG.
+type Chart interface {
+	draw(c canvas)
+}
+
+// PNG renders ch as a PNG image.
+func PNG(ch Chart) ([]byte, error) {
+	c, err := newPNGCanvas(Width, Height)
+	if err != nil {
+		return nil, err
+	}
+	ch.draw(c)
+	return c.encode()
+}
+
+// SVG renders ch as an SVG document.
+func SVG(ch Chart) []byte {
+	c := newSVGCanvas(Width, Height)
+	ch.draw(c)
+	return c.encode()
+}
+
+// Point is a labelled value: a day of a series, a bar or a pie slice.
+type Point struct {
+	Label string
+	Value float64
+}
+
+// Cumulative draws the cumulative spend against the cumulative budget, day by day.
+// Budget may be shorter than Spent or empty.
+type Cumulative struct {
+	Title  string
+	Spent  []Point
+	Budget []Point
+}
+
+func (ch Cumulative) draw(c canvas) {
+	title(c, ch.Title)
+	if len(ch.Spent) == 0 {
+		noData(c)
+		return
+	}
+	top := 0.0
+	for _, p := range append(append([]Point(nil), ch.Spent...), ch.Budget...) {
+		top = max(top, p.Value)
+	}
+	y := axes(c, top)
+	n := max(len(ch.Spent), len(ch.Budget))
+	x := func(i int) float64 {
+		if n == 1 {
+			return marginLeft + plotWidth()/2
+		}
+		return marginLeft + plotWidth()*float64(i)/float64(n-1)
+	}
+	series := func(points []Point, width float64, col color.RGBA) {
+		xs, ys := make([]float64, len(points)), make([]float64, len(points))
+		for i, p := range points {
+			xs[i], ys[i] = x(i), y(p.Value)
+		}
+		c.polyline(xs, ys, width, col)
+	}
+	series(ch.Budget, 2, muted)
+	col := spentColor
+	if last := len(ch.Spent) - 1; last < len(ch.Budget) && ch.Spent[last].Value > ch.Budget[last].Value {
+		col = overColor
+	}
+	series(ch.Spent, 3, col)
+	xLabels(c, ch.Spent, ch.Budget, x)
+	legend(c, []string{"Spent", "Budget"}, []color.RGBA{col, muted})
+}
+
+// Bars draws one bar per point, e.g. daily totals. A positive Limit is drawn as a
+// horizontal line and bars above it are highlighted.
+type Bars struct {
+	Title string
+	Bars  []Point
+	Limit float64
+}
+
+func (ch Bars) draw(c canvas) {
+	title(c, ch.Title)
+	if len(ch.Bars) == 0 {
+		noData(c)
+		return
+	}
+	top := ch.Limit
+	for _, p := range ch.Bars {
+		top = max(top, p.Value)
+	}
+	y := axes(c, top)
+	slot := plotWidth() / float64(len(ch.Bars))
+	gap := min(slot*0.2, 4)
+	x := func(i int) float64 { return marginLeft + slot*(float64(i)+0.5) }
+	for i, p := range ch.Bars {
+		if p.Value <= 0 {
+			continue
+		}
+		col := spentColor
+		if ch.Limit > 0 && p.Value > ch.Limit {
+			col = overColor
+		}
+		c.rect(marginLeft+slot*float64(i)+gap/2, y(p.Value), max(slot-gap, 1), y(0)-y(p.Value), col)
+	}
+	if ch.Limit > 0 {
+		c.line(marginLeft, y(ch.Limit), Width-marginRight, y(ch.Limit), 1, muted)
+		c.text(Width-marginRight, y(ch.Limit)-4, "limit "+amount(ch.Limit), anchorEnd, muted)
+	}
+	xLabels(c, ch.Bars, nil, x)
+}
+
+// Pie draws the share of every slice, largest first. Slices beyond the largest seven
+// are merged into "other".
+type Pie struct {
+	Title  string
+	Slices []Point
+}
+
+func (ch Pie) draw(c canvas) {
+	title(c, ch.Title)
+	slices := groupSlices(ch.Slices)
+	total := 0.0
+	for _, s := range slices {
+		total += s.Value
+	}
+	if total <= 0 {
+		noData(c)
+		return
+	}
+
+	const cx, cy, r = 230.0, 250.0, 170.0
+	from := 0.0
+	for i, s := range slices {
+		to := from + 2*math.Pi*s.Value/total
+		if i == len(slices)-1 {
+			to = 2 * math.Pi
+		}
+		c.wedge(cx, cy, r, from, to, palette[i%len(palette)])
+		from = to
+	}
+
+	const lx, rowHeight = 450.0, 28.0
+	ly := cy - rowHeight*float64(len(slices))/2
+	for i, s := range slices {
+		row := ly + rowHeight*float64(i)
+		c.rect(lx, row, 14, 14, palette[i%len(palette)])
+		c.text(lx+22, row+12, fmt.Sprintf("%s  %.1f%%  %s", s.Label, s.Value/total*100, amount(s.Value)), anchorStart, foreground)
+	}
+}
+
+// Heatmap shades a grid of values, e.g. spending by weekday and hour, darker for
+// more. Values holds one row per label in Rows, each with one value per Columns.
+type Heatmap struct {
+	Title   string
+	Rows    []string
+	Columns []string
+	Values  [][]float64
+}
+
+// heatmapColumnLabels is how many column labels are written under a heatmap.
+const heatmapColumnLabels = 8
+
+func (ch Heatmap) draw(c canvas) {
+	title(c, ch.Title)
+	top := 0.0
+	for _, row := range ch.Values {
+		for _, v := range row {
+			top = max(top, v)
+		}
+	}
+	if len(ch.Rows) == 0 || len(ch.Columns) == 0 || top <= 0 {
+		noData(c)
+		return
+	}
+	cw, rh := plotWidth()/float64(len(ch.Columns)), plotHeight()/float64(len(ch.Rows))
+	for i, label := range ch.Rows {
+		y := marginTop + rh*float64(i)
+		for j := range ch.Columns {
+			v := 0.0
+			if i < len(ch.Values) && j < len(ch.Values[i]) {
+				v = ch.Values[i][j]
+			}
+			c.rect(marginLeft+cw*float64(j)+1, y+1, cw-2, rh-2, shade(v/top))
+		}
+		c.text(marginLeft-8, y+rh/2+4, label, anchorEnd, foreground)
+	}
+	step := max(1, len(ch.Columns)/heatmapColumnLabels)
+	for j := 0; j < len(ch.Columns); j += step {
+		c.text(marginLeft+cw*float64(j), Height-marginBottom+20, ch.Columns[j], anchorStart, muted)
+	}
+}
+
+// shade blends from an empty cell towards spentColor as f goes from 0 to 1.
+func shade(f float64) color.RGBA {
+	if f <= 0 {
+		return color.RGBA{0xf4, 0xf4, 0xf4, 0xff}
+	}
+	// Small values stay visible
+	f = 0.15 + 0.85*min(f, 1)
+	mix := func(from, to uint8) uint8 { return uint8(float64(from) + (float64(to)-float64(from))*f) }
+	return color.RGBA{mix(0xff, spentColor.R), mix(0xff, spentColor.G), mix(0xff, spentColor.B), 0xff}
+}
+
+// groupSlices sorts positive slices by value and merges the tail into "other".
+func groupSlices(points []Point) []Point {
+	var res []Point
+	for _, p := range points {
+		if p.Value > 0 {
+			res = append(res, p)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Value > res[j].Value })
+	if len(res) <= maxSlices+1 {
+		return res
+	}
+	other := Point{Label: "other"}
+	for _, p := range res[maxSlices:] {
+		other.Value += p.Value
+	}
+	return append(res[:maxSlices], other)
+}
+
+func plotWidth() float64  { return Width - marginLeft - marginRight }
+func plotHeight() float64 { return Height - marginTop - marginBottom }
+
+func title(c canvas, s string) {
+	c.text(marginLeft, 28, s, anchorStart, foreground)
+}
+
+func noData(c canvas) {
+	c.text(Width/2, Height/2, "No data", anchorMiddle, muted)
+}
+
+// axes draws the horizontal grid up to a round value above top and returns the
+// mapping from amounts to y coordinates.
+func axes(c canvas, top float64) func(float64) float64 {
+	scale := niceCeil(top)
+	y := func(v float64) float64 {
+		return marginTop + plotHeight()*(1-v/scale)
+	}
+	const lines = 4
+	for i := 0; i <= lines; i++ {
+		v := scale * float64(i) / lines
+		c.line(marginLeft, y(v), Width-marginRight, y(v), 1, grid)
+		c.text(marginLeft-8, y(v)+4, amount(v), anchorEnd, muted)
+	}
+	return y
+}
+
+// xLabels writes the first, middle and last labels under the plot.
+func xLabels(c canvas, points, fallback []Point, x func(int) float64) {
+	if len(points) < len(fallback) {
+		points = fallback
+	}
+	n := len(points)
+	for _, i := range []int{0, n / 2, n - 1} {
+		if i == n/2 && (i == 0 || i == n-1) {
+			continue
+		}
+		a := anchorMiddle
+		switch {
+		case i == 0 && n > 1:
+			a = anchorStart
+		case i == n-1 && n > 1:
+			a = anchorEnd
+		}
+		c.text(x(i), Height-marginBottom+20, points[i].Label, a, muted)
+	}
+}
+
+func legend(c canvas, labels []string, colors []color.RGBA) {
+	x := float64(Width - marginRight)
+	for i := len(labels) - 1; i >= 0; i-- {
+		x -= float64(len(labels[i]))*7 + 30
+		c.rect(x, 18, 12, 12, colors[i])
+		c.text(x+18, 28, labels[i], anchorStart, foreground)
+	}
+}
+
+// niceCeil rounds v up to 1, 2 or 5 times a power of ten.
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	pow := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*pow {
+			return m * pow
+		}
+	}
+	return 10 * pow
+}
+
+// amount formats an axis amount compactly: 950, 12k, 1.5k.
+func amount(v float64) string {
+	if math.Abs(v) < 1000 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", v/1000), ".0") + "k"
+}
@@ -0,0 +1,93 @@
+package chart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"io"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	days := []Point{{"2025-08-01", 500}, {"2025-08-02", 0}, {"2025-08-03", 1200}}
+	tests := []struct {
+		name  string
+		chart Chart
+	}{
+		{"cumulative", Cumulative{Title: "Spent vs allowance", Spent: []Point{{"2025-08-01", 500}, {"2025-08-02", 500}}, Budget: days}},
+		{"cumulative single day", Cumulative{Title: "One day", Spent: days[:1]}},
+		{"bars", Bars{Title: "Daily spend", Bars: days, Limit: 800}},
+		{"pie", Pie{Title: "По категориям", Slices: []Point{{"groceries", 3000}, {"кафе <&>", 1000}}}},
+		{"pie single slice", Pie{Title: "Rent", Slices: []Point{{"rent", 30000}}}},
+		{"heatmap", Heatmap{Title: "By hour", Rows: []string{"Mon", "Tue"}, Columns: []string{"00", "01", "02"}, Values: [][]float64{{0, 150, 900}, {40}}}},
+		{"empty", Bars{Title: "Nothing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data, err := PNG(tt.chart)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("PNG does not decode: %v", err)
+			}
+			if b := img.Bounds(); b.Dx() != Width || b.Dy() != Height {
+				t.Errorf("PNG size = %dx%d, want %dx%d", b.Dx(), b.Dy(), Width, Height)
+			}
+
+			dec := xml.NewDecoder(bytes.NewReader(SVG(tt.chart)))
+			for {
+				if _, err := dec.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("SVG is not well-formed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupSlices(t *testing.T) {
+	t.Parallel()
+
+	var points []Point
+	for i := 1; i <= 10; i++ {
+		points = append(points, Point{Label: string(rune('a' + i - 1)), Value: float64(i)})
+	}
+	points = append(points, Point{Label: "refund", Value: -5})
+
+	got := groupSlices(points)
+	if len(got) != maxSlices+1 {
+		t.Fatalf("got %d slices, want %d", len(got), maxSlices+1)
+	}
+	if got[0].Label != "j" || got[0].Value != 10 {
+		t.Errorf("largest slice = %+v, want j 10", got[0])
+	}
+	if last := got[len(got)-1]; last.Label != "other" || last.Value != 1+2+3 {
+		t.Errorf("last slice = %+v, want other 6", last)
+	}
+}
+
+func TestNiceCeil(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in, want float64
+	}{
+		{0, 1},
+		{7, 10},
+		{12, 20},
+		{450, 500},
+		{1000, 1000},
+		{12001, 20000},
+	}
+	for _, tt := range tests {
+		if got := niceCeil(tt.in); got != tt.want {
+			t.Errorf("niceCeil(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
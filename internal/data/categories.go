@@ -0,0 +1,77 @@
+package data
+
+// SetCategoryResolver makes every transaction written to the ledger from now on
+// store resolve(category) as its category, e.g. the ID of a managed category for
+// one of its aliases. Transactions already in the ledger are left alone, see
+// Recategorize.
+func (d *Data) SetCategoryResolver(resolve func(string) string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.resolveCategory = resolve
+}
+
+// normalize returns txs with their categories, including those of split lines,
+// resolved, their tags normalized and the dates of timed ones derived from their
+// time; callers must hold d.mu.
+func (d *Data) normalize(txs []Transaction) []Transaction {
+	res := make([]Transaction, len(txs))
+	for i, tx := range txs {
+		if tx.Category == "" && len(tx.Splits) > 0 {
+			tx.Category = tx.Splits[0].Category
+		}
+		tx.Splits = append([]Split(nil), tx.Splits...)
+		if d.resolveCategory != nil {
+			tx.Category = d.resolveCategory(tx.Category)
+			for j := range tx.Splits {
+				tx.Splits[j].Category = d.resolveCategory(tx.Splits[j].Category)
+			}
+		}
+		tx.Tags = NormalizeTags(tx.Tags)
+		if !tx.Time.IsZero() {
+			tx.Date = d.dayOf(tx.Time)
+		}
+		res[i] = tx
+	}
+	return res
+}
+
+// Recategorize sets the category of every transaction and split line for which
+// rename returns true, e.g. to merge categories, and returns how many
+// transactions changed. Transactions keep
+// their IDs and positions.
+func (d *Data) Recategorize(rename func(category string) (string, bool)) (int, error) {
+	d.mu.Lock()
+	n := 0
+	for i, tx := range d.Transactions {
+		changed := false
+		if c, ok := rename(tx.Category); ok && c != tx.Category {
+			d.Transactions[i].Category = c
+			changed = true
+		}
+		// Copied, as transactions handed out share the split lines
+		splits := append([]Split(nil), tx.Splits...)
+		for j, split := range splits {
+			if c, ok := rename(split.Category); ok && c != split.Category {
+				splits[j].Category = c
+				d.Transactions[i].Splits = splits
+				changed = true
+			}
+		}
+		if changed {
+			n++
+		}
+	}
+	if n > 0 {
+		// Sorting by category changes, so query cursors are no longer valid.
+		d.generation++
+	}
+	d.mu.Unlock()
+	if n == 0 {
+		return 0, nil
+	}
+	if err := d.save(); err != nil {
+		return 0, err
+	}
+	d.changes.publish(ChangeReplace, nil)
+	return n, nil
+}
@@ -0,0 +1,85 @@
+package data
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCategories(t *testing.T) {
+	t.Parallel()
+
+	d, err := New(filepath.Join(t.TempDir(), "expenses.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddTransaction(Transaction{Date: "2025-08-01", Category: "Food", Amount: 100}); err != nil {
+		t.Fatal(err)
+	}
+	d.SetCategoryResolver(strings.ToLower)
+
+	tx, err := d.CreateTransaction(Transaction{Date: "2025-08-02", Category: "Transport", Amount: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Category != "transport" {
+		t.Errorf("created category = %q, want it resolved to transport", tx.Category)
+	}
+	tx.Category = "Taxi"
+	if err := d.UpdateTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := d.GetTransaction(tx.ID); got.Category != "taxi" {
+		t.Errorf("updated category = %q, want taxi", got.Category)
+	}
+	if got := d.GetAllTransactions()[0].Category; got != "Food" {
+		t.Errorf("existing category = %q, want it left alone", got)
+	}
+
+	changes, stop := d.Changes().Subscribe()
+	defer stop()
+	before := d.GetAllTransactions()
+	n, err := d.Recategorize(func(c string) (string, bool) { return "groceries", c == "Food" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := d.GetAllTransactions()
+	if n != 1 || after[0].Category != "groceries" || after[1].Category != "taxi" {
+		t.Errorf("Recategorize() = %d, %+v; want only Food rewritten", n, after)
+	}
+	if after[0].ID != before[0].ID {
+		t.Errorf("ID changed from %s to %s", before[0].ID, after[0].ID)
+	}
+	if c := <-changes; c.Kind != ChangeReplace {
+		t.Errorf("change = %s, want replace", c.Kind)
+	}
+}
+
+func TestRecategorizeSplits(t *testing.T) {
+	t.Parallel()
+
+	d, err := New(filepath.Join(t.TempDir(), "expenses.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetCategoryResolver(strings.ToLower)
+	tx, err := d.CreateTransaction(Transaction{Date: "2025-08-01", Amount: 1200, Splits: []Split{{"Food", 800}, {"Household", 400}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Category != "food" || tx.Splits[1].Category != "household" {
+		t.Fatalf("created %+v, want the main category from the first line and lines resolved", tx)
+	}
+
+	n, err := d.Recategorize(func(c string) (string, bool) { return "groceries", c == "food" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := d.GetTransaction(tx.ID)
+	if n != 1 || got.Category != "groceries" || got.Splits[0].Category != "groceries" || got.Splits[1].Category != "household" {
+		t.Errorf("Recategorize() = %d, %+v; want the main category and first line rewritten", n, got)
+	}
+	if tx.Splits[0].Category != "food" {
+		t.Error("Recategorize() changed the split lines of a transaction handed out earlier")
+	}
+}
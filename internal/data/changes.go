@@ -0,0 +1,91 @@
+package data
+
+import "sync"
+
+// ChangeKind says how the ledger changed.
+type ChangeKind string
+
+const (
+	ChangeAdd    ChangeKind = "add"
+	ChangeUpdate ChangeKind = "update"
+	ChangeDelete ChangeKind = "delete"
+	// ChangeReplace means the whole ledger was replaced or cleared, e.g. by an
+	// import; it carries no transactions and readers should reload.
+	ChangeReplace ChangeKind = "replace"
+)
+
+// Change is one successful write to the ledger. Transactions are the added or
+// updated transactions with their IDs, or the deleted one.
+type Change struct {
+	Seq          uint64
+	Kind         ChangeKind
+	Transactions []Transaction
+}
+
+// subscriberBuffer is how many changes a subscriber may fall behind before it
+// is dropped.
+const subscriberBuffer = 32
+
+// Hub fans ledger changes out to subscribers such as open web pages. Writers
+// never wait for subscribers: one that falls behind has its channel closed and
+// must resubscribe and reload. The zero value is ready to use.
+type Hub struct {
+	mu   sync.Mutex
+	seq  uint64
+	subs map[chan Change]struct{}
+}
+
+// Subscribe returns a channel of changes after this call and a function that
+// ends the subscription. The channel is closed when the subscription ends.
+func (h *Hub) Subscribe() (<-chan Change, func()) {
+	ch := make(chan Change, subscriberBuffer)
+	h.mu.Lock()
+	if h.subs == nil {
+		h.subs = map[chan Change]struct{}{}
+	}
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			h.drop(ch)
+			h.mu.Unlock()
+		})
+	}
+}
+
+// drop closes ch if it is still subscribed; callers must hold h.mu.
+func (h *Hub) drop(ch chan Change) {
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// Seq is the sequence number of the last published change.
+func (h *Hub) Seq() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seq
+}
+
+func (h *Hub) publish(kind ChangeKind, txs []Transaction) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	change := Change{Seq: h.seq, Kind: kind, Transactions: txs}
+	for ch := range h.subs {
+		select {
+		case ch <- change:
+		default:
+			h.drop(ch)
+		}
+	}
+}
+
+// Changes returns the hub that announces every successful write to d.
+func (d *Data) Changes() *Hub {
+	return &d.changes
+}
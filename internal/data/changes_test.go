@@ -0,0 +1,108 @@
+package data
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	t.Parallel()
+
+	d, err := New(filepath.Join(t.TempDir(), "expenses.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, stop := d.Changes().Subscribe()
+	defer stop()
+
+	tx, err := d.CreateTransaction(Transaction{Date: "2025-08-01", Category: "dining", Description: "Coffee", Amount: 250})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Amount = 300
+	if err := d.UpdateTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.DeleteTransaction(tx.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ReplaceAll([]Transaction{{Date: "2025-08-02", Category: "transport", Amount: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Clear(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		kind   ChangeKind
+		amount float64 // of the only transaction, 0 for none
+	}{
+		{ChangeAdd, 250},
+		{ChangeUpdate, 300},
+		{ChangeDelete, 300},
+		{ChangeReplace, 0},
+		{ChangeReplace, 0},
+	}
+	for i, w := range want {
+		c := <-changes
+		if c.Seq != uint64(i+1) || c.Kind != w.kind {
+			t.Fatalf("change %d = #%d %s, want #%d %s", i, c.Seq, c.Kind, i+1, w.kind)
+		}
+		if w.amount == 0 {
+			if len(c.Transactions) != 0 {
+				t.Errorf("%s change carries %d transactions, want none", c.Kind, len(c.Transactions))
+			}
+			continue
+		}
+		if len(c.Transactions) != 1 || c.Transactions[0].ID != tx.ID || c.Transactions[0].Amount != w.amount {
+			t.Errorf("%s change carries %+v, want %s with amount %v", c.Kind, c.Transactions, tx.ID, w.amount)
+		}
+	}
+	if got := d.Changes().Seq(); got != uint64(len(want)) {
+		t.Errorf("Seq() = %d, want %d", got, len(want))
+	}
+
+	// Failed writes are not announced.
+	if err := d.DeleteTransaction("missing"); err == nil {
+		t.Fatal("DeleteTransaction(missing) succeeded")
+	}
+	select {
+	case c := <-changes:
+		t.Errorf("got %s change for a failed write", c.Kind)
+	default:
+	}
+
+	stop()
+	if _, ok := <-changes; ok {
+		t.Error("channel still open after stop")
+	}
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	t.Parallel()
+
+	var h Hub
+	slow, stopSlow := h.Subscribe()
+	defer stopSlow()
+	fast, stopFast := h.Subscribe()
+	defer stopFast()
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		h.publish(ChangeAdd, nil)
+		<-fast
+	}
+
+	for i := 0; i < subscriberBuffer; i++ {
+		if _, ok := <-slow; !ok {
+			t.Fatalf("slow subscriber closed after %d changes, want %d buffered", i, subscriberBuffer)
+		}
+	}
+	if _, ok := <-slow; ok {
+		t.Error("slow subscriber still open after overflowing its buffer")
+	}
+
+	h.publish(ChangeAdd, nil)
+	if c, ok := <-fast; !ok || c.Seq != subscriberBuffer+2 {
+		t.Errorf("fast subscriber got #%d (open %v), want #%d", c.Seq, ok, subscriberBuffer+2)
+	}
+}
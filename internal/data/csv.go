@@ -2,11 +2,9 @@ package data
 
 import (
 	"encoding/csv"
-	"errors"
-	"fmt"
 	"os"
-	"strconv"
 	"sync"
+	"time"
 )
 
 type Transaction struct {
@@ -14,12 +12,41 @@ type Transaction struct {
 	Category    string
 	Description string
 	Amount      float64
+	// Fixed marks a committed cost (rent, bills) that is reserved from the cycle
+	// budget up front instead of being tracked against the daily allowance.
+	Fixed bool
+	// ID identifies the transaction, see derivedIDs. It is set on transactions
+	// returned by Data and empty in Data.Transactions.
+	ID string `json:",omitempty"`
+	// Payer is the household member who paid, e.g. the Telegram first name.
+	Payer string
+	// Tags label the transaction across categories, see NormalizeTags.
+	Tags []string `json:",omitempty"`
+	// Splits divide the amount across categories, see Parts; Category is then
+	// the main one, that of the first line unless set.
+	Splits []Split `json:",omitempty"`
+	// Fiscal identifies the Russian fiscal receipt the transaction was entered
+	// from (fiscal drive, document number and sign), so it is entered once.
+	Fiscal string `json:",omitempty"`
+	// Time is when the transaction happened, with the zone it was recorded in,
+	// if known; Date is then its day, see SetLocation.
+	Time time.Time `json:",omitzero"`
 }
 
 type Data struct {
 	mu           sync.Mutex
 	dataPath     string
 	Transactions []Transaction
+	ids          []string // ID of each transaction, in the same order
+	listeners    []func([]Transaction)
+	changes      Hub
+	// resolveCategory maps the category of new and changed transactions, see SetCategoryResolver
+	resolveCategory func(string) string
+	// location is where the day of a timed transaction is taken, see SetLocation
+	location *time.Location
+	// generation changes whenever stored transactions change or move rather than
+	// new ones being appended, see encodeCursor
+	generation int
 }
 
 func New(dataPath string) (*Data, error) {
@@ -62,37 +89,71 @@ func (d *Data) load() error {
 	}
 
 	// Validate header
-	expectedHeader := []string{"Date", "Category", "Description", "Amount"}
-	if !compareStringSlices(records[0], expectedHeader) {
-		return errors.New("CSV header does not match expected format")
+	columns, err := ParseHeader(records[0])
+	if err != nil {
+		return err
 	}
 
-	d.Transactions = make([]Transaction, 0, len(records)-1)
+	txs := make([]Transaction, 0, len(records)-1)
 	for i, record := range records[1:] { // Skip header row
-		if len(record) != 4 {
-			return fmt.Errorf("invalid record length on line %d: expected 4 fields, got %d", i+2, len(record))
-		}
-		amount, err := strconv.ParseFloat(record[3], 64)
+		tx, err := columns.Parse(record, i+2)
 		if err != nil {
-			return fmt.Errorf("invalid amount on line %d: %w", i+2, err)
+			return err
+		}
+		if !tx.Time.IsZero() {
+			tx.Date = d.dayOf(tx.Time)
 		}
-		d.Transactions = append(d.Transactions, Transaction{
-			Date:        record[0],
-			Category:    record[1],
-			Description: record[2],
-			Amount:      amount,
-		})
+		txs = append(txs, tx)
 	}
+	d.Transactions = make([]Transaction, 0, len(txs))
+	d.adopt(txs)
 
 	return nil
 }
 
 func (d *Data) AddTransaction(tx Transaction) error {
+	return d.AddTransactions([]Transaction{tx})
+}
+
+// AddTransactions appends several transactions with a single write, e.g. for imports.
+func (d *Data) AddTransactions(txs []Transaction) error {
+	_, err := d.add(txs)
+	return err
+}
+
+// add appends txs and returns them as stored, with their IDs.
+func (d *Data) add(txs []Transaction) ([]Transaction, error) {
 	d.mu.Lock()
-	d.Transactions = append(d.Transactions, tx)
+	if err := d.checkFiscal(txs, -1); err != nil {
+		d.mu.Unlock()
+		return nil, err
+	}
+	stored := d.adopt(d.normalize(txs))
 	d.mu.Unlock()
 
-	return d.save()
+	if err := d.save(); err != nil {
+		return nil, err
+	}
+	d.changes.publish(ChangeAdd, stored)
+	d.notify(stored)
+	return stored, nil
+}
+
+// OnAdd registers fn to be called with the stored transactions after every
+// successful AddTransaction, AddTransactions and ReplaceAll.
+func (d *Data) OnAdd(fn func([]Transaction)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.listeners = append(d.listeners, fn)
+}
+
+func (d *Data) notify(txs []Transaction) {
+	d.mu.Lock()
+	listeners := append([]func([]Transaction){}, d.listeners...)
+	d.mu.Unlock()
+	for _, fn := range listeners {
+		fn(txs)
+	}
 }
 
 func (d *Data) save() error {
@@ -105,42 +166,45 @@ func (d *Data) save() error {
 	}
 	defer file.Close()
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Write header
-	writer.Write([]string{"Date", "Category", "Description", "Amount"})
-
-	for _, tx := range d.Transactions {
-		err := writer.Write([]string{
-			tx.Date,
-			tx.Category,
-			tx.Description,
-			strconv.FormatFloat(tx.Amount, 'f', 2, 64),
-		})
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return WriteCSV(file, d.withIDs(0, len(d.Transactions)))
 }
 
 // ReplaceAll atomically replaces all stored transactions and persists them to disk.
+// It returns ErrDuplicateReceipt, leaving the ledger as it was, if two of the
+// transactions have the same Fiscal key.
 func (d *Data) ReplaceAll(transactions []Transaction) error {
-    d.mu.Lock()
-    d.Transactions = make([]Transaction, len(transactions))
-    copy(d.Transactions, transactions)
-    d.mu.Unlock()
-    return d.save()
+	d.mu.Lock()
+	old, oldIDs := d.Transactions, d.ids
+	d.Transactions = make([]Transaction, 0, len(transactions))
+	d.ids = nil
+	if err := d.checkFiscal(transactions, -1); err != nil {
+		d.Transactions, d.ids = old, oldIDs
+		d.mu.Unlock()
+		return err
+	}
+	stored := d.adopt(d.normalize(transactions))
+	d.generation++
+	d.mu.Unlock()
+	if err := d.save(); err != nil {
+		return err
+	}
+	d.changes.publish(ChangeReplace, nil)
+	d.notify(stored)
+	return nil
 }
 
 // Clear removes all transactions and leaves only the CSV header in the file.
 func (d *Data) Clear() error {
-    d.mu.Lock()
-    d.Transactions = []Transaction{}
-    d.mu.Unlock()
-    return d.save()
+	d.mu.Lock()
+	d.Transactions = []Transaction{}
+	d.ids = nil
+	d.generation++
+	d.mu.Unlock()
+	if err := d.save(); err != nil {
+		return err
+	}
+	d.changes.publish(ChangeReplace, nil)
+	return nil
 }
 
 func (d *Data) GetTransactionsByDate(date string) []Transaction {
@@ -148,8 +212,9 @@ func (d *Data) GetTransactionsByDate(date string) []Transaction {
 	defer d.mu.Unlock()
 
 	var result []Transaction
-	for _, tx := range d.Transactions {
+	for i, tx := range d.Transactions {
 		if tx.Date == date {
+			tx.ID = d.ids[i]
 			result = append(result, tx)
 		}
 	}
@@ -160,9 +225,7 @@ func (d *Data) GetAllTransactions() []Transaction {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	result := make([]Transaction, len(d.Transactions))
-	copy(result, d.Transactions)
-	return result
+	return d.withIDs(0, len(d.Transactions))
 }
 
 func compareStringSlices(a, b []string) bool {
@@ -0,0 +1,42 @@
+package data
+
+import "errors"
+
+// ErrDuplicateReceipt is returned when a transaction repeats the Fiscal key of
+// another one, i.e. the same receipt would be entered twice.
+var ErrDuplicateReceipt = errors.New("this receipt has already been entered")
+
+// FindFiscal returns the transaction entered from the fiscal receipt with the
+// given key.
+func (d *Data) FindFiscal(key string) (Transaction, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, tx := range d.Transactions {
+		if key != "" && tx.Fiscal == key {
+			return d.withIDs(i, i+1)[0], true
+		}
+	}
+	return Transaction{}, false
+}
+
+// checkFiscal returns ErrDuplicateReceipt if a transaction of txs has the
+// Fiscal key of a stored transaction other than the one at skip, or of an
+// earlier one in txs. Callers must hold d.mu.
+func (d *Data) checkFiscal(txs []Transaction, skip int) error {
+	seen := map[string]bool{}
+	for i, tx := range d.Transactions {
+		if i != skip && tx.Fiscal != "" {
+			seen[tx.Fiscal] = true
+		}
+	}
+	for _, tx := range txs {
+		if tx.Fiscal == "" {
+			continue
+		}
+		if seen[tx.Fiscal] {
+			return ErrDuplicateReceipt
+		}
+		seen[tx.Fiscal] = true
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package data
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestFiscalDuplicates(t *testing.T) {
+	t.Parallel()
+
+	d, err := New(filepath.Join(t.TempDir(), "expenses.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const key = "7380440800000000-12345-1234567890"
+	tx, err := d.CreateTransaction(Transaction{Date: "2025-08-01", Category: "groceries", Amount: 1234.5, Fiscal: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := d.CreateTransaction(Transaction{Date: "2025-08-01", Category: "dining", Amount: 450})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := d.FindFiscal(key); !ok || got.ID != tx.ID {
+		t.Errorf("FindFiscal() = %+v, %v; want the transaction entered from the receipt", got, ok)
+	}
+	if _, ok := d.FindFiscal(""); ok {
+		t.Error("FindFiscal(\"\") found a transaction without a receipt")
+	}
+	if _, err := d.CreateTransaction(Transaction{Date: "2025-08-02", Category: "food", Amount: 1234.5, Fiscal: key}); !errors.Is(err, ErrDuplicateReceipt) {
+		t.Errorf("entering the receipt again: error = %v, want ErrDuplicateReceipt", err)
+	}
+	if err := d.AddTransactions([]Transaction{
+		{Date: "2025-08-03", Category: "food", Amount: 1, Fiscal: "1-2-3"},
+		{Date: "2025-08-03", Category: "food", Amount: 1, Fiscal: "1-2-3"},
+	}); !errors.Is(err, ErrDuplicateReceipt) {
+		t.Errorf("importing a receipt twice: error = %v, want ErrDuplicateReceipt", err)
+	}
+	if n := len(d.GetAllTransactions()); n != 2 {
+		t.Errorf("%d transactions after rejected writes, want 2", n)
+	}
+
+	// Editing keeps the receipt, moving it to another transaction does not
+	tx.Category = "food"
+	if err := d.UpdateTransaction(tx); err != nil {
+		t.Errorf("editing the transaction entered from the receipt: %v", err)
+	}
+	other.Fiscal = key
+	if err := d.UpdateTransaction(other); !errors.Is(err, ErrDuplicateReceipt) {
+		t.Errorf("copying the receipt to another transaction: error = %v, want ErrDuplicateReceipt", err)
+	}
+
+	// Replacing the ledger checks the new set alone
+	if err := d.ReplaceAll([]Transaction{
+		{Date: "2025-08-03", Category: "food", Amount: 1, Fiscal: "1-2-3"},
+		{Date: "2025-08-04", Category: "food", Amount: 2, Fiscal: "1-2-3"},
+	}); !errors.Is(err, ErrDuplicateReceipt) {
+		t.Errorf("replacing with a receipt twice: error = %v, want ErrDuplicateReceipt", err)
+	}
+	if n := len(d.GetAllTransactions()); n != 2 {
+		t.Errorf("%d transactions after a rejected replace, want 2", n)
+	}
+	if err := d.ReplaceAll([]Transaction{{Date: "2025-08-05", Category: "food", Amount: 1234.5, Fiscal: key}}); err != nil {
+		t.Errorf("replacing with the stored receipt: %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package data
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BaseHeader is the classic CSV header every ledger file starts with.
+var BaseHeader = []string{"Date", "Category", "Description", "Amount"}
+
+// optionalColumns may follow the base header. They are written only when at least one
+// transaction uses them, so a plain ledger keeps the portable four-column format.
+var optionalColumns = []string{"Fixed", "Payer", "ID", "Tags", "Splits", "Fiscal", "Time"}
+
+// tagSeparator joins the tags of a transaction in the Tags column.
+const tagSeparator = " "
+
+// ErrInvalidHeader is returned when a CSV header does not match the expected format.
+var ErrInvalidHeader = errors.New("CSV header does not match expected format")
+
+// Columns maps the optional columns of a CSV header to their positions.
+type Columns struct {
+	header []string
+	index  map[string]int
+}
+
+// ParseHeader validates a CSV header: the base columns in order, followed by any
+// known optional columns.
+func ParseHeader(header []string) (Columns, error) {
+	if len(header) < len(BaseHeader) || !compareStringSlices(header[:len(BaseHeader)], BaseHeader) {
+		return Columns{}, ErrInvalidHeader
+	}
+	c := Columns{header: header, index: map[string]int{}}
+	for i, name := range header[len(BaseHeader):] {
+		if !isOptionalColumn(name) {
+			return Columns{}, ErrInvalidHeader
+		}
+		if _, dup := c.index[name]; dup {
+			return Columns{}, ErrInvalidHeader
+		}
+		c.index[name] = len(BaseHeader) + i
+	}
+	return c, nil
+}
+
+// Parse converts a record into a transaction. line is used in error messages only.
+func (c Columns) Parse(record []string, line int) (Transaction, error) {
+	if len(record) != len(c.header) {
+		return Transaction{}, fmt.Errorf("invalid record length on line %d: expected %d fields, got %d", line, len(c.header), len(record))
+	}
+	amount, err := strconv.ParseFloat(record[3], 64)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("invalid amount on line %d: %w", line, err)
+	}
+	tx := Transaction{
+		Date:        record[0],
+		Category:    record[1],
+		Description: record[2],
+		Amount:      amount,
+	}
+	if i, ok := c.index["Fixed"]; ok && record[i] != "" {
+		fixed, err := strconv.ParseBool(record[i])
+		if err != nil {
+			return Transaction{}, fmt.Errorf("invalid Fixed value on line %d: %w", line, err)
+		}
+		tx.Fixed = fixed
+	}
+	if i, ok := c.index["Payer"]; ok {
+		tx.Payer = record[i]
+	}
+	if i, ok := c.index["ID"]; ok {
+		tx.ID = record[i]
+	}
+	if i, ok := c.index["Tags"]; ok && record[i] != "" {
+		tx.Tags = NormalizeTags(strings.Split(record[i], tagSeparator))
+	}
+	if i, ok := c.index["Splits"]; ok && record[i] != "" {
+		if tx.Splits, err = parseSplits(record[i]); err == nil {
+			err = tx.ValidateSplits()
+		}
+		if err != nil {
+			return Transaction{}, fmt.Errorf("invalid Splits value on line %d: %w", line, err)
+		}
+	}
+	if i, ok := c.index["Fiscal"]; ok {
+		tx.Fiscal = record[i]
+	}
+	if i, ok := c.index["Time"]; ok && record[i] != "" {
+		if tx.Time, err = time.Parse(TimeLayout, record[i]); err != nil {
+			return Transaction{}, fmt.Errorf("invalid Time value on line %d: %w", line, err)
+		}
+	}
+	return tx, nil
+}
+
+// Header returns the CSV header needed to store txs losslessly. IDs that can be
+// derived from the content are not stored, see WriteCSV.
+func Header(txs []Transaction) []string {
+	header := append([]string(nil), BaseHeader...)
+	var fixed, payer, id, tags, splits, fiscal, timed bool
+	for _, tx := range txs {
+		fixed = fixed || tx.Fixed
+		payer = payer || tx.Payer != ""
+		id = id || tx.ID != ""
+		tags = tags || len(tx.Tags) > 0
+		splits = splits || len(tx.Splits) > 0
+		fiscal = fiscal || tx.Fiscal != ""
+		timed = timed || !tx.Time.IsZero()
+	}
+	if fixed {
+		header = append(header, "Fixed")
+	}
+	if payer {
+		header = append(header, "Payer")
+	}
+	if id {
+		header = append(header, "ID")
+	}
+	if tags {
+		header = append(header, "Tags")
+	}
+	if splits {
+		header = append(header, "Splits")
+	}
+	if fiscal {
+		header = append(header, "Fiscal")
+	}
+	if timed {
+		header = append(header, "Time")
+	}
+	return header
+}
+
+// Record formats tx according to header.
+func Record(header []string, tx Transaction) []string {
+	record := []string{
+		tx.Date,
+		tx.Category,
+		tx.Description,
+		strconv.FormatFloat(tx.Amount, 'f', 2, 64),
+	}
+	for _, name := range header[len(BaseHeader):] {
+		switch name {
+		case "Fixed":
+			if tx.Fixed {
+				record = append(record, "true")
+			} else {
+				record = append(record, "")
+			}
+		case "Payer":
+			record = append(record, tx.Payer)
+		case "ID":
+			record = append(record, tx.ID)
+		case "Tags":
+			record = append(record, strings.Join(tx.Tags, tagSeparator))
+		case "Splits":
+			record = append(record, formatSplits(tx.Splits))
+		case "Fiscal":
+			record = append(record, tx.Fiscal)
+		case "Time":
+			if tx.Time.IsZero() {
+				record = append(record, "")
+			} else {
+				record = append(record, tx.Time.Format(TimeLayout))
+			}
+		}
+	}
+	return record
+}
+
+// WriteCSV writes txs with a header to w. Only IDs that differ from the derived
+// ones are written, so a ledger without edits keeps the plain format.
+func WriteCSV(w io.Writer, txs []Transaction) error {
+	txs = storedIDs(txs)
+	writer := csv.NewWriter(w)
+	header := Header(txs)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if err := writer.Write(Record(header, tx)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func isOptionalColumn(name string) bool {
+	for _, c := range optionalColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package data
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHeader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr bool
+	}{
+		{"base", "Date,Category,Description,Amount", false},
+		{"with fixed", "Date,Category,Description,Amount,Fixed", false},
+		{"with payer", "Date,Category,Description,Amount,Fixed,Payer", false},
+		{"unknown column", "Date,Category,Description,Amount,Note", true},
+		{"duplicate column", "Date,Category,Description,Amount,Fixed,Fixed", true},
+		{"reordered base", "Category,Date,Description,Amount", true},
+		{"too short", "Date,Category,Description", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := ParseHeader(strings.Split(tt.header, ","))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHeader(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidHeader) {
+				t.Errorf("ParseHeader(%q) error = %v, want ErrInvalidHeader", tt.header, err)
+			}
+		})
+	}
+}
+
+func TestWriteCSVRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		txs        []Transaction
+		wantHeader string
+	}{
+		{
+			name:       "plain ledger keeps four columns",
+			txs:        []Transaction{{Date: "2025-08-01", Category: "groceries", Description: "Milk, bread", Amount: 120}},
+			wantHeader: "Date,Category,Description,Amount",
+		},
+		{
+			name: "fixed column only when used",
+			txs: []Transaction{
+				{Date: "2025-08-01", Category: "rent", Description: "Flat", Amount: 30000, Fixed: true},
+				{Date: "2025-08-02", Category: "dining", Amount: 450},
+			},
+			wantHeader: "Date,Category,Description,Amount,Fixed",
+		},
+		{
+			name: "payer column only when used",
+			txs: []Transaction{
+				{Date: "2025-08-01", Category: "groceries", Amount: 800, Payer: "Anya"},
+				{Date: "2025-08-02", Category: "dining", Amount: 450},
+			},
+			wantHeader: "Date,Category,Description,Amount,Payer",
+		},
+		{
+			name: "tags column only when used",
+			txs: []Transaction{
+				{Date: "2025-08-01", Category: "groceries", Amount: 800, Tags: []string{"kazan", "trip"}},
+				{Date: "2025-08-02", Category: "dining", Amount: 450},
+			},
+			wantHeader: "Date,Category,Description,Amount,Tags",
+		},
+		{
+			name: "splits column only when used",
+			txs: []Transaction{
+				{Date: "2025-08-01", Category: "groceries", Description: "Auchan", Amount: 1200.5, Splits: []Split{{"groceries", 800.25}, {"food/snacks", 400.25}}},
+				{Date: "2025-08-02", Category: "dining", Amount: 450},
+			},
+			wantHeader: "Date,Category,Description,Amount,Splits",
+		},
+		{
+			name: "fiscal column only when used",
+			txs: []Transaction{
+				{Date: "2025-08-01", Category: "groceries", Amount: 1234.5, Fiscal: "7380440800000000-12345-1234567890"},
+				{Date: "2025-08-02", Category: "dining", Amount: 450},
+			},
+			wantHeader: "Date,Category,Description,Amount,Fiscal",
+		},
+		{
+			name: "time column only when used",
+			txs: []Transaction{
+				{Date: "2025-08-01", Category: "groceries", Amount: 800, Time: time.Date(2025, 8, 1, 18, 45, 10, 0, time.FixedZone("", 3*60*60))},
+				{Date: "2025-08-02", Category: "dining", Amount: 450},
+			},
+			wantHeader: "Date,Category,Description,Amount,Time",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if err := WriteCSV(&buf, tt.txs); err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(buf.String(), "\n")
+			if lines[0] != tt.wantHeader {
+				t.Errorf("header = %q, want %q", lines[0], tt.wantHeader)
+			}
+			columns, err := ParseHeader(strings.Split(lines[0], ","))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []Transaction
+			records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, r := range records[1:] {
+				tx, err := columns.Parse(r, i+2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, tx)
+			}
+			if !reflect.DeepEqual(got, tt.txs) {
+				t.Errorf("round trip = %+v, want %+v", got, tt.txs)
+			}
+		})
+	}
+}
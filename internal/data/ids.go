@@ -0,0 +1,205 @@
+package data
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strconv"
+)
+
+// ErrNotFound is returned when no transaction has the requested ID.
+var ErrNotFound = errors.New("transaction not found")
+
+// ErrChanged is returned by a conditional write when the stored transaction no
+// longer matches the one the caller saw.
+var ErrChanged = errors.New("transaction has changed")
+
+// Transaction IDs are derived from the content of the row and its occurrence among
+// identical rows, so a plain ledger needs no ID column. An ID is stored in the
+// optional ID column only when it differs from the derived one, e.g. after an edit,
+// which keeps it stable from then on.
+
+// derivedIDs returns the content-derived ID of every transaction.
+func derivedIDs(txs []Transaction) []string {
+	ids := make([]string, len(txs))
+	seen := map[string]int{}
+	for i, tx := range txs {
+		// A timed transaction is keyed by its time, as its date depends on the
+		// location, see SetLocation
+		day := tx.Date
+		if !tx.Time.IsZero() {
+			day = tx.Time.UTC().Format(TimeLayout)
+		}
+		key := day + "\x00" + tx.Category + "\x00" + tx.Description + "\x00" + strconv.FormatFloat(tx.Amount, 'f', 2, 64)
+		ids[i] = hashID(key + "\x00" + strconv.Itoa(seen[key]))
+		seen[key]++
+	}
+	return ids
+}
+
+func hashID(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:6])
+}
+
+// adopt appends txs to the ledger, moving their IDs to d.ids and assigning the
+// missing or clashing ones. It returns the stored transactions with their IDs.
+// Callers must hold d.mu.
+func (d *Data) adopt(txs []Transaction) []Transaction {
+	taken := make(map[string]bool, len(d.ids)+len(txs))
+	for _, id := range d.ids {
+		taken[id] = true
+	}
+	start := len(d.Transactions)
+	for _, tx := range txs {
+		id := tx.ID
+		if taken[id] {
+			id = ""
+		}
+		taken[id] = id != ""
+		tx.ID = ""
+		d.Transactions = append(d.Transactions, tx)
+		d.ids = append(d.ids, id)
+	}
+	derived := derivedIDs(d.Transactions)
+	for i := start; i < len(d.ids); i++ {
+		if d.ids[i] != "" {
+			continue
+		}
+		id := derived[i]
+		for n := 1; taken[id]; n++ {
+			id = hashID(derived[i] + "\x00" + strconv.Itoa(n))
+		}
+		d.ids[i] = id
+		taken[id] = true
+	}
+	return d.withIDs(start, len(d.Transactions))
+}
+
+// withIDs returns copies of the transactions in [from, to) with their IDs set.
+// Callers must hold d.mu.
+func (d *Data) withIDs(from, to int) []Transaction {
+	res := make([]Transaction, to-from)
+	copy(res, d.Transactions[from:to])
+	for i := range res {
+		res[i].ID = d.ids[from+i]
+	}
+	return res
+}
+
+// indexOf returns the position of the transaction with the given ID, or -1.
+// Callers must hold d.mu.
+func (d *Data) indexOf(id string) int {
+	for i, v := range d.ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// storedIDs returns txs with the IDs that equal the derived ones cleared, as written
+// to the CSV.
+func storedIDs(txs []Transaction) []Transaction {
+	derived := derivedIDs(txs)
+	res := make([]Transaction, len(txs))
+	copy(res, txs)
+	for i := range res {
+		if res[i].ID == derived[i] {
+			res[i].ID = ""
+		}
+	}
+	return res
+}
+
+// CreateTransaction appends tx like AddTransaction and returns it with its ID.
+func (d *Data) CreateTransaction(tx Transaction) (Transaction, error) {
+	stored, err := d.add([]Transaction{tx})
+	if err != nil {
+		return Transaction{}, err
+	}
+	return stored[0], nil
+}
+
+// GetTransaction returns the transaction with the given ID.
+func (d *Data) GetTransaction(id string) (Transaction, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := d.indexOf(id)
+	if i < 0 {
+		return Transaction{}, ErrNotFound
+	}
+	return d.withIDs(i, i+1)[0], nil
+}
+
+// UpdateTransaction replaces the transaction with tx.ID, keeping its position.
+func (d *Data) UpdateTransaction(tx Transaction) error {
+	return d.UpdateTransactionIf(tx, nil)
+}
+
+// UpdateTransactionIf is UpdateTransaction, applied only if match accepts the
+// stored transaction and ErrChanged otherwise. The check and the write happen
+// under one lock, so no other edit can come in between. A nil match always
+// accepts.
+func (d *Data) UpdateTransactionIf(tx Transaction, match func(Transaction) bool) error {
+	d.mu.Lock()
+	i := d.indexOf(tx.ID)
+	if i >= 0 {
+		if match != nil && !match(d.withIDs(i, i+1)[0]) {
+			d.mu.Unlock()
+			return ErrChanged
+		}
+		if err := d.checkFiscal([]Transaction{tx}, i); err != nil {
+			d.mu.Unlock()
+			return err
+		}
+		tx = d.normalize([]Transaction{tx})[0]
+		tx.ID = ""
+		d.Transactions[i] = tx
+		tx.ID = d.ids[i]
+		// Its sort keys may change, so query cursors are no longer valid.
+		d.generation++
+	}
+	d.mu.Unlock()
+	if i < 0 {
+		return ErrNotFound
+	}
+	if err := d.save(); err != nil {
+		return err
+	}
+	d.changes.publish(ChangeUpdate, []Transaction{tx})
+	return nil
+}
+
+// DeleteTransaction removes the transaction with the given ID.
+func (d *Data) DeleteTransaction(id string) error {
+	return d.DeleteTransactionIf(id, nil)
+}
+
+// DeleteTransactionIf is DeleteTransaction, applied only if match accepts the
+// stored transaction, see UpdateTransactionIf.
+func (d *Data) DeleteTransactionIf(id string, match func(Transaction) bool) error {
+	d.mu.Lock()
+	i := d.indexOf(id)
+	var deleted []Transaction
+	if i >= 0 {
+		if match != nil && !match(d.withIDs(i, i+1)[0]) {
+			d.mu.Unlock()
+			return ErrChanged
+		}
+		deleted = d.withIDs(i, i+1)
+		d.Transactions = append(d.Transactions[:i], d.Transactions[i+1:]...)
+		d.ids = append(d.ids[:i], d.ids[i+1:]...)
+		// Later positions shift, so query cursors are no longer valid.
+		d.generation++
+	}
+	d.mu.Unlock()
+	if i < 0 {
+		return ErrNotFound
+	}
+	if err := d.save(); err != nil {
+		return err
+	}
+	d.changes.publish(ChangeDelete, deleted)
+	return nil
+}
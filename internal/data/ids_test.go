@@ -0,0 +1,122 @@
+package data
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransactionIDs(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "expenses.csv")
+	d, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coffee := Transaction{Date: "2025-08-01", Category: "dining", Description: "Coffee", Amount: 250}
+	if err := d.AddTransactions([]Transaction{coffee, coffee, {Date: "2025-08-02", Category: "transport", Description: "Metro", Amount: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	txs := d.GetAllTransactions()
+	if txs[0].ID == "" || txs[0].ID == txs[1].ID {
+		t.Fatalf("identical transactions got IDs %q and %q, want distinct ones", txs[0].ID, txs[1].ID)
+	}
+
+	// Derived IDs are not written, so the ledger keeps the plain format.
+	if header := readHeader(t, path); header != "Date,Category,Description,Amount" {
+		t.Errorf("header = %q, want the plain four columns", header)
+	}
+	reloaded, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.GetAllTransactions(); got[1].ID != txs[1].ID {
+		t.Errorf("ID after reload = %q, want %q", got[1].ID, txs[1].ID)
+	}
+
+	// An edited transaction keeps its ID, which is then stored.
+	edited := txs[1]
+	edited.Amount = 300
+	if err := d.UpdateTransaction(edited); err != nil {
+		t.Fatal(err)
+	}
+	if header := readHeader(t, path); header != "Date,Category,Description,Amount,ID" {
+		t.Errorf("header after edit = %q, want an ID column", header)
+	}
+	reloaded, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reloaded.GetTransaction(edited.ID); err != nil || got.Amount != 300 {
+		t.Errorf("GetTransaction() after reload = %+v, %v; want the edited transaction", got, err)
+	}
+
+	if err := reloaded.DeleteTransaction(txs[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.GetTransaction(txs[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTransaction() of a deleted transaction = %v, want ErrNotFound", err)
+	}
+	if got := reloaded.GetAllTransactions(); len(got) != 2 || got[0].ID != edited.ID || got[1].ID != txs[2].ID {
+		t.Errorf("transactions after delete = %+v, want the other two with their IDs", got)
+	}
+	if err := reloaded.UpdateTransaction(Transaction{ID: "missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateTransaction() of a missing ID = %v, want ErrNotFound", err)
+	}
+}
+
+func TestConditionalWrites(t *testing.T) {
+	t.Parallel()
+
+	d, err := New(filepath.Join(t.TempDir(), "expenses.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := d.CreateTransaction(Transaction{Date: "2025-08-01", Category: "dining", Description: "Coffee", Amount: 250})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := tx
+	unchanged := func(stored Transaction) bool { return stored.Amount == seen.Amount }
+
+	// Another edit comes in between reading and writing
+	tx.Amount = 300
+	if err := d.UpdateTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	edit := seen
+	edit.Description = "Latte"
+	if err := d.UpdateTransactionIf(edit, unchanged); !errors.Is(err, ErrChanged) {
+		t.Errorf("UpdateTransactionIf() of a changed transaction = %v, want ErrChanged", err)
+	}
+	if err := d.DeleteTransactionIf(tx.ID, unchanged); !errors.Is(err, ErrChanged) {
+		t.Errorf("DeleteTransactionIf() of a changed transaction = %v, want ErrChanged", err)
+	}
+	if got, _ := d.GetTransaction(tx.ID); got.Amount != 300 || got.Description != "Coffee" {
+		t.Errorf("transaction after rejected writes = %+v, want the other edit", got)
+	}
+
+	seen = tx
+	if err := d.UpdateTransactionIf(edit, unchanged); err != nil {
+		t.Errorf("UpdateTransactionIf() of an unchanged transaction: %v", err)
+	}
+	if err := d.DeleteTransactionIf(tx.ID, func(Transaction) bool { return true }); err != nil {
+		t.Errorf("DeleteTransactionIf(): %v", err)
+	}
+	if err := d.DeleteTransactionIf(tx.ID, unchanged); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteTransactionIf() of a deleted transaction = %v, want ErrNotFound", err)
+	}
+}
+
+func readHeader(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _, _ := strings.Cut(string(b), "\n")
+	return header
+}
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MerchantKey normalizes a transaction description into a merchant key so that
+// "PYATEROCHKA 20572" and "Pyaterochka 432 (QR)" compare equal. Store numbers,
+// parenthesized notes and punctuation are dropped.
+func MerchantKey(description string) string {
+	s := strings.ToLower(description)
+	if i := strings.Index(s, "("); i >= 0 {
+		s = s[:i]
+	}
+	var words []string
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-' || r == '_' || r == '*' || r == ',' || r == '.'
+	}) {
+		if strings.IndexFunc(w, unicode.IsDigit) >= 0 {
+			continue
+		}
+		w = strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) })
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Merchant returns the normalized merchant of the transaction, falling back to
+// the category when the description is empty.
+func (tx Transaction) Merchant() string {
+	if key := MerchantKey(tx.Description); key != "" {
+		return key
+	}
+	return strings.ToLower(tx.Category)
+}
@@ -0,0 +1,31 @@
+package data
+
+import "testing"
+
+func TestMerchantKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"store number", "PYATEROCHKA 20572", "pyaterochka"},
+		{"store number with suffix", "31YY Pyaterochka (QR)", "pyaterochka"},
+		{"dash separated code", "DIXY-78018D", "dixy"},
+		{"underscore code", "VKUSVILL_5775", "vkusvill"},
+		{"asterisk prefix", "YM*URENT", "ym urent"},
+		{"parenthesized note", "bilet.nspk.ru (metro)", "bilet nspk ru"},
+		{"plain words", "Kimchi to go", "kimchi to go"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := MerchantKey(tt.in); got != tt.want {
+				t.Errorf("MerchantKey(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package data
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor is returned for a cursor that does not belong to the current ledger.
+var ErrInvalidCursor = errors.New("invalid or stale cursor")
+
+// SortFields are the fields a query can be sorted by.
+var SortFields = []string{"date", "amount", "category", "description", "merchant", "payer"}
+
+// Query selects transactions. Zero values do not filter.
+type Query struct {
+	From, To    string   // YYYY-MM-DD, inclusive
+	Categories  []string // any of, case-insensitive, including subcategories and split lines, see InCategory
+	Tags        []string // any of, see Transaction.HasTag
+	MinAmount   *float64
+	MaxAmount   *float64
+	Description string         // case-insensitive substring
+	Pattern     *regexp.Regexp // matched against the description
+	Merchant    string         // normalized like MerchantKey
+	Payer       string         // case-insensitive
+	// Sort is one of SortFields, prefixed with "-" for descending order. Ties keep
+	// ledger order. Defaults to date.
+	Sort   string
+	Cursor string // NextCursor of the previous page
+	Limit  int    // page size; 0 returns everything after Cursor
+}
+
+// Totals aggregates all transactions matching a query, not only the current page.
+type Totals struct {
+	Count      int                `json:"count"`
+	Amount     float64            `json:"amount"`
+	Average    float64            `json:"average"`
+	Min        float64            `json:"min"`
+	Max        float64            `json:"max"`
+	ByCategory map[string]float64 `json:"by_category"`
+}
+
+// Page is one page of query results.
+type Page struct {
+	Transactions []Transaction
+	Totals       Totals
+	NextCursor   string // empty on the last page
+}
+
+// Match reports whether tx passes the filters of q.
+func (q Query) Match(tx Transaction) bool {
+	if q.From != "" && tx.Date < q.From || q.To != "" && tx.Date > q.To {
+		return false
+	}
+	if len(q.Categories) > 0 {
+		found := false
+		for _, c := range q.Categories {
+			if tx.inCategory(c) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(q.Tags) > 0 {
+		found := false
+		for _, t := range q.Tags {
+			if tx.HasTag(t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.MinAmount != nil && tx.Amount < *q.MinAmount || q.MaxAmount != nil && tx.Amount > *q.MaxAmount {
+		return false
+	}
+	if q.Description != "" && !strings.Contains(strings.ToLower(tx.Description), strings.ToLower(q.Description)) {
+		return false
+	}
+	if q.Pattern != nil && !q.Pattern.MatchString(tx.Description) {
+		return false
+	}
+	if q.Merchant != "" && tx.Merchant() != MerchantKey(q.Merchant) {
+		return false
+	}
+	if q.Payer != "" && !strings.EqualFold(tx.Payer, q.Payer) {
+		return false
+	}
+	return true
+}
+
+// entry is a matching transaction with its position in the ledger, the tie-breaker
+// that keeps sorting and cursors stable.
+type entry struct {
+	tx    Transaction
+	index int
+}
+
+// Query returns the transactions matching q, sorted and paginated.
+func (d *Data) Query(q Query) (Page, error) {
+	field, desc := strings.TrimPrefix(q.Sort, "-"), strings.HasPrefix(q.Sort, "-")
+	if field == "" {
+		field = "date"
+	}
+	key, ok := sortKeys[field]
+	if !ok {
+		return Page{}, fmt.Errorf("invalid sort field %q: expected one of %s", field, strings.Join(SortFields, ", "))
+	}
+	less := func(a, b entry) bool {
+		if c := key(a.tx, b.tx); c != 0 {
+			return (c < 0) != desc
+		}
+		return a.index < b.index
+	}
+
+	d.mu.Lock()
+	var matched []entry
+	for i, tx := range d.Transactions {
+		if q.Match(tx) {
+			tx.ID = d.ids[i]
+			matched = append(matched, entry{tx: tx, index: i})
+		}
+	}
+	var after *entry
+	if q.Cursor != "" {
+		gen, i, err := decodeCursor(q.Cursor)
+		if err != nil || gen != d.generation || i >= len(d.Transactions) {
+			d.mu.Unlock()
+			return Page{}, ErrInvalidCursor
+		}
+		after = &entry{tx: d.Transactions[i], index: i}
+	}
+	generation := d.generation
+	d.mu.Unlock()
+
+	sort.Slice(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+
+	page := Page{Totals: totals(matched, q.Categories), Transactions: []Transaction{}}
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matched), func(i int) bool { return less(*after, matched[i]) })
+	}
+	end := len(matched)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+		page.NextCursor = encodeCursor(generation, matched[end-1].index)
+	}
+	for _, e := range matched[start:end] {
+		page.Transactions = append(page.Transactions, e.tx)
+	}
+	return page, nil
+}
+
+// sortKeys compare two transactions by a field.
+var sortKeys = map[string]func(a, b Transaction) int{
+	"date": Chronological,
+	"amount": func(a, b Transaction) int {
+		switch {
+		case a.Amount < b.Amount:
+			return -1
+		case a.Amount > b.Amount:
+			return 1
+		}
+		return 0
+	},
+	"category": func(a, b Transaction) int {
+		return strings.Compare(strings.ToLower(a.Category), strings.ToLower(b.Category))
+	},
+	"description": func(a, b Transaction) int {
+		return strings.Compare(strings.ToLower(a.Description), strings.ToLower(b.Description))
+	},
+	"merchant": func(a, b Transaction) int { return strings.Compare(a.Merchant(), b.Merchant()) },
+	"payer":    func(a, b Transaction) int { return strings.Compare(strings.ToLower(a.Payer), strings.ToLower(b.Payer)) },
+}
+
+// totals aggregates entries; with categories only their split lines count, see PartsIn.
+func totals(entries []entry, categories []string) Totals {
+	t := Totals{Count: len(entries), ByCategory: map[string]float64{}}
+	for i, e := range entries {
+		amount := e.tx.AmountIn(categories)
+		t.Amount += amount
+		for _, p := range e.tx.PartsIn(categories) {
+			t.ByCategory[strings.ToLower(p.Category)] += p.Amount
+		}
+		if i == 0 || amount < t.Min {
+			t.Min = amount
+		}
+		if i == 0 || amount > t.Max {
+			t.Max = amount
+		}
+	}
+	if t.Count > 0 {
+		t.Average = t.Amount / float64(t.Count)
+	}
+	return t
+}
+
+// The cursor is the ledger position of the last transaction of a page. Positions
+// change when the ledger is replaced or a transaction deleted, and sort keys when
+// transactions are updated or recategorized; each bumps the generation and so
+// invalidates older cursors, rather than the next page skipping or repeating rows.
+func encodeCursor(generation, index int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", generation, index)))
+}
+
+func decodeCursor(cursor string) (generation, index int, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	gen, idx, ok := strings.Cut(string(b), ".")
+	if !ok {
+		return 0, 0, ErrInvalidCursor
+	}
+	if generation, err = strconv.Atoi(gen); err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	if index, err = strconv.Atoi(idx); err != nil || index < 0 {
+		return 0, 0, ErrInvalidCursor
+	}
+	return generation, index, nil
+}
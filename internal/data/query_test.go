@@ -0,0 +1,182 @@
+package data
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	d, err := New(filepath.Join(t.TempDir(), "expenses.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddTransactions([]Transaction{
+		{Date: "2025-08-01", Category: "groceries", Description: "Pyaterochka 12", Amount: 800, Payer: "Anya"},
+		{Date: "2025-08-01", Category: "dining/cafes", Description: "Coffee", Amount: 250},
+		{Date: "2025-08-02", Category: "Groceries", Description: "PYATEROCHKA 15", Amount: 1200, Payer: "Lev", Splits: []Split{{"Groceries", 1000}, {"household", 200}}},
+		{Date: "2025-08-03", Category: "transport", Description: "Metro", Amount: 100, Payer: "anya", Tags: []string{"kazan"}},
+		{Date: "2025-08-05", Category: "dining", Description: "Pizza", Amount: 900, Payer: "Lev", Tags: []string{"Kazan", "friends"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	amount := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name      string
+		q         Query
+		want      []string // descriptions in order
+		wantTotal float64
+	}{
+		{"all by date", Query{}, []string{"Pyaterochka 12", "Coffee", "PYATEROCHKA 15", "Metro", "Pizza"}, 3250},
+		{"date range", Query{From: "2025-08-02", To: "2025-08-03"}, []string{"PYATEROCHKA 15", "Metro"}, 1300},
+		{"categories", Query{Categories: []string{"GROCERIES", "transport"}}, []string{"Pyaterochka 12", "PYATEROCHKA 15", "Metro"}, 1900},
+		{"category subtree", Query{Categories: []string{"Dining"}}, []string{"Coffee", "Pizza"}, 1150},
+		{"subcategory", Query{Categories: []string{"dining/CAFES"}}, []string{"Coffee"}, 250},
+		{"tags", Query{Tags: []string{"#kazan"}}, []string{"Metro", "Pizza"}, 1000},
+		// only the lines of a split in the filter count
+		{"split line", Query{Categories: []string{"household"}}, []string{"PYATEROCHKA 15"}, 200},
+		{"amount range", Query{MinAmount: amount(250), MaxAmount: amount(900)}, []string{"Pyaterochka 12", "Coffee", "Pizza"}, 1950},
+		{"substring", Query{Description: "pyater"}, []string{"Pyaterochka 12", "PYATEROCHKA 15"}, 2000},
+		{"regexp", Query{Pattern: regexp.MustCompile(`^P\w+a$`)}, []string{"Pizza"}, 900},
+		{"merchant", Query{Merchant: "Pyaterochka"}, []string{"Pyaterochka 12", "PYATEROCHKA 15"}, 2000},
+		{"payer", Query{Payer: "ANYA"}, []string{"Pyaterochka 12", "Metro"}, 900},
+		{"sort by amount descending", Query{Sort: "-amount", Categories: []string{"dining", "transport"}}, []string{"Pizza", "Coffee", "Metro"}, 1250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			page, err := d.Query(tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, tx := range page.Transactions {
+				got = append(got, tx.Description)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+			if page.Totals.Amount != tt.wantTotal || page.Totals.Count != len(tt.want) {
+				t.Errorf("totals = %+v, want %.2f in %d", page.Totals, tt.wantTotal, len(tt.want))
+			}
+		})
+	}
+
+	t.Run("split totals", func(t *testing.T) {
+		t.Parallel()
+		page, err := d.Query(Query{Categories: []string{"groceries"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if by := page.Totals.ByCategory; by["groceries"] != 1800 || len(by) != 1 || page.Totals.Max != 1000 {
+			t.Errorf("totals = %+v, want only the groceries lines, 1800 up to 1000", page.Totals)
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		t.Parallel()
+		var got []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatal("pagination does not end")
+			}
+			page, err := d.Query(Query{Sort: "-amount", Limit: 2, Cursor: cursor})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Totals.Count != 5 {
+				t.Errorf("totals count = %d, want the whole filtered set of 5", page.Totals.Count)
+			}
+			for _, tx := range page.Transactions {
+				got = append(got, tx.Description)
+			}
+			if cursor = page.NextCursor; cursor == "" {
+				break
+			}
+		}
+		want := []string{"PYATEROCHKA 15", "Pizza", "Pyaterochka 12", "Coffee", "Metro"}
+		if len(got) != len(want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		}
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		t.Parallel()
+		if _, err := d.Query(Query{Sort: "color"}); err == nil {
+			t.Error("expected an error for an unknown sort field")
+		}
+		if _, err := d.Query(Query{Cursor: "bm9wZQ"}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("error = %v, want ErrInvalidCursor", err)
+		}
+	})
+}
+
+func TestQueryCursorAfterWrite(t *testing.T) {
+	t.Parallel()
+
+	d, err := New(filepath.Join(t.TempDir(), "expenses.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, amount := range []float64{400, 300, 200, 100} {
+		if _, err := d.CreateTransaction(Transaction{Date: "2025-08-01", Category: "groceries", Amount: amount}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, err := d.Query(Query{Sort: "-amount", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Appending keeps the cursor: new rows have later positions.
+	if _, err := d.CreateTransaction(Transaction{Date: "2025-08-02", Category: "dining", Amount: 50}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Query(Query{Sort: "-amount", Limit: 2, Cursor: first.NextCursor}); err != nil {
+		t.Fatalf("next page after an append: %v", err)
+	}
+
+	writes := []struct {
+		name  string
+		write func() error
+	}{
+		{"update", func() error {
+			// The 400 of the first page drops below the 300 after the cursor.
+			tx := first.Transactions[0]
+			tx.Amount = 150
+			return d.UpdateTransaction(tx)
+		}},
+		{"recategorize", func() error {
+			_, err := d.Recategorize(func(c string) (string, bool) { return "food", c == "groceries" })
+			return err
+		}},
+	}
+	for _, w := range writes {
+		page, err := d.Query(Query{Sort: "-amount", Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.write(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.Query(Query{Sort: "-amount", Limit: 2, Cursor: page.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("next page after %s: error = %v, want ErrInvalidCursor", w.name, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Split is one line of a transaction divided across categories, e.g. the
+// household goods on a supermarket receipt.
+type Split struct {
+	Category string
+	Amount   float64
+}
+
+// Parts returns the splits of tx, or its whole amount in its category when it
+// is not split. Category totals and limits add up the parts.
+func (tx Transaction) Parts() []Split {
+	if len(tx.Splits) == 0 {
+		return []Split{{Category: tx.Category, Amount: tx.Amount}}
+	}
+	return tx.Splits
+}
+
+// PartsIn returns the parts of tx in any of categories, see InCategory, or all
+// of them without categories. Totals under a category filter add up these, so
+// the other lines of a split receipt are left out.
+func (tx Transaction) PartsIn(categories []string) []Split {
+	if len(categories) == 0 {
+		return tx.Parts()
+	}
+	var res []Split
+	for _, p := range tx.Parts() {
+		for _, c := range categories {
+			if InCategory(p.Category, c) {
+				res = append(res, p)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// AmountIn returns the total of PartsIn.
+func (tx Transaction) AmountIn(categories []string) float64 {
+	if len(categories) == 0 {
+		return tx.Amount
+	}
+	var sum float64
+	for _, p := range tx.PartsIn(categories) {
+		sum += p.Amount
+	}
+	return sum
+}
+
+// inCategory reports whether tx or one of its split lines is in category filter,
+// see InCategory.
+func (tx Transaction) inCategory(filter string) bool {
+	if InCategory(tx.Category, filter) {
+		return true
+	}
+	for _, s := range tx.Splits {
+		if InCategory(s.Category, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSplits checks that the splits of tx, if any, have at least two lines
+// with a category and a positive amount each, summing to the amount of tx.
+func (tx Transaction) ValidateSplits() error {
+	if len(tx.Splits) == 0 {
+		return nil
+	}
+	if len(tx.Splits) == 1 {
+		return errors.New("a split needs at least two lines")
+	}
+	var sum float64
+	for _, s := range tx.Splits {
+		if strings.TrimSpace(s.Category) == "" {
+			return errors.New("every split line needs a category")
+		}
+		if strings.Contains(s.Category, splitSeparator) {
+			return fmt.Errorf("split category %q must not contain %q", s.Category, splitSeparator)
+		}
+		if s.Amount <= 0 {
+			return errors.New("split amounts must be positive")
+		}
+		sum += s.Amount
+	}
+	if math.Abs(sum-tx.Amount) >= 0.005 {
+		return fmt.Errorf("split amounts add up to %.2f, not %.2f", sum, tx.Amount)
+	}
+	return nil
+}
+
+// In the Splits CSV column the lines are written as category:amount, separated
+// by semicolons, e.g. groceries:800.00;household:400.00. The amount follows the
+// last colon, so only semicolons are ruled out in split categories.
+const (
+	splitSeparator       = ";"
+	splitAmountSeparator = ":"
+)
+
+func formatSplits(splits []Split) string {
+	lines := make([]string, len(splits))
+	for i, s := range splits {
+		lines[i] = s.Category + splitAmountSeparator + strconv.FormatFloat(s.Amount, 'f', 2, 64)
+	}
+	return strings.Join(lines, splitSeparator)
+}
+
+func parseSplits(s string) ([]Split, error) {
+	var splits []Split
+	for _, line := range strings.Split(s, splitSeparator) {
+		i := strings.LastIndex(line, splitAmountSeparator)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid split %q, expected category:amount", line)
+		}
+		amount, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid split amount %q: %w", line[i+1:], err)
+		}
+		splits = append(splits, Split{Category: line[:i], Amount: amount})
+	}
+	return splits, nil
+}
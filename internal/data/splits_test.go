@@ -0,0 +1,51 @@
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSplits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		splits  []Split
+		wantErr bool
+	}{
+		{"not split", nil, false},
+		{"sums to the amount", []Split{{"groceries", 800}, {"household", 399.999}}, false},
+		{"single line", []Split{{"groceries", 1200}}, true},
+		{"short", []Split{{"groceries", 800}, {"household", 300}}, true},
+		{"no category", []Split{{"groceries", 800}, {" ", 400}}, true},
+		{"separator in category", []Split{{"groceries", 800}, {"a;b", 400}}, true},
+		{"negative line", []Split{{"groceries", 1600}, {"household", -400}}, true},
+	}
+	for _, tt := range tests {
+		tx := Transaction{Date: "2025-08-01", Category: "groceries", Amount: 1200, Splits: tt.splits}
+		if err := tx.ValidateSplits(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateSplits() = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseSplitsColumn(t *testing.T) {
+	t.Parallel()
+
+	columns, err := ParseHeader(strings.Split("Date,Category,Description,Amount,Splits", ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := columns.Parse([]string{"2025-08-01", "groceries", "", "1200.00", "cafés: bar:200;groceries:1000"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Splits) != 2 || tx.Splits[0].Category != "cafés: bar" || tx.Splits[1].Amount != 1000 {
+		t.Errorf("Parse() splits = %+v, want the category up to the last colon", tx.Splits)
+	}
+	for _, bad := range []string{"groceries:800;household:300", "groceries", "groceries:x;household:400"} {
+		if _, err := columns.Parse([]string{"2025-08-01", "groceries", "", "1200.00", bad}, 2); err == nil {
+			t.Errorf("Parse() accepted splits %q", bad)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package data
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tags are free-form labels that cut across categories, e.g. a trip: "kazan".
+// They are stored lower-cased without the leading #, in the order first given.
+
+// NormalizeTags lower-cases tags, strips a leading # and drops empty and
+// duplicate ones. Characters other than letters, digits, - and _ end a tag.
+func NormalizeTags(tags []string) []string {
+	var res []string
+	seen := map[string]bool{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimLeft(strings.TrimSpace(t), "#"))
+		if end := strings.IndexFunc(t, func(r rune) bool { return !isTagRune(r) }); end >= 0 {
+			t = t[:end]
+		}
+		if t != "" && !seen[t] {
+			seen[t] = true
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_'
+}
+
+// ExtractTags splits the #tags out of free text, e.g. a chat message, and
+// returns the remaining words and the normalized tags.
+func ExtractTags(text string) (string, []string) {
+	var words, tags []string
+	for _, w := range strings.Fields(text) {
+		if len(w) > 1 && strings.HasPrefix(w, "#") {
+			tags = append(tags, w)
+			continue
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, " "), NormalizeTags(tags)
+}
+
+// HasTag reports whether tx carries tag, ignoring case and a leading #.
+func (tx Transaction) HasTag(tag string) bool {
+	tag = strings.ToLower(strings.TrimLeft(tag, "#"))
+	for _, t := range tx.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// InCategory reports whether category is filter or lies below it in a path
+// such as food/cafes, ignoring case: food matches food and food/cafes.
+func InCategory(category, filter string) bool {
+	category, filter = strings.ToLower(category), strings.ToLower(strings.TrimSuffix(filter, "/"))
+	return category == filter || strings.HasPrefix(category, filter+"/")
+}
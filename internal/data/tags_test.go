@@ -0,0 +1,48 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractTags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text     string
+		wantText string
+		wantTags []string
+	}{
+		{"350 dining lunch", "350 dining lunch", nil},
+		{"350 dining #Kazan lunch #trip, #kazan", "350 dining lunch", []string{"kazan", "trip"}},
+		{"# 350 #", "# 350 #", nil},
+		{"1200 продукты #поездка_казань", "1200 продукты", []string{"поездка_казань"}},
+	}
+	for _, tt := range tests {
+		text, tags := ExtractTags(tt.text)
+		if text != tt.wantText || !reflect.DeepEqual(tags, tt.wantTags) {
+			t.Errorf("ExtractTags(%q) = %q, %v; want %q, %v", tt.text, text, tags, tt.wantText, tt.wantTags)
+		}
+	}
+}
+
+func TestInCategory(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		category, filter string
+		want             bool
+	}{
+		{"food", "food", true},
+		{"Food/Cafes", "food", true},
+		{"food/cafes", "food/cafes/", true},
+		{"food/cafes", "food/bars", false},
+		{"foodcourt", "food", false},
+		{"food", "food/cafes", false},
+	}
+	for _, tt := range tests {
+		if got := InCategory(tt.category, tt.filter); got != tt.want {
+			t.Errorf("InCategory(%q, %q) = %v, want %v", tt.category, tt.filter, got, tt.want)
+		}
+	}
+}
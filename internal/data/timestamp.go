@@ -0,0 +1,46 @@
+package data
+
+import (
+	"strings"
+	"time"
+)
+
+// TimeLayout is how the Time column stores a timestamp: RFC 3339, with the
+// offset of the zone it was recorded in.
+const TimeLayout = time.RFC3339
+
+// dateLayout is the form of Transaction.Date.
+const dateLayout = "2006-01-02"
+
+// SetLocation makes the date of every transaction with a time its day in loc,
+// e.g. the time zone of the reports, so everything grouping by day agrees.
+// Transactions already in the ledger are updated in memory; their IDs do not
+// depend on the date, see derivedIDs.
+func (d *Data) SetLocation(loc *time.Location) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.location = loc
+	for i, tx := range d.Transactions {
+		if !tx.Time.IsZero() {
+			d.Transactions[i].Date = d.dayOf(tx.Time)
+		}
+	}
+}
+
+// dayOf returns the date of t in the location of the ledger, or in its own zone
+// before SetLocation. Callers must hold d.mu.
+func (d *Data) dayOf(t time.Time) string {
+	if d.location != nil {
+		t = t.In(d.location)
+	}
+	return t.Format(dateLayout)
+}
+
+// Chronological compares a and b by date and then time. A transaction without a
+// time counts as the start of its day.
+func Chronological(a, b Transaction) int {
+	if c := strings.Compare(a.Date, b.Date); c != 0 {
+		return c
+	}
+	return a.Time.Compare(b.Time)
+}
@@ -0,0 +1,80 @@
+package data
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetLocation(t *testing.T) {
+	t.Parallel()
+
+	d, err := New(filepath.Join(t.TempDir(), "data.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 23:30 in Moscow is already the next day in Yekaterinburg
+	late := time.Date(2025, 8, 1, 23, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+	stored, err := d.CreateTransaction(Transaction{Category: "dining", Amount: 900, Time: late})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Date != "2025-08-01" {
+		t.Errorf("date before SetLocation = %q, want the day in its own zone", stored.Date)
+	}
+
+	d.SetLocation(time.FixedZone("YEKT", 5*60*60))
+	got, err := d.GetTransaction(stored.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Date != "2025-08-02" {
+		t.Errorf("date after SetLocation = %q, want 2025-08-02", got.Date)
+	}
+	if !got.Time.Equal(late) {
+		t.Errorf("time = %v, want %v", got.Time, late)
+	}
+
+	// The date follows the time on edits as well, and the ID stays
+	got.Time = late.Add(-2 * time.Hour)
+	got.Date = "2020-01-01"
+	if err := d.UpdateTransaction(got); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = d.GetTransaction(stored.ID); got.Date != "2025-08-01" {
+		t.Errorf("date after edit = %q, want 2025-08-01", got.Date)
+	}
+
+	reloaded, err := New(d.dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded.SetLocation(time.FixedZone("YEKT", 5*60*60))
+	if _, err := reloaded.GetTransaction(stored.ID); err != nil {
+		t.Errorf("ID after reload: %v", err)
+	}
+}
+
+func TestChronological(t *testing.T) {
+	t.Parallel()
+
+	morning := Transaction{Date: "2025-08-01", Time: time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)}
+	evening := Transaction{Date: "2025-08-01", Time: time.Date(2025, 8, 1, 20, 0, 0, 0, time.UTC)}
+	untimed := Transaction{Date: "2025-08-01"}
+	nextDay := Transaction{Date: "2025-08-02"}
+
+	for _, tt := range []struct {
+		a, b Transaction
+		want int
+	}{
+		{morning, evening, -1},
+		{evening, morning, 1},
+		{untimed, morning, -1},
+		{evening, nextDay, -1},
+		{untimed, untimed, 0},
+	} {
+		if got := Chronological(tt.a, tt.b); got != tt.want {
+			t.Errorf("Chronological(%s %v, %s %v) = %d, want %d", tt.a.Date, tt.a.Time, tt.b.Date, tt.b.Time, got, tt.want)
+		}
+	}
+}
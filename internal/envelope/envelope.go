@@ -0,0 +1,539 @@
+package envelope
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	// History is optional, files written before it was added lack it
+	envelopeHeader = []string{"Name", "Allocation", "Categories", "Start", "History"}
+	moveHeader     = []string{"Date", "Amount", "From", "To"}
+)
+
+// ErrNotFound is returned when an envelope does not exist.
+var ErrNotFound = errors.New("envelope not found")
+
+// Envelope receives Allocation at the start of every pay cycle since Start. Spending
+// in one of its categories draws from it; without categories the envelope name is
+// its only category.
+type Envelope struct {
+	Name       string
+	Allocation float64
+	Categories []string
+	Start      string // YYYY-MM-DD, first cycle that is allocated
+	// History lists every allocation with the day it was set, oldest first, once
+	// the allocation changed; empty while Allocation has applied since Start
+	History []Change
+}
+
+// Change is an allocation set on Date. It applies from the cycle containing Date
+// on, so the balances of earlier cycles keep the allocation they had.
+type Change struct {
+	Date       string
+	Allocation float64
+}
+
+// Move transfers money between two envelopes.
+type Move struct {
+	Date   string
+	Amount float64
+	From   string
+	To     string
+}
+
+// Balance is the state of an envelope on a given day. Balances persist across
+// cycles: whatever is left at the end of a cycle stays in the envelope.
+type Balance struct {
+	Envelope
+	Allocated  float64 // all allocations so far
+	Moved      float64 // net amount moved in
+	Spent      float64 // all spending so far
+	CycleSpent float64 // spending in the current cycle
+	Balance    float64 // Allocated + Moved - Spent
+}
+
+// Summary is the envelope view of the budget on a given day.
+type Summary struct {
+	CycleStart     time.Time
+	NextCycleStart time.Time
+	Budget         float64 // cycle budget
+	Unallocated    float64 // Budget minus the allocations of all envelopes
+	Unassigned     float64 // spending in the current cycle that no envelope covers
+	Envelopes      []Balance
+}
+
+// CycleFunc returns the start of the pay cycle containing a date and the next cycle start.
+type CycleFunc func(time.Time) (time.Time, time.Time)
+
+// Store keeps envelopes and moves in two CSV files.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	movesPath string
+	envelopes []Envelope
+	moves     []Move
+	// resolveCategory maps the categories of envelopes being set, see SetCategoryResolver
+	resolveCategory func(string) string
+}
+
+func New(path, movesPath string) (*Store, error) {
+	s := &Store{path: path, movesPath: movesPath}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// readCSV reads the records of a CSV file with the given header, of which the
+// last optional columns may be missing; their fields are then empty.
+func readCSV(path string, header []string, optional int) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	n := len(records[0])
+	if n < len(header)-optional || n > len(header) || strings.Join(records[0], ",") != strings.Join(header[:n], ",") {
+		return nil, fmt.Errorf("%s header does not match expected format", path)
+	}
+	for i := range records {
+		records[i] = append(records[i], make([]string, len(header)-n)...)
+	}
+	return records[1:], nil
+}
+
+func (s *Store) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := readCSV(s.path, envelopeHeader, 1)
+	if err != nil {
+		return err
+	}
+	for i, r := range records {
+		allocation, err := strconv.ParseFloat(r[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid allocation on line %d: %w", i+2, err)
+		}
+		history, err := parseHistory(r[4])
+		if err != nil {
+			return fmt.Errorf("invalid history on line %d: %w", i+2, err)
+		}
+		s.envelopes = append(s.envelopes, Envelope{Name: r[0], Allocation: allocation, Categories: splitCategories(r[2]), Start: r[3], History: history})
+	}
+
+	records, err = readCSV(s.movesPath, moveHeader, 0)
+	if err != nil {
+		return err
+	}
+	for i, r := range records {
+		amount, err := strconv.ParseFloat(r[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount on line %d: %w", i+2, err)
+		}
+		s.moves = append(s.moves, Move{Date: r[0], Amount: amount, From: r[2], To: r[3]})
+	}
+	return nil
+}
+
+// save persists envelopes; callers must hold s.mu.
+func (s *Store) save() error {
+	file, err := os.Create(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(envelopeHeader)
+	for _, e := range s.envelopes {
+		err := writer.Write([]string{
+			e.Name,
+			strconv.FormatFloat(e.Allocation, 'f', 2, 64),
+			strings.Join(e.Categories, ";"),
+			e.Start,
+			formatHistory(e.History),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// saveMoves persists moves; callers must hold s.mu.
+func (s *Store) saveMoves() error {
+	file, err := os.Create(s.movesPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(moveHeader)
+	for _, m := range s.moves {
+		err := writer.Write([]string{m.Date, strconv.FormatFloat(m.Amount, 'f', 2, 64), m.From, m.To})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// List returns all envelopes ordered by name.
+func (s *Store) List() []Envelope {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]Envelope, len(s.envelopes))
+	copy(res, s.envelopes)
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// SetCategoryResolver makes Set store resolve(category) for the categories of an
+// envelope, the way the ledger stores those of transactions, see
+// data.Data.SetCategoryResolver.
+func (s *Store) SetCategoryResolver(resolve func(string) string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolveCategory = resolve
+}
+
+// Set adds an envelope or updates the allocation and categories of an existing one.
+// An existing envelope keeps its start date and therefore its balance; a new
+// allocation is recorded in its History as of e.Start, the day it is set.
+// Categories are resolved, see SetCategoryResolver; a name that resolves to
+// another category is stored as the envelope's category.
+func (s *Store) Set(e Envelope) (Envelope, error) {
+	e.Name = strings.ToLower(strings.TrimSpace(e.Name))
+	if e.Name == "" || strings.ContainsAny(e.Name, " ,;") {
+		return Envelope{}, errors.New("envelope name must be a single word")
+	}
+	if e.Allocation < 0 {
+		return Envelope{}, errors.New("allocation must not be negative")
+	}
+	if _, err := time.Parse(dateLayout, e.Start); err != nil {
+		return Envelope{}, fmt.Errorf("invalid start date %q", e.Start)
+	}
+	// Resolved without holding s.mu: a category merge holds the category store
+	// while it recategorizes envelopes.
+	s.mu.Lock()
+	resolve := s.resolveCategory
+	s.mu.Unlock()
+	categories := e.Categories
+	if len(categories) == 0 && resolve != nil && strings.ToLower(resolve(e.Name)) != e.Name {
+		categories = []string{e.Name}
+	}
+	e.Categories = nil
+	for _, c := range categories {
+		c = strings.TrimSpace(c)
+		if resolve != nil {
+			c = resolve(c)
+		}
+		if c = strings.ToLower(c); c != "" && !slices.Contains(e.Categories, c) {
+			e.Categories = append(e.Categories, c)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.History = nil
+	for i := range s.envelopes {
+		if cur := s.envelopes[i]; cur.Name == e.Name {
+			e.History = cur.History
+			if e.Allocation != cur.Allocation {
+				e.History = cur.changed(Change{Date: e.Start, Allocation: e.Allocation})
+			}
+			e.Start = cur.Start
+			s.envelopes[i] = e
+			return e, s.save()
+		}
+	}
+	s.envelopes = append(s.envelopes, e)
+	return e, s.save()
+}
+
+// changed returns the history of e with c added. A change on the same day
+// replaces the one before.
+func (e Envelope) changed(c Change) []Change {
+	history := append([]Change(nil), e.history()...)
+	if last := len(history) - 1; history[last].Date >= c.Date {
+		history = history[:last]
+	}
+	if len(history) == 0 {
+		c.Date = e.Start
+	}
+	return append(history, c)
+}
+
+// history returns the allocations of e, oldest first.
+func (e Envelope) history() []Change {
+	if len(e.History) == 0 {
+		return []Change{{Date: e.Start, Allocation: e.Allocation}}
+	}
+	return e.History
+}
+
+// allocationIn returns the allocation of the cycle ending before next: the last
+// one set before next.
+func (e Envelope) allocationIn(next time.Time) float64 {
+	end := next.Format(dateLayout)
+	amount := 0.0
+	for _, c := range e.history() {
+		if c.Date < end {
+			amount = c.Allocation
+		}
+	}
+	return amount
+}
+
+// Delete removes an envelope. Its spending becomes unassigned.
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.envelopes {
+		if s.envelopes[i].Name == strings.ToLower(name) {
+			s.envelopes = append(s.envelopes[:i], s.envelopes[i+1:]...)
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// Recategorize sets the categories of every envelope for which rename returns
+// true, e.g. to merge categories, and returns how many envelopes changed. An
+// envelope without categories, which covers its own name, then lists the new one.
+func (s *Store) Recategorize(rename func(category string) (string, bool)) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for i, e := range s.envelopes {
+		var categories []string
+		changed := false
+		for _, c := range e.categories() {
+			if to, ok := rename(c); ok && to != c {
+				c, changed = strings.ToLower(to), true
+			}
+			if !slices.Contains(categories, c) {
+				categories = append(categories, c)
+			}
+		}
+		if changed {
+			s.envelopes[i].Categories = categories
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.save()
+}
+
+// Move transfers money between two existing envelopes.
+func (s *Store) Move(m Move) error {
+	m.From, m.To = strings.ToLower(m.From), strings.ToLower(m.To)
+	if m.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if m.From == m.To {
+		return errors.New("cannot move money to the same envelope")
+	}
+	if _, err := time.Parse(dateLayout, m.Date); err != nil {
+		return fmt.Errorf("invalid date %q", m.Date)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range []string{m.From, m.To} {
+		if s.find(name) < 0 {
+			return fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+	}
+	s.moves = append(s.moves, m)
+	return s.saveMoves()
+}
+
+// find returns the index of the named envelope or -1; callers must hold s.mu.
+func (s *Store) find(name string) int {
+	for i := range s.envelopes {
+		if s.envelopes[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Summary computes envelope balances on date from the ledger. Balances are derived from
+// allocations, moves and spending each time, so they always agree with the ledger.
+func (s *Store) Summary(txs []data.Transaction, cycle CycleFunc, date time.Time, budget float64) Summary {
+	envelopes := s.List()
+	s.mu.Lock()
+	moves := append([]Move(nil), s.moves...)
+	s.mu.Unlock()
+
+	start, next := cycle(date)
+	sum := Summary{CycleStart: start, NextCycleStart: next, Budget: budget, Unallocated: budget}
+
+	var owners []owner
+	balances := make([]Balance, len(envelopes))
+	for i, e := range envelopes {
+		balances[i] = Balance{Envelope: e, Allocated: allocated(cycle, e, date)}
+		for _, c := range e.categories() {
+			owners = append(owners, owner{category: c, envelope: i})
+		}
+		sum.Unallocated -= e.allocationIn(next)
+	}
+
+	dateStr, startStr := date.Format(dateLayout), start.Format(dateLayout)
+	for _, tx := range txs {
+		if tx.Date > dateStr {
+			continue
+		}
+		// Each split line counts towards the envelope of its own category
+		for _, p := range tx.Parts() {
+			i, ok := ownerOf(owners, p.Category)
+			if !ok {
+				if tx.Date >= startStr {
+					sum.Unassigned += p.Amount
+				}
+				continue
+			}
+			if tx.Date < balances[i].Start {
+				continue
+			}
+			balances[i].Spent += p.Amount
+			if tx.Date >= startStr {
+				balances[i].CycleSpent += p.Amount
+			}
+		}
+	}
+
+	for _, m := range moves {
+		if m.Date > dateStr {
+			continue
+		}
+		for i := range balances {
+			switch balances[i].Name {
+			case m.From:
+				balances[i].Moved -= m.Amount
+			case m.To:
+				balances[i].Moved += m.Amount
+			}
+		}
+	}
+
+	for i := range balances {
+		balances[i].Balance = balances[i].Allocated + balances[i].Moved - balances[i].Spent
+	}
+	sum.Envelopes = balances
+	return sum
+}
+
+// owner is an envelope drawing from a category.
+type owner struct {
+	category string
+	envelope int
+}
+
+// ownerOf returns the envelope that spending in category draws from: the one
+// with the most specific category that category lies in, see data.InCategory,
+// and on a tie the first by name.
+func ownerOf(owners []owner, category string) (int, bool) {
+	best := -1
+	for i, o := range owners {
+		if data.InCategory(category, o.category) && (best < 0 || len(o.category) > len(owners[best].category)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return owners[best].envelope, true
+}
+
+func (e Envelope) categories() []string {
+	if len(e.Categories) == 0 {
+		return []string{e.Name}
+	}
+	return e.Categories
+}
+
+// allocated sums the allocations of e over the cycles from the one containing its
+// start up to the one containing date, each cycle with the allocation it had.
+func allocated(cycle CycleFunc, e Envelope, date time.Time) float64 {
+	d, err := time.ParseInLocation(dateLayout, e.Start, date.Location())
+	if err != nil {
+		return 0
+	}
+	total := 0.0
+	for cs, next := cycle(d); !cs.After(date) && next.After(cs); cs, next = cycle(next) {
+		total += e.allocationIn(next)
+	}
+	return total
+}
+
+// parseHistory parses the History column, e.g. "2025-07-01=6000.00;2025-09-03=7000.00".
+func parseHistory(s string) ([]Change, error) {
+	var res []Change
+	for _, part := range strings.Split(s, ";") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		date, amount, _ := strings.Cut(part, "=")
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return nil, fmt.Errorf("invalid date %q", date)
+		}
+		v, err := strconv.ParseFloat(amount, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allocation %q", amount)
+		}
+		res = append(res, Change{Date: date, Allocation: v})
+	}
+	return res, nil
+}
+
+func formatHistory(history []Change) string {
+	parts := make([]string, len(history))
+	for i, c := range history {
+		parts[i] = c.Date + "=" + strconv.FormatFloat(c.Allocation, 'f', 2, 64)
+	}
+	return strings.Join(parts, ";")
+}
+
+func splitCategories(s string) []string {
+	var res []string
+	for _, c := range strings.Split(s, ";") {
+		if c = strings.TrimSpace(c); c != "" {
+			res = append(res, c)
+		}
+	}
+	return res
+}
@@ -0,0 +1,281 @@
+package envelope
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+// monthly cycles starting on the 1st
+func monthly(d time.Time) (time.Time, time.Time) {
+	start := time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, d.Location())
+	return start, start.AddDate(0, 1, 0)
+}
+
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(dateLayout, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func newStore(t *testing.T) *Store {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := New(filepath.Join(dir, "envelopes.csv"), filepath.Join(dir, "moves.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSummary(t *testing.T) {
+	t.Parallel()
+
+	s := newStore(t)
+	for _, e := range []Envelope{
+		{Name: "groceries", Allocation: 6000, Start: "2025-07-01"},
+		{Name: "cafes", Allocation: 2000, Categories: []string{"dining", "coffee"}, Start: "2025-07-15"},
+	} {
+		if _, err := s.Set(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Move(Move{Date: "2025-08-10", Amount: 500, From: "groceries", To: "cafes"}); err != nil {
+		t.Fatal(err)
+	}
+
+	txs := []data.Transaction{
+		{Date: "2025-07-05", Category: "Groceries", Amount: 4000},
+		{Date: "2025-07-20", Category: "coffee", Amount: 300},
+		{Date: "2025-08-02", Category: "dining", Amount: 2000},
+		// split lines count towards their own envelopes
+		{Date: "2025-08-03", Category: "dining", Amount: 600, Splits: []data.Split{{Category: "dining", Amount: 500}, {Category: "transport", Amount: 100}}},
+		{Date: "2025-07-03", Category: "transport", Amount: 999},
+		{Date: "2025-08-20", Category: "groceries", Amount: 777},
+	}
+
+	got := s.Summary(txs, monthly, mustDate(t, "2025-08-15"), 10000)
+	if got.Unallocated != 2000 || got.Unassigned != 100 {
+		t.Errorf("Unallocated = %.2f, Unassigned = %.2f, want 2000, 100", got.Unallocated, got.Unassigned)
+	}
+
+	want := map[string]struct{ allocated, spent, cycleSpent, balance float64 }{
+		// two cycles of 6000, 4000 spent, 500 moved out
+		"groceries": {12000, 4000, 0, 7500},
+		// created mid-July: two cycles, 2800 spent, 500 moved in
+		"cafes": {4000, 2800, 2500, 1700},
+	}
+	for _, b := range got.Envelopes {
+		w := want[b.Name]
+		for _, c := range []struct {
+			field     string
+			got, want float64
+		}{
+			{"Allocated", b.Allocated, w.allocated},
+			{"Spent", b.Spent, w.spent},
+			{"CycleSpent", b.CycleSpent, w.cycleSpent},
+			{"Balance", b.Balance, w.balance},
+		} {
+			if math.Abs(c.got-c.want) > 0.001 {
+				t.Errorf("%s %s = %.2f, want %.2f", b.Name, c.field, c.got, c.want)
+			}
+		}
+	}
+
+	// Reloading from disk gives the same result.
+	reloaded, err := New(s.path, s.movesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again := reloaded.Summary(txs, monthly, mustDate(t, "2025-08-15"), 10000)
+	for i := range got.Envelopes {
+		if got.Envelopes[i].Balance != again.Envelopes[i].Balance {
+			t.Errorf("balance of %s after reload = %.2f, want %.2f", got.Envelopes[i].Name, again.Envelopes[i].Balance, got.Envelopes[i].Balance)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	t.Parallel()
+
+	s := newStore(t)
+	for _, name := range []string{"cafes", "groceries"} {
+		if _, err := s.Set(Envelope{Name: name, Allocation: 1000, Start: "2025-08-01"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		move    Move
+		wantErr error
+	}{
+		{name: "valid", move: Move{Date: "2025-08-02", Amount: 500, From: "Cafes", To: "groceries"}},
+		{name: "unknown envelope", move: Move{Date: "2025-08-02", Amount: 500, From: "cafes", To: "travel"}, wantErr: ErrNotFound},
+		{name: "same envelope", move: Move{Date: "2025-08-02", Amount: 500, From: "cafes", To: "cafes"}, wantErr: errAny},
+		{name: "non-positive amount", move: Move{Date: "2025-08-02", Amount: 0, From: "cafes", To: "groceries"}, wantErr: errAny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Move(tt.move)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Errorf("Move() error = %v", err)
+			case tt.wantErr == errAny && err == nil:
+				t.Error("Move() succeeded, want error")
+			case tt.wantErr != nil && tt.wantErr != errAny && !errors.Is(err, tt.wantErr):
+				t.Errorf("Move() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+var errAny = errors.New("any error")
+
+func TestRecategorize(t *testing.T) {
+	t.Parallel()
+
+	s := newStore(t)
+	for _, e := range []Envelope{
+		{Name: "cafes", Allocation: 1000, Categories: []string{"кафе", "dining"}, Start: "2025-08-01"},
+		{Name: "еда", Allocation: 1000, Start: "2025-08-01"},
+		{Name: "transport", Allocation: 500, Start: "2025-08-01"},
+	} {
+		if _, err := s.Set(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	merged := map[string]string{"кафе": "dining", "еда": "groceries"}
+	n, err := s.Recategorize(func(c string) (string, bool) {
+		to, ok := merged[c]
+		return to, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Recategorize() changed %d envelopes, want 2", n)
+	}
+	want := map[string]string{"cafes": "dining", "еда": "groceries", "transport": ""}
+	for _, e := range s.List() {
+		if got := strings.Join(e.Categories, ","); got != want[e.Name] {
+			t.Errorf("%s categories = %q, want %q", e.Name, got, want[e.Name])
+		}
+	}
+}
+
+func TestAllocationChange(t *testing.T) {
+	t.Parallel()
+
+	s := newStore(t)
+	if _, err := s.Set(Envelope{Name: "groceries", Allocation: 6000, Start: "2025-07-01"}); err != nil {
+		t.Fatal(err)
+	}
+	// Raised in August, then corrected the same day
+	for _, amount := range []float64{8000, 7000} {
+		if _, err := s.Set(Envelope{Name: "groceries", Allocation: amount, Start: "2025-08-10"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		date            string
+		wantAllocated   float64
+		wantUnallocated float64
+	}{
+		{"2025-07-20", 6000, 4000},
+		{"2025-08-15", 6000 + 7000, 3000},
+		{"2025-09-01", 6000 + 7000 + 7000, 3000},
+	}
+	for _, store := range []*Store{s, mustReload(t, s)} {
+		for _, tt := range tests {
+			got := store.Summary(nil, monthly, mustDate(t, tt.date), 10000)
+			if b := got.Envelopes[0]; b.Allocated != tt.wantAllocated || b.Start != "2025-07-01" || got.Unallocated != tt.wantUnallocated {
+				t.Errorf("on %s: Allocated = %.2f, Start = %s, Unallocated = %.2f; want %.2f, 2025-07-01, %.2f", tt.date, b.Allocated, b.Start, got.Unallocated, tt.wantAllocated, tt.wantUnallocated)
+			}
+		}
+	}
+}
+
+func mustReload(t *testing.T, s *Store) *Store {
+	t.Helper()
+	reloaded, err := New(s.path, s.movesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reloaded
+}
+
+func TestLoadWithoutHistory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "envelopes.csv")
+	if err := os.WriteFile(path, []byte("Name,Allocation,Categories,Start\ncafes,2000.00,dining;coffee,2025-07-15\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(path, filepath.Join(dir, "moves.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.List(); len(got) != 1 || got[0].Allocation != 2000 || len(got[0].Categories) != 2 || got[0].History != nil {
+		t.Errorf("List() = %+v, want cafes with 2000 and two categories", got)
+	}
+}
+
+func TestCategoryResolver(t *testing.T) {
+	t.Parallel()
+
+	s := newStore(t)
+	aliases := map[string]string{"продукты": "groceries", "кафе": "dining"}
+	s.SetCategoryResolver(func(c string) string {
+		if to, ok := aliases[strings.ToLower(c)]; ok {
+			return to
+		}
+		return c
+	})
+	for _, e := range []Envelope{
+		// /envelopes set food 5000 продукты
+		{Name: "food", Allocation: 5000, Categories: []string{"Продукты"}, Start: "2025-08-01"},
+		// named after an alias, without categories
+		{Name: "кафе", Allocation: 2000, Start: "2025-08-01"},
+		// a more specific category than food's
+		{Name: "veg", Allocation: 1000, Categories: []string{"groceries/овощи"}, Start: "2025-08-01"},
+	} {
+		if _, err := s.Set(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string]string{"food": "groceries", "кафе": "dining", "veg": "groceries/овощи"}
+	for _, e := range s.List() {
+		if got := strings.Join(e.Categories, ","); got != want[e.Name] {
+			t.Errorf("%s categories = %q, want %q", e.Name, got, want[e.Name])
+		}
+	}
+
+	txs := []data.Transaction{
+		{Date: "2025-08-02", Category: "groceries", Amount: 1000},
+		{Date: "2025-08-03", Category: "groceries/молоко", Amount: 200},
+		{Date: "2025-08-04", Category: "groceries/овощи/томаты", Amount: 300},
+		{Date: "2025-08-05", Category: "dining/cafes", Amount: 400},
+		{Date: "2025-08-06", Category: "transport", Amount: 50},
+	}
+	got := s.Summary(txs, monthly, mustDate(t, "2025-08-10"), 10000)
+	spent := map[string]float64{}
+	for _, b := range got.Envelopes {
+		spent[b.Name] = b.Spent
+	}
+	if spent["food"] != 1200 || spent["veg"] != 300 || spent["кафе"] != 400 || got.Unassigned != 50 {
+		t.Errorf("spent = %v, unassigned %.2f; want food 1200, veg 300, кафе 400, unassigned 50", spent, got.Unassigned)
+	}
+}
@@ -0,0 +1,110 @@
+package fiscal
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // photos sent to the bot
+	_ "image/png"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// ErrNoCode is returned when no QR code can be read from an image.
+var ErrNoCode = errors.New("no QR code found")
+
+// Operation types of the n field; only purchases are expenses.
+const (
+	Purchase       = 1 // приход
+	PurchaseRefund = 2 // возврат прихода
+	Outgo          = 3 // расход
+	OutgoRefund    = 4 // возврат расхода
+)
+
+// timeLayouts are the forms of the t field, with and without seconds.
+var timeLayouts = []string{"20060102T150405", "20060102T1504"}
+
+// Receipt is what the QR code of a Russian fiscal receipt (54-ФЗ) encodes, e.g.
+// t=20250801T1530&s=1234.50&fn=7380440800000000&i=12345&fp=1234567890&n=1.
+type Receipt struct {
+	Time time.Time // wall clock of the store, without a time zone
+	Sum  float64   // total in RUB
+	FN   string    // fiscal drive number (ФН)
+	FD   string    // fiscal document number (ФД), the i field
+	FP   string    // fiscal sign (ФП)
+	Kind int       // operation type, see Purchase
+}
+
+// Parse reads the text of a receipt QR code.
+func Parse(text string) (Receipt, error) {
+	values, err := url.ParseQuery(strings.TrimSpace(text))
+	if err != nil {
+		return Receipt{}, fmt.Errorf("not a fiscal receipt code: %w", err)
+	}
+	r := Receipt{FN: values.Get("fn"), FD: values.Get("i"), FP: values.Get("fp"), Kind: Purchase}
+	if r.FN == "" || r.FD == "" || r.FP == "" {
+		return Receipt{}, errors.New("not a fiscal receipt code: fn, i and fp are required")
+	}
+	for _, layout := range timeLayouts {
+		if r.Time, err = time.Parse(layout, values.Get("t")); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return Receipt{}, fmt.Errorf("invalid receipt time %q", values.Get("t"))
+	}
+	if r.Sum, err = strconv.ParseFloat(values.Get("s"), 64); err != nil || r.Sum <= 0 {
+		return Receipt{}, fmt.Errorf("invalid receipt sum %q", values.Get("s"))
+	}
+	if n := values.Get("n"); n != "" {
+		if r.Kind, err = strconv.Atoi(n); err != nil || r.Kind < Purchase || r.Kind > OutgoRefund {
+			return Receipt{}, fmt.Errorf("invalid operation type %q", n)
+		}
+	}
+	return r, nil
+}
+
+// At returns the time of the receipt as the wall clock of loc, the zone the
+// store is assumed to be in.
+func (r Receipt) At(loc *time.Location) time.Time {
+	return time.Date(r.Time.Year(), r.Time.Month(), r.Time.Day(), r.Time.Hour(), r.Time.Minute(), r.Time.Second(), 0, loc)
+}
+
+// Key identifies the receipt: the fiscal drive, document number and sign
+// together are unique. It is stored with the transaction entered from it.
+func (r Receipt) Key() string {
+	return r.FN + "-" + r.FD + "-" + r.FP
+}
+
+// Decode reads a QR code from img.
+func Decode(img image.Image) (string, error) {
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", err
+	}
+	hints := map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_TRY_HARDER: true}
+	res, err := qrcode.NewQRCodeReader().Decode(bmp, hints)
+	if err != nil {
+		return "", ErrNoCode
+	}
+	return res.GetText(), nil
+}
+
+// Read decodes a JPEG or PNG photo of a receipt and parses its QR code.
+func Read(r io.Reader) (Receipt, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return Receipt{}, fmt.Errorf("not a JPEG or PNG image: %w", err)
+	}
+	text, err := Decode(img)
+	if err != nil {
+		return Receipt{}, err
+	}
+	return Parse(text)
+}
@@ -0,0 +1,89 @@
+package fiscal
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+const code = "t=20250801T1530&s=1234.50&fn=7380440800000000&i=12345&fp=1234567890&n=1"
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	r, err := Parse(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Receipt{Time: time.Date(2025, 8, 1, 15, 30, 0, 0, time.UTC), Sum: 1234.5, FN: "7380440800000000", FD: "12345", FP: "1234567890", Kind: Purchase}
+	if r != want {
+		t.Errorf("Parse() = %+v, want %+v", r, want)
+	}
+	if r.Key() != "7380440800000000-12345-1234567890" {
+		t.Errorf("Key() = %q", r.Key())
+	}
+
+	if r, err := Parse("t=20250801T153012&s=99&fn=1&i=2&fp=3"); err != nil || r.Time.Second() != 12 || r.Kind != Purchase {
+		t.Errorf("with seconds and without n = %+v, %v", r, err)
+	}
+	if r, err := Parse("t=20250801T1530&s=99&fn=1&i=2&fp=3&n=2"); err != nil || r.Kind != PurchaseRefund {
+		t.Errorf("refund = %+v, %v", r, err)
+	}
+
+	for _, bad := range []string{
+		"https://example.com",
+		"t=20250801T1530&s=99&fn=1&i=2",
+		"t=2025-08-01&s=99&fn=1&i=2&fp=3",
+		"t=20250801T1530&s=-5&fn=1&i=2&fp=3",
+		"t=20250801T1530&s=99&fn=1&i=2&fp=3&n=7",
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	matrix, err := qrcode.NewQRCodeWriter().Encode(code, gozxing.BarcodeFormat_QR_CODE, 200, 200, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A photo is larger than the code, with a margin around it
+	img := image.NewGray(image.Rect(0, 0, 320, 320))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for y := 0; y < matrix.GetHeight(); y++ {
+		for x := 0; x < matrix.GetWidth(); x++ {
+			if matrix.Get(x, y) {
+				img.SetGray(x+60, y+60, color.Gray{})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Sum != 1234.5 || r.FD != "12345" {
+		t.Errorf("Read() = %+v", r)
+	}
+
+	var blank bytes.Buffer
+	png.Encode(&blank, image.NewGray(image.Rect(0, 0, 100, 100)))
+	if _, err := Read(&blank); !errors.Is(err, ErrNoCode) {
+		t.Errorf("blank image: error = %v, want ErrNoCode", err)
+	}
+}
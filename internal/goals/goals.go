@@ -0,0 +1,383 @@
+package goals
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/budget"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	goalHeader         = []string{"ID", "Name", "Target", "Deadline", "Created"}
+	contributionHeader = []string{"Date", "GoalID", "Amount"}
+)
+
+// ErrNotFound is returned when a goal does not exist.
+var ErrNotFound = errors.New("goal not found")
+
+// Goal is a savings target, e.g. "vacation 60000 RUB by 2027-06-01".
+type Goal struct {
+	ID       int
+	Name     string
+	Target   float64
+	Deadline string // YYYY-MM-DD
+	Created  string // YYYY-MM-DD; surpluses of cycles finishing after it count towards the goal
+}
+
+// Contribution is money put aside for a goal by hand.
+type Contribution struct {
+	Date   string
+	GoalID int
+	Amount float64
+}
+
+// Surplus is money left over from a finished pay cycle, dated at the next cycle start.
+type Surplus struct {
+	Date   time.Time
+	Amount float64
+}
+
+// Progress is the state of a goal on a given day.
+type Progress struct {
+	Goal
+	Manual      float64 // manual contributions
+	FromSurplus float64 // share of cycle surpluses
+	Saved       float64
+	Remaining   float64
+	CyclesLeft  int       // pay cycles starting before the deadline, including the current one
+	PerCycle    float64   // contribution per cycle needed to reach the target in time
+	AvgPerCycle float64   // average contribution per cycle so far
+	Projected   time.Time // projected completion at the average pace; zero if there is no pace yet
+	Done        bool
+	OnTrack     bool
+}
+
+// CycleFunc returns the start of the pay cycle containing a date and the next cycle start.
+type CycleFunc func(time.Time) (time.Time, time.Time)
+
+// Store keeps goals and manual contributions in two CSV files.
+type Store struct {
+	mu            sync.Mutex
+	path          string
+	contribPath   string
+	goals         []Goal
+	contributions []Contribution
+}
+
+func New(path, contribPath string) (*Store, error) {
+	s := &Store{path: path, contribPath: contribPath}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func readCSV(path string, header []string) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	if strings.Join(records[0], ",") != strings.Join(header, ",") {
+		return nil, fmt.Errorf("%s header does not match expected format", path)
+	}
+	return records[1:], nil
+}
+
+func writeCSV(path string, header []string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+func (s *Store) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := readCSV(s.path, goalHeader)
+	if err != nil {
+		return err
+	}
+	for i, r := range records {
+		id, err := strconv.Atoi(r[0])
+		if err != nil {
+			return fmt.Errorf("invalid ID on line %d: %w", i+2, err)
+		}
+		target, err := strconv.ParseFloat(r[2], 64)
+		if err != nil {
+			return fmt.Errorf("invalid target on line %d: %w", i+2, err)
+		}
+		s.goals = append(s.goals, Goal{ID: id, Name: r[1], Target: target, Deadline: r[3], Created: r[4]})
+	}
+
+	records, err = readCSV(s.contribPath, contributionHeader)
+	if err != nil {
+		return err
+	}
+	for i, r := range records {
+		id, err := strconv.Atoi(r[1])
+		if err != nil {
+			return fmt.Errorf("invalid goal ID on line %d: %w", i+2, err)
+		}
+		amount, err := strconv.ParseFloat(r[2], 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount on line %d: %w", i+2, err)
+		}
+		s.contributions = append(s.contributions, Contribution{Date: r[0], GoalID: id, Amount: amount})
+	}
+	return nil
+}
+
+// save persists goals; callers must hold s.mu.
+func (s *Store) save() error {
+	rows := make([][]string, 0, len(s.goals))
+	for _, g := range s.goals {
+		rows = append(rows, []string{strconv.Itoa(g.ID), g.Name, strconv.FormatFloat(g.Target, 'f', 2, 64), g.Deadline, g.Created})
+	}
+	return writeCSV(s.path, goalHeader, rows)
+}
+
+// saveContributions persists contributions; callers must hold s.mu.
+func (s *Store) saveContributions() error {
+	rows := make([][]string, 0, len(s.contributions))
+	for _, c := range s.contributions {
+		rows = append(rows, []string{c.Date, strconv.Itoa(c.GoalID), strconv.FormatFloat(c.Amount, 'f', 2, 64)})
+	}
+	return writeCSV(s.contribPath, contributionHeader, rows)
+}
+
+// List returns all goals ordered by deadline.
+func (s *Store) List() []Goal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]Goal, len(s.goals))
+	copy(res, s.goals)
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Deadline != res[j].Deadline {
+			return res[i].Deadline < res[j].Deadline
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// Find returns a goal by ID or by case-insensitive name.
+func (s *Store) Find(ref string) (Goal, error) {
+	ref = strings.TrimPrefix(ref, "#")
+	id, _ := strconv.Atoi(ref)
+	for _, g := range s.List() {
+		if g.ID == id || strings.EqualFold(g.Name, ref) {
+			return g, nil
+		}
+	}
+	return Goal{}, ErrNotFound
+}
+
+// Add stores a new goal and returns it with its assigned ID.
+func (s *Store) Add(g Goal) (Goal, error) {
+	g.Name = strings.TrimSpace(g.Name)
+	if g.Name == "" {
+		return Goal{}, errors.New("name is required")
+	}
+	if g.Target <= 0 {
+		return Goal{}, errors.New("target must be positive")
+	}
+	deadline, err := time.Parse(dateLayout, g.Deadline)
+	if err != nil {
+		return Goal{}, fmt.Errorf("invalid deadline %q", g.Deadline)
+	}
+	created, err := time.Parse(dateLayout, g.Created)
+	if err != nil {
+		return Goal{}, fmt.Errorf("invalid creation date %q", g.Created)
+	}
+	if !deadline.After(created) {
+		return Goal{}, errors.New("deadline must be in the future")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g.ID = 1
+	for _, existing := range s.goals {
+		if existing.ID >= g.ID {
+			g.ID = existing.ID + 1
+		}
+	}
+	s.goals = append(s.goals, g)
+	return g, s.save()
+}
+
+// Delete removes a goal together with its manual contributions.
+func (s *Store) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.goals {
+		if s.goals[i].ID == id {
+			s.goals = append(s.goals[:i], s.goals[i+1:]...)
+			kept := s.contributions[:0]
+			for _, c := range s.contributions {
+				if c.GoalID != id {
+					kept = append(kept, c)
+				}
+			}
+			s.contributions = kept
+			if err := s.saveContributions(); err != nil {
+				return err
+			}
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// Contribute records a manual contribution; a negative amount withdraws money.
+func (s *Store) Contribute(c Contribution) error {
+	if c.Amount == 0 {
+		return errors.New("amount must not be zero")
+	}
+	if _, err := time.Parse(dateLayout, c.Date); err != nil {
+		return fmt.Errorf("invalid date %q", c.Date)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range s.goals {
+		if g.ID == c.GoalID {
+			s.contributions = append(s.contributions, c)
+			return s.saveContributions()
+		}
+	}
+	return ErrNotFound
+}
+
+// Progress computes the progress of every goal on today. Cycle surpluses are handed
+// to the goals existing at the time by deadline, each up to what it still misses;
+// pass no surpluses when they are carried over by the budget instead.
+func (s *Store) Progress(surpluses []Surplus, cycle CycleFunc, today time.Time) []Progress {
+	goals := s.List()
+	s.mu.Lock()
+	contributions := append([]Contribution(nil), s.contributions...)
+	s.mu.Unlock()
+
+	res := make([]Progress, len(goals))
+	byID := map[int]*Progress{}
+	todayStr := today.Format(dateLayout)
+	for i, g := range goals {
+		res[i] = Progress{Goal: g}
+		byID[g.ID] = &res[i]
+	}
+	for _, c := range contributions {
+		if p, ok := byID[c.GoalID]; ok && c.Date <= todayStr {
+			p.Manual += c.Amount
+		}
+	}
+
+	surpluses = append([]Surplus(nil), surpluses...)
+	sort.Slice(surpluses, func(i, j int) bool { return surpluses[i].Date.Before(surpluses[j].Date) })
+	for _, sp := range surpluses {
+		left := sp.Amount
+		dateStr := sp.Date.Format(dateLayout)
+		for i := range res {
+			p := &res[i]
+			// The surplus of a cycle belongs to goals that existed when it started.
+			if left <= 0 || dateStr > todayStr || p.Created > dateStr || p.Deadline < dateStr {
+				continue
+			}
+			take := min(left, max(p.Target-p.Manual-p.FromSurplus, 0))
+			p.FromSurplus += take
+			left -= take
+		}
+	}
+
+	for i := range res {
+		p := &res[i]
+		p.Saved = p.Manual + p.FromSurplus
+		p.Remaining = max(p.Target-p.Saved, 0)
+		p.Done = p.Remaining == 0
+		deadline, err := time.ParseInLocation(dateLayout, p.Deadline, today.Location())
+		if err != nil {
+			continue
+		}
+		created, _ := time.ParseInLocation(dateLayout, p.Created, today.Location())
+		p.CyclesLeft = cyclesBetween(cycle, today, deadline)
+		if p.CyclesLeft > 0 {
+			p.PerCycle = p.Remaining / float64(p.CyclesLeft)
+		} else {
+			p.PerCycle = p.Remaining
+		}
+		if elapsed := cyclesBetween(cycle, created, today); elapsed > 0 {
+			p.AvgPerCycle = p.Saved / float64(elapsed)
+		}
+		switch {
+		case p.Done:
+			p.Projected = today
+		case p.AvgPerCycle > 0:
+			p.Projected = advance(cycle, today, int(math.Ceil(p.Remaining/p.AvgPerCycle)))
+		}
+		p.OnTrack = p.Done || (!p.Projected.IsZero() && !p.Projected.After(deadline))
+	}
+	return res
+}
+
+// cyclesBetween counts the cycles starting from the one containing from up to the last
+// one starting before to.
+func cyclesBetween(cycle CycleFunc, from, to time.Time) int {
+	n := 0
+	for start, next := cycle(from); start.Before(to) && next.After(start) && n < 1200; start, next = cycle(next) {
+		n++
+	}
+	return n
+}
+
+// advance returns the start of the n-th cycle after the one containing from.
+func advance(cycle CycleFunc, from time.Time, n int) time.Time {
+	_, next := cycle(from)
+	for i := 1; i < min(n, 1200); i++ {
+		_, next = cycle(next)
+	}
+	return next
+}
+
+// SurplusFrom returns what finished cycles left over and did not carry into the next
+// cycle: the whole positive result without rollover, the part above the cap with a
+// capped rollover, nothing with a full rollover.
+func SurplusFrom(results []budget.CycleResult) []Surplus {
+	var res []Surplus
+	for _, r := range results {
+		if left := r.Result - r.CarryOut; left > 0 {
+			res = append(res, Surplus{Date: r.Next, Amount: left})
+		}
+	}
+	return res
+}
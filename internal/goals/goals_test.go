@@ -0,0 +1,124 @@
+package goals
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// monthly cycles starting on the 1st
+func monthly(d time.Time) (time.Time, time.Time) {
+	start := time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, d.Location())
+	return start, start.AddDate(0, 1, 0)
+}
+
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(dateLayout, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	s, err := New(filepath.Join(dir, "goals.csv"), filepath.Join(dir, "contributions.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range []Goal{
+		{Name: "vacation", Target: 10000, Deadline: "2025-12-01", Created: "2025-06-10"},
+		{Name: "laptop", Target: 5000, Deadline: "2026-06-01", Created: "2025-07-20"},
+		{Name: "gift", Target: 1000, Deadline: "2025-09-01", Created: "2025-08-10"},
+	} {
+		if _, err := s.Add(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []Contribution{
+		{Date: "2025-06-20", GoalID: 1, Amount: 1000},
+		{Date: "2025-08-10", GoalID: 2, Amount: 500},
+		{Date: "2025-09-01", GoalID: 2, Amount: 999}, // after today
+	} {
+		if err := s.Contribute(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	surpluses := []Surplus{
+		{Date: mustDate(t, "2025-08-01"), Amount: 8000},
+		{Date: mustDate(t, "2025-07-01"), Amount: 3000},
+	}
+
+	// Reload to make sure everything round-trips through the CSV files.
+	s, err = New(s.path, s.contribPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.Progress(surpluses, monthly, mustDate(t, "2025-08-15"))
+
+	tests := []struct {
+		name          string
+		wantSurplus   float64
+		wantSaved     float64
+		wantPerCycle  float64
+		wantCycles    int
+		wantProjected string
+		wantOnTrack   bool
+	}{
+		// June surplus 3000, then 6000 of the July surplus fill the target.
+		{"vacation", 9000, 10000, 0, 4, "2025-08-15", true},
+		// Gets the rest of the July surplus; 2500 left over 10 cycles, 1250 per cycle so far.
+		{"laptop", 2000, 2500, 250, 10, "2025-10-01", true},
+		// Created after the last surplus: no pace, no projection.
+		{"gift", 0, 0, 1000, 1, "0001-01-01", false},
+	}
+	byName := map[string]Progress{}
+	for _, p := range got {
+		byName[p.Name] = p
+	}
+	for _, tt := range tests {
+		p := byName[tt.name]
+		if math.Abs(p.FromSurplus-tt.wantSurplus) > 0.001 || math.Abs(p.Saved-tt.wantSaved) > 0.001 || math.Abs(p.PerCycle-tt.wantPerCycle) > 0.001 {
+			t.Errorf("%s: surplus %.2f saved %.2f per cycle %.2f, want %.2f %.2f %.2f", tt.name, p.FromSurplus, p.Saved, p.PerCycle, tt.wantSurplus, tt.wantSaved, tt.wantPerCycle)
+		}
+		if p.CyclesLeft != tt.wantCycles {
+			t.Errorf("%s: cycles left = %d, want %d", tt.name, p.CyclesLeft, tt.wantCycles)
+		}
+		if p.Projected.Format(dateLayout) != tt.wantProjected || p.OnTrack != tt.wantOnTrack {
+			t.Errorf("%s: projected %s on track %v, want %s %v", tt.name, p.Projected.Format(dateLayout), p.OnTrack, tt.wantProjected, tt.wantOnTrack)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(filepath.Join(t.TempDir(), "goals.csv"), filepath.Join(t.TempDir(), "contributions.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		goal    Goal
+		wantErr bool
+	}{
+		{name: "valid", goal: Goal{Name: "vacation", Target: 60000, Deadline: "2027-06-01", Created: "2025-08-01"}},
+		{name: "no name", goal: Goal{Target: 100, Deadline: "2027-06-01", Created: "2025-08-01"}, wantErr: true},
+		{name: "no target", goal: Goal{Name: "x", Deadline: "2027-06-01", Created: "2025-08-01"}, wantErr: true},
+		{name: "deadline in the past", goal: Goal{Name: "x", Target: 100, Deadline: "2025-07-01", Created: "2025-08-01"}, wantErr: true},
+		{name: "bad deadline", goal: Goal{Name: "x", Target: 100, Deadline: "June", Created: "2025-08-01"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Add(tt.goal); (err != nil) != tt.wantErr {
+				t.Errorf("Add() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
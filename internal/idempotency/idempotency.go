@@ -0,0 +1,150 @@
+package idempotency
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Window is how long a key is remembered. Clients retry within seconds; a day
+// also covers a Mini App that is reopened with a stale form.
+const Window = 24 * time.Hour
+
+// MaxKeyLength bounds client-generated keys; a UUID is 36 characters.
+const MaxKeyLength = 128
+
+var header = []string{"Key", "Created", "Result"}
+
+// ErrInvalidKey is returned for an empty or overlong key.
+var ErrInvalidKey = errors.New("idempotency key must be 1 to 128 characters")
+
+type entry struct {
+	created time.Time
+	result  string
+}
+
+// call is a submission in progress; duplicates wait for it to finish.
+type call struct {
+	done   chan struct{}
+	result string
+	err    error
+}
+
+// Store remembers the result of each submission by its client-generated key, so
+// a retried or duplicated submission returns the original result instead of
+// being applied again. Keys survive restarts in a CSV file next to the ledger.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	entries  map[string]entry
+	inflight map[string]*call
+}
+
+func New(path string) (*Store, error) {
+	s := &Store{path: path, entries: map[string]entry{}, inflight: map[string]*call{}}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if strings.Join(records[0], ",") != strings.Join(header, ",") {
+		return errors.New("idempotency CSV header does not match expected format")
+	}
+	for i, r := range records[1:] {
+		created, err := time.Parse(time.RFC3339, r[1])
+		if err != nil {
+			return fmt.Errorf("invalid time on line %d: %w", i+2, err)
+		}
+		s.entries[r[0]] = entry{created: created, result: r[2]}
+	}
+	return nil
+}
+
+// save drops expired keys and persists the rest; callers must hold s.mu.
+func (s *Store) save(now time.Time) error {
+	for key, e := range s.entries {
+		if now.Sub(e.created) > Window {
+			delete(s.entries, key)
+		}
+	}
+
+	file, err := os.Create(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	for key, e := range s.entries {
+		if err := writer.Write([]string{key, e.created.UTC().Format(time.RFC3339), e.result}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Do runs fn once per key within Window and remembers its result. A repeated key
+// returns the remembered result with replayed set; a concurrent duplicate waits
+// for the first submission. Failures are not remembered, so they can be retried.
+func (s *Store) Do(key string, now time.Time, fn func() (string, error)) (result string, replayed bool, err error) {
+	if key == "" || len(key) > MaxKeyLength {
+		return "", false, ErrInvalidKey
+	}
+
+	s.mu.Lock()
+	if e, ok := s.entries[key]; ok && now.Sub(e.created) <= Window {
+		s.mu.Unlock()
+		return e.result, true, nil
+	}
+	if c, ok := s.inflight[key]; ok {
+		s.mu.Unlock()
+		<-c.done
+		return c.result, c.err == nil, c.err
+	}
+	c := &call{done: make(chan struct{})}
+	s.inflight[key] = c
+	s.mu.Unlock()
+
+	c.result, c.err = fn()
+
+	s.mu.Lock()
+	delete(s.inflight, key)
+	if c.err == nil {
+		s.entries[key] = entry{created: now, result: c.result}
+		if err := s.save(now); err != nil {
+			// The submission itself succeeded; only its replay after a restart is lost.
+			log.Printf("Failed to save idempotency keys: %v", err)
+		}
+	}
+	s.mu.Unlock()
+	close(c.done)
+	return c.result, false, c.err
+}
@@ -0,0 +1,69 @@
+package idempotency
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "keys.csv")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	var runs int32
+	submit := func() (string, error) {
+		n := atomic.AddInt32(&runs, 1)
+		return "tx" + strconv.Itoa(int(n)), nil
+	}
+
+	// Concurrent duplicates, e.g. the HTTP request and tg.sendData, apply once.
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _, _ = s.Do("k1", now, submit)
+		}()
+	}
+	wg.Wait()
+	for _, r := range results {
+		if r != "tx1" {
+			t.Fatalf("results = %v, want the first result everywhere", results)
+		}
+	}
+
+	// Remembered across a restart, within the window.
+	s, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, replayed, err := s.Do("k1", now.Add(time.Hour), submit); err != nil || !replayed || r != "tx1" {
+		t.Errorf("Do() after restart = %q, %v, %v; want the replayed tx1", r, replayed, err)
+	}
+	if r, replayed, _ := s.Do("k1", now.Add(Window+time.Minute), submit); replayed || r != "tx2" {
+		t.Errorf("Do() after the window = %q, %v; want a new run", r, replayed)
+	}
+
+	// Failures are not remembered.
+	fail := errors.New("disk full")
+	if _, _, err := s.Do("k2", now, func() (string, error) { return "", fail }); !errors.Is(err, fail) {
+		t.Fatalf("Do() = %v, want the failure", err)
+	}
+	if r, replayed, _ := s.Do("k2", now, submit); replayed || r != "tx3" {
+		t.Errorf("retry after a failure = %q, %v; want a new run", r, replayed)
+	}
+
+	if _, _, err := s.Do("", now, submit); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Do() with an empty key = %v, want ErrInvalidKey", err)
+	}
+}
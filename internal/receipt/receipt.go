@@ -0,0 +1,343 @@
+package receipt
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// MaxSize bounds a receipt file; Telegram lets bots download files up to 20 MB.
+const MaxSize = 20 << 20
+
+// IndexFile is the name of the index in the receipts directory.
+const IndexFile = "receipts.csv"
+
+var header = []string{"Hash", "Transaction", "Name", "ContentType", "Size", "Added"}
+
+var (
+	// ErrNotFound is returned when a transaction has no receipt with the given hash.
+	ErrNotFound = errors.New("receipt not found")
+	// ErrTooLarge is returned for files over MaxSize.
+	ErrTooLarge = errors.New("receipt file is larger than 20 MB")
+	// ErrUnsupported is returned for files that are neither images nor PDFs.
+	ErrUnsupported = errors.New("receipt must be a JPEG, PNG, WebP or HEIC photo or a PDF")
+)
+
+// contentTypes are the accepted file types and the extension offered on download.
+var contentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"image/heic":      ".heic",
+	"application/pdf": ".pdf",
+}
+
+// Receipt is a photo or PDF attached to a transaction. The same file attached
+// twice is stored once.
+type Receipt struct {
+	Hash          string // SHA-256 of the content, also its file name
+	TransactionID string
+	Name          string // file name as uploaded
+	ContentType   string
+	Size          int64
+	Added         string // YYYY-MM-DD
+}
+
+// FileName returns the name to offer on download, with an extension matching
+// the content type.
+func (r Receipt) FileName() string {
+	ext := contentTypes[r.ContentType]
+	name := r.Name
+	if name == "" {
+		name = "receipt"
+	}
+	if ext != "" && !strings.EqualFold(filepath.Ext(name), ext) {
+		name += ext
+	}
+	return name
+}
+
+// Store keeps receipt files in a content-addressed directory next to the ledger:
+// each file is named after the SHA-256 of its content, in a subdirectory named
+// after the first two hex digits. An index CSV in the same directory links the
+// files to transactions by ID, so the directory alone is a complete backup.
+type Store struct {
+	mu       sync.Mutex
+	dir      string
+	receipts []Receipt
+}
+
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Dir returns the receipts directory.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+func (s *Store) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(filepath.Join(s.dir, IndexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if strings.Join(records[0], ",") != strings.Join(header, ",") {
+		return errors.New("receipts CSV header does not match expected format")
+	}
+	for i, r := range records[1:] {
+		size, err := strconv.ParseInt(r[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid size on line %d: %w", i+2, err)
+		}
+		s.receipts = append(s.receipts, Receipt{Hash: r[0], TransactionID: r[1], Name: r[2], ContentType: r[3], Size: size, Added: r[5]})
+	}
+	return nil
+}
+
+// save persists the index; callers must hold s.mu.
+func (s *Store) save() error {
+	path := filepath.Join(s.dir, IndexFile)
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	for _, r := range s.receipts {
+		writer.Write([]string{r.Hash, r.TransactionID, r.Name, r.ContentType, strconv.FormatInt(r.Size, 10), r.Added})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Path returns the file of r.
+func (s *Store) Path(r Receipt) string {
+	return filepath.Join(s.dir, r.Hash[:2], r.Hash)
+}
+
+// Attach stores the file read from r and links it to the transaction. The
+// content type is sniffed; contentType, e.g. from an upload, is used when the
+// content does not tell (HEIC). Attaching the same file to the same transaction
+// again returns the existing receipt.
+func (s *Store) Attach(txID, name, contentType string, r io.Reader, now time.Time) (Receipt, error) {
+	tmp, err := os.CreateTemp(s.dir, "upload-*")
+	if err != nil {
+		return Receipt{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return Receipt{}, err
+	}
+	if size > MaxSize {
+		return Receipt{}, ErrTooLarge
+	}
+	head := make([]byte, 512)
+	n, _ := tmp.ReadAt(head, 0)
+	if contentType, err = detect(head[:n], contentType); err != nil {
+		return Receipt{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return Receipt{}, err
+	}
+
+	rec := Receipt{
+		Hash:          hex.EncodeToString(h.Sum(nil)),
+		TransactionID: txID,
+		Name:          filepath.Base(strings.TrimSpace(name)),
+		ContentType:   contentType,
+		Size:          size,
+		Added:         now.Format(dateLayout),
+	}
+	if rec.Name == "." || rec.Name == string(filepath.Separator) {
+		rec.Name = ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.receipts {
+		if existing.TransactionID == txID && existing.Hash == rec.Hash {
+			return existing, nil
+		}
+	}
+	path := s.Path(rec)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return Receipt{}, err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return Receipt{}, err
+		}
+	}
+	s.receipts = append(s.receipts, rec)
+	return rec, s.save()
+}
+
+// detect returns the content type of a file starting with head, falling back to
+// declared when sniffing is inconclusive.
+func detect(head []byte, declared string) (string, error) {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if _, ok := contentTypes[sniffed]; ok {
+		return sniffed, nil
+	}
+	declared, _, _ = mime.ParseMediaType(declared)
+	if _, ok := contentTypes[declared]; ok && sniffed == "application/octet-stream" {
+		return declared, nil
+	}
+	return "", ErrUnsupported
+}
+
+// List returns the receipts of a transaction in the order they were attached.
+func (s *Store) List(txID string) []Receipt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []Receipt
+	for _, r := range s.receipts {
+		if r.TransactionID == txID {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// All returns every receipt in the order they were attached.
+func (s *Store) All() []Receipt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]Receipt, len(s.receipts))
+	copy(res, s.receipts)
+	return res
+}
+
+// Counts returns the number of receipts of every transaction that has any.
+func (s *Store) Counts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := map[string]int{}
+	for _, r := range s.receipts {
+		counts[r.TransactionID]++
+	}
+	return counts
+}
+
+// Get returns the receipt of a transaction with the given hash.
+func (s *Store) Get(txID, hash string) (Receipt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range s.receipts {
+		if r.TransactionID == txID && r.Hash == hash {
+			return r, nil
+		}
+	}
+	return Receipt{}, ErrNotFound
+}
+
+// Detach unlinks a receipt from a transaction. The file is removed when no other
+// transaction uses it.
+func (s *Store) Detach(txID, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.receipts {
+		if r.TransactionID == txID && r.Hash == hash {
+			s.receipts = append(s.receipts[:i], s.receipts[i+1:]...)
+			if err := s.save(); err != nil {
+				return err
+			}
+			return s.removeUnused(r)
+		}
+	}
+	return ErrNotFound
+}
+
+// DetachAll unlinks every receipt of a transaction, e.g. when it is deleted.
+func (s *Store) DetachAll(txID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var kept, removed []Receipt
+	for _, r := range s.receipts {
+		if r.TransactionID == txID {
+			removed = append(removed, r)
+		} else {
+			kept = append(kept, r)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	s.receipts = kept
+	if err := s.save(); err != nil {
+		return err
+	}
+	for _, r := range removed {
+		if err := s.removeUnused(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeUnused deletes the file of r unless another receipt has the same
+// content; callers must hold s.mu.
+func (s *Store) removeUnused(r Receipt) error {
+	for _, other := range s.receipts {
+		if other.Hash == r.Hash {
+			return nil
+		}
+	}
+	if err := os.Remove(s.Path(r)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package receipt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	day = time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	pdf = []byte("%PDF-1.7\n%receipt")
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	s, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	photo, err := s.Attach("tx1", "IMG_1.png", "", bytes.NewReader(png), day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if photo.ContentType != "image/png" || photo.Size != int64(len(png)) || photo.Added != "2025-08-01" {
+		t.Errorf("Attach() = %+v", photo)
+	}
+	if b, err := os.ReadFile(s.Path(photo)); err != nil || !bytes.Equal(b, png) {
+		t.Fatalf("stored file = %q, %v", b, err)
+	}
+	if again, err := s.Attach("tx1", "copy.png", "", bytes.NewReader(png), day); err != nil || again != photo {
+		t.Errorf("attaching the same file again = %+v, %v; want the existing receipt", again, err)
+	}
+	// The same content on another transaction shares the file
+	shared, err := s.Attach("tx2", "", "", bytes.NewReader(png), day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Attach("tx1", "check.pdf", "application/pdf", bytes.NewReader(pdf), day); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Counts(); got["tx1"] != 2 || got["tx2"] != 1 {
+		t.Errorf("Counts() = %v", got)
+	}
+
+	// The index survives a restart
+	reloaded, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.List("tx1"); len(got) != 2 || got[0] != photo || got[1].Name != "check.pdf" {
+		t.Errorf("List() after reload = %+v", got)
+	}
+
+	if err := s.Detach("tx1", photo.Hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.Path(photo)); err != nil {
+		t.Errorf("file still used by tx2 was removed: %v", err)
+	}
+	if err := s.DetachAll("tx2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.Path(shared)); !os.IsNotExist(err) {
+		t.Errorf("unused file was kept: %v", err)
+	}
+	if _, err := s.Get("tx2", shared.Hash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after DetachAll = %v, want ErrNotFound", err)
+	}
+	if err := s.Detach("tx2", shared.Hash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Detach() of a missing receipt = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAttachRejects(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Attach("tx1", "notes.txt", "text/plain", strings.NewReader("milk, bread"), day); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("text file: error = %v, want ErrUnsupported", err)
+	}
+	// Declared types only count when the content does not tell
+	if _, err := s.Attach("tx1", "fake.pdf", "application/pdf", strings.NewReader("<html>"), day); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("HTML declared as PDF: error = %v, want ErrUnsupported", err)
+	}
+	heic := append([]byte("\x00\x00\x00\x18ftypheic"), make([]byte, 16)...)
+	if r, err := s.Attach("tx1", "IMG_2.HEIC", "image/heic", bytes.NewReader(heic), day); err != nil || r.ContentType != "image/heic" {
+		t.Errorf("HEIC photo = %+v, %v", r, err)
+	}
+	if _, err := s.Attach("tx1", "huge.pdf", "", bytes.NewReader(append(pdf, make([]byte, MaxSize)...)), day); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("large file: error = %v, want ErrTooLarge", err)
+	}
+	if entries, _ := os.ReadDir(s.Dir()); len(entries) != 2 {
+		t.Errorf("receipts dir holds %d entries, want the index and one subdirectory without leftover uploads", len(entries))
+	}
+}
+
+func TestFileName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		r    Receipt
+		want string
+	}{
+		{Receipt{Name: "IMG_1.jpg", ContentType: "image/jpeg"}, "IMG_1.jpg"},
+		{Receipt{Name: "scan", ContentType: "application/pdf"}, "scan.pdf"},
+		{Receipt{ContentType: "image/png"}, "receipt.png"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.FileName(); got != tt.want {
+			t.Errorf("FileName() of %+v = %q, want %q", tt.r, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package recurring
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+const (
+	minDetectCharges   = 3    // charges needed before a merchant is considered recurring
+	maxDetectCharges   = 6    // most recent charges taken into account
+	amountTolerance    = 0.15 // relative spread allowed between regular charges
+	priceUpThreshold   = 0.02 // relative increase of the latest charge reported as a price rise
+	minDetectInterval  = 5    // shorter intervals are everyday shopping, not subscriptions
+	maxDetectInterval  = 62
+	averageMonthLength = 30.44
+)
+
+// Suspect is a merchant that looks like a subscription or another regular payment.
+type Suspect struct {
+	Key            string // stable identifier derived from merchant and category
+	Merchant       string // merchant key, see data.MerchantKey
+	Description    string // description of the latest charge
+	Category       string
+	Amount         float64 // latest charge
+	PreviousAmount float64
+	Interval       int // typical number of days between charges
+	Schedule       Schedule
+	MonthlyCost    float64
+	Count          int
+	LastDate       time.Time
+	NextExpected   time.Time
+	Missing        bool // the expected charge is overdue
+	PriceUp        bool // the latest charge is more expensive than the previous one
+}
+
+// Detect scans the ledger for payments to the same merchant at a similar amount on a
+// regular interval. Results are sorted by monthly cost, most expensive first.
+func Detect(txs []data.Transaction, today time.Time) []Suspect {
+	type charge struct {
+		date        time.Time
+		amount      float64
+		description string
+		category    string
+	}
+	groups := map[string]map[string]*charge{}
+	for _, tx := range txs {
+		d, err := time.Parse(dateLayout, tx.Date)
+		if err != nil || tx.Amount <= 0 {
+			continue
+		}
+		key := tx.Merchant() + "|" + strings.ToLower(tx.Category)
+		if groups[key] == nil {
+			groups[key] = map[string]*charge{}
+		}
+		// Several charges on the same day are folded into one.
+		if c, ok := groups[key][tx.Date]; ok {
+			c.amount += tx.Amount
+			continue
+		}
+		groups[key][tx.Date] = &charge{date: d, amount: tx.Amount, description: tx.Description, category: tx.Category}
+	}
+
+	today = day(today)
+	var res []Suspect
+	for key, byDate := range groups {
+		if len(byDate) < minDetectCharges {
+			continue
+		}
+		charges := make([]*charge, 0, len(byDate))
+		for _, c := range byDate {
+			charges = append(charges, c)
+		}
+		sort.Slice(charges, func(i, j int) bool { return charges[i].date.Before(charges[j].date) })
+		if len(charges) > maxDetectCharges {
+			charges = charges[len(charges)-maxDetectCharges:]
+		}
+
+		intervals := make([]float64, 0, len(charges)-1)
+		for i := 1; i < len(charges); i++ {
+			intervals = append(intervals, charges[i].date.Sub(charges[i-1].date).Hours()/24)
+		}
+		interval := median(intervals)
+		if interval < minDetectInterval || interval > maxDetectInterval {
+			continue
+		}
+		slack := math.Max(3, interval*amountTolerance)
+		regular := true
+		for _, iv := range intervals {
+			if math.Abs(iv-interval) > slack {
+				regular = false
+				break
+			}
+		}
+		if !regular {
+			continue
+		}
+
+		// All charges but the latest must agree on the price; the latest may differ
+		// when the price changes, which is reported rather than hidden.
+		amounts := make([]float64, 0, len(charges)-1)
+		for _, c := range charges[:len(charges)-1] {
+			amounts = append(amounts, c.amount)
+		}
+		typical := median(amounts)
+		for _, a := range amounts {
+			if math.Abs(a-typical) > typical*amountTolerance {
+				regular = false
+				break
+			}
+		}
+		last := charges[len(charges)-1]
+		if !regular || math.Abs(last.amount-typical) > typical*0.5 {
+			continue
+		}
+
+		s := Suspect{
+			Key:            shortKey(key),
+			Merchant:       strings.SplitN(key, "|", 2)[0],
+			Description:    last.description,
+			Category:       last.category,
+			Amount:         last.amount,
+			PreviousAmount: charges[len(charges)-2].amount,
+			Interval:       int(math.Round(interval)),
+			Count:          len(charges),
+			LastDate:       last.date,
+		}
+		switch {
+		case interval >= 27 && interval <= 33:
+			s.Schedule = Schedule{Kind: Monthly, Day: last.date.Day()}
+			s.NextExpected = addMonthClamped(last.date, last.date.Day())
+			s.MonthlyCost = s.Amount
+		case interval >= 6 && interval <= 8:
+			s.Schedule = Schedule{Kind: Weekly, Weekday: last.date.Weekday()}
+			s.NextExpected = last.date.AddDate(0, 0, 7)
+			s.MonthlyCost = s.Amount * averageMonthLength / 7
+		default:
+			s.Schedule = Schedule{Kind: Every, Interval: s.Interval}
+			s.NextExpected = last.date.AddDate(0, 0, s.Interval)
+			s.MonthlyCost = s.Amount * averageMonthLength / float64(s.Interval)
+		}
+		grace := int(math.Max(3, interval/10))
+		s.Missing = today.After(s.NextExpected.AddDate(0, 0, grace))
+		s.PriceUp = s.Amount > s.PreviousAmount*(1+priceUpThreshold)
+		res = append(res, s)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].MonthlyCost != res[j].MonthlyCost {
+			return res[i].MonthlyCost > res[j].MonthlyCost
+		}
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
+
+// Template converts a suspect into a recurring template that starts right after
+// the latest observed charge, so the charge already in the ledger is not repeated.
+func (s Suspect) Template() Template {
+	return Template{
+		Schedule:    s.Schedule,
+		Start:       s.LastDate.AddDate(0, 0, 1).Format(dateLayout),
+		Category:    s.Category,
+		Description: s.Description,
+		Amount:      s.Amount,
+	}
+}
+
+// Tracks reports whether template t already covers the suspected subscription.
+func (s Suspect) Tracks(t Template) bool {
+	return strings.EqualFold(t.Category, s.Category) &&
+		(data.MerchantKey(t.Description) == s.Merchant || (t.Description == "" && s.Merchant == strings.ToLower(s.Category)))
+}
+
+// addMonthClamped returns the given day of the month following d, clamped to the month length.
+func addMonthClamped(d time.Time, dayOfMonth int) time.Time {
+	first := time.Date(d.Year(), d.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(dayOfMonth, last)-1)
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func shortKey(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:4])
+}
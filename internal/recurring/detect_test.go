@@ -0,0 +1,127 @@
+package recurring
+
+import (
+	"testing"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	tx := func(date, category, description string, amount float64) data.Transaction {
+		return data.Transaction{Date: date, Category: category, Description: description, Amount: amount}
+	}
+	streaming := []data.Transaction{
+		tx("2025-05-03", "entertainment", "KION 001", 299),
+		tx("2025-06-03", "entertainment", "KION 002", 299),
+		tx("2025-07-03", "entertainment", "KION 003", 299),
+	}
+
+	tests := []struct {
+		name        string
+		txs         []data.Transaction
+		today       string
+		wantCount   int
+		wantSched   string
+		wantNext    string
+		wantMissing bool
+		wantPriceUp bool
+	}{
+		{
+			name:      "monthly subscription",
+			txs:       streaming,
+			today:     "2025-07-10",
+			wantCount: 1,
+			wantSched: "monthly:3",
+			wantNext:  "2025-08-03",
+		},
+		{
+			name:        "missing charge",
+			txs:         streaming,
+			today:       "2025-08-10",
+			wantCount:   1,
+			wantSched:   "monthly:3",
+			wantNext:    "2025-08-03",
+			wantMissing: true,
+		},
+		{
+			name:        "price increase",
+			txs:         append(append([]data.Transaction(nil), streaming...), tx("2025-08-03", "entertainment", "KION", 349)),
+			today:       "2025-08-04",
+			wantCount:   1,
+			wantSched:   "monthly:3",
+			wantNext:    "2025-09-03",
+			wantPriceUp: true,
+		},
+		{
+			name: "weekly",
+			txs: []data.Transaction{
+				tx("2025-08-01", "other", "Cleaning", 1500),
+				tx("2025-08-08", "other", "Cleaning", 1500),
+				tx("2025-08-15", "other", "Cleaning", 1500),
+			},
+			today:     "2025-08-16",
+			wantCount: 1,
+			wantSched: "weekly:fri",
+			wantNext:  "2025-08-22",
+		},
+		{
+			name: "irregular intervals are ignored",
+			txs: []data.Transaction{
+				tx("2025-08-01", "groceries", "PYATEROCHKA 20572", 500),
+				tx("2025-08-09", "groceries", "PYATEROCHKA 432", 500),
+				tx("2025-08-30", "groceries", "PYATEROCHKA 20572", 500),
+			},
+			today: "2025-08-30",
+		},
+		{
+			name: "different amounts are ignored",
+			txs: []data.Transaction{
+				tx("2025-06-01", "dining", "COUS-COUS", 200),
+				tx("2025-07-01", "dining", "COUS-COUS", 900),
+				tx("2025-08-01", "dining", "COUS-COUS", 450),
+			},
+			today: "2025-08-02",
+		},
+		{
+			name: "everyday spending is ignored",
+			txs: []data.Transaction{
+				tx("2025-08-01", "transport", "metro", 70),
+				tx("2025-08-02", "transport", "metro", 70),
+				tx("2025-08-03", "transport", "metro", 70),
+				tx("2025-08-04", "transport", "metro", 70),
+			},
+			today: "2025-08-05",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := Detect(tt.txs, mustDate(t, tt.today))
+			if len(got) != tt.wantCount {
+				t.Fatalf("Detect() returned %d suspects, want %d: %+v", len(got), tt.wantCount, got)
+			}
+			if tt.wantCount == 0 {
+				return
+			}
+			s := got[0]
+			if s.Schedule.String() != tt.wantSched {
+				t.Errorf("schedule = %s, want %s", s.Schedule, tt.wantSched)
+			}
+			if s.NextExpected.Format(dateLayout) != tt.wantNext {
+				t.Errorf("next expected = %s, want %s", s.NextExpected.Format(dateLayout), tt.wantNext)
+			}
+			if s.Missing != tt.wantMissing {
+				t.Errorf("missing = %v, want %v", s.Missing, tt.wantMissing)
+			}
+			if s.PriceUp != tt.wantPriceUp {
+				t.Errorf("price up = %v, want %v", s.PriceUp, tt.wantPriceUp)
+			}
+			if tmpl := s.Template(); tmpl.Start <= s.LastDate.Format(dateLayout) {
+				t.Errorf("template start %s must be after the last charge %s", tmpl.Start, s.LastDate.Format(dateLayout))
+			}
+		})
+	}
+}
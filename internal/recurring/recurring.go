@@ -0,0 +1,479 @@
+package recurring
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+const dateLayout = "2006-01-02"
+
+var header = []string{"ID", "Schedule", "Start", "Category", "Description", "Amount", "LastRun", "Paused"}
+
+// ErrNotFound is returned when a template with the given ID does not exist.
+var ErrNotFound = errors.New("recurring template not found")
+
+// ErrChanged is returned by a conditional write when the stored template no
+// longer matches the one the caller saw.
+var ErrChanged = errors.New("recurring template has changed")
+
+// Kind is the type of a recurrence schedule.
+type Kind string
+
+const (
+	Monthly Kind = "monthly" // monthly on a given day of month
+	Weekly  Kind = "weekly"  // weekly on a given weekday
+	Every   Kind = "every"   // every N days counted from the template start
+)
+
+// Schedule describes when a template produces a charge.
+type Schedule struct {
+	Kind     Kind
+	Day      int          // Monthly: 1..31, clamped to the last day of shorter months
+	Weekday  time.Weekday // Weekly
+	Interval int          // Every: number of days between charges
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseSchedule parses "monthly:15", "weekly:mon" or "every:14".
+func ParseSchedule(s string) (Schedule, error) {
+	kind, arg, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), ":")
+	if !ok || arg == "" {
+		return Schedule{}, fmt.Errorf("invalid schedule %q: expected monthly:N, weekly:DAY or every:N", s)
+	}
+	switch Kind(kind) {
+	case Monthly:
+		day, err := strconv.Atoi(arg)
+		if err != nil || day < 1 || day > 31 {
+			return Schedule{}, fmt.Errorf("invalid schedule %q: day of month must be 1..31", s)
+		}
+		return Schedule{Kind: Monthly, Day: day}, nil
+	case Weekly:
+		wd, ok := weekdays[arg[:min(3, len(arg))]]
+		if !ok {
+			return Schedule{}, fmt.Errorf("invalid schedule %q: unknown weekday", s)
+		}
+		return Schedule{Kind: Weekly, Weekday: wd}, nil
+	case Every:
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			return Schedule{}, fmt.Errorf("invalid schedule %q: interval must be a positive number of days", s)
+		}
+		return Schedule{Kind: Every, Interval: n}, nil
+	}
+	return Schedule{}, fmt.Errorf("invalid schedule %q: unknown kind %q", s, kind)
+}
+
+func (s Schedule) String() string {
+	switch s.Kind {
+	case Monthly:
+		return fmt.Sprintf("monthly:%d", s.Day)
+	case Weekly:
+		return "weekly:" + strings.ToLower(s.Weekday.String()[:3])
+	case Every:
+		return fmt.Sprintf("every:%d", s.Interval)
+	}
+	return ""
+}
+
+// Describe returns a human readable form of the schedule.
+func (s Schedule) Describe() string {
+	switch s.Kind {
+	case Monthly:
+		return fmt.Sprintf("monthly on day %d", s.Day)
+	case Weekly:
+		return "weekly on " + s.Weekday.String()
+	case Every:
+		return fmt.Sprintf("every %d days", s.Interval)
+	}
+	return ""
+}
+
+// matches reports whether a charge falls on day d for a template started on start.
+func (s Schedule) matches(start, d time.Time) bool {
+	switch s.Kind {
+	case Monthly:
+		last := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		return d.Day() == min(s.Day, last)
+	case Weekly:
+		return d.Weekday() == s.Weekday
+	case Every:
+		days := int(d.Sub(start).Hours() / 24)
+		return days%s.Interval == 0
+	}
+	return false
+}
+
+// Occurrences returns the charge dates within [from, to] (inclusive) for a template started on start.
+func (s Schedule) Occurrences(start, from, to time.Time) []time.Time {
+	start, from, to = day(start), day(from), day(to)
+	if from.Before(start) {
+		from = start
+	}
+	var res []time.Time
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if s.matches(start, d) {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+// Template is a recurring charge that is materialized into the ledger on schedule.
+type Template struct {
+	ID          int
+	Schedule    Schedule
+	Start       string // YYYY-MM-DD, first day the template may produce a charge
+	Category    string
+	Description string
+	Amount      float64
+	LastRun     string // YYYY-MM-DD, last day already materialized; empty if never
+	Paused      bool
+}
+
+// Charge is a single scheduled occurrence of a template.
+type Charge struct {
+	Date     time.Time
+	Template Template
+}
+
+// Store keeps recurring templates in a CSV file next to the ledger.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	templates []Template
+}
+
+func New(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if strings.Join(records[0], ",") != strings.Join(header, ",") {
+		return errors.New("recurring CSV header does not match expected format")
+	}
+
+	s.templates = make([]Template, 0, len(records)-1)
+	for i, r := range records[1:] {
+		id, err := strconv.Atoi(r[0])
+		if err != nil {
+			return fmt.Errorf("invalid ID on line %d: %w", i+2, err)
+		}
+		sched, err := ParseSchedule(r[1])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", i+2, err)
+		}
+		amount, err := strconv.ParseFloat(r[5], 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount on line %d: %w", i+2, err)
+		}
+		s.templates = append(s.templates, Template{
+			ID:          id,
+			Schedule:    sched,
+			Start:       r[2],
+			Category:    r[3],
+			Description: r[4],
+			Amount:      amount,
+			LastRun:     r[6],
+			Paused:      r[7] == "true",
+		})
+	}
+	return nil
+}
+
+// save persists templates; callers must hold s.mu.
+func (s *Store) save() error {
+	file, err := os.Create(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	for _, t := range s.templates {
+		err := writer.Write([]string{
+			strconv.Itoa(t.ID),
+			t.Schedule.String(),
+			t.Start,
+			t.Category,
+			t.Description,
+			strconv.FormatFloat(t.Amount, 'f', 2, 64),
+			t.LastRun,
+			strconv.FormatBool(t.Paused),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// List returns all templates ordered by ID.
+func (s *Store) List() []Template {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]Template, len(s.templates))
+	copy(res, s.templates)
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// validate checks the user-provided fields of a template.
+func (t Template) validate() error {
+	if t.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if t.Category == "" {
+		return errors.New("category is required")
+	}
+	if t.Schedule.Kind == "" {
+		return errors.New("schedule is required")
+	}
+	if _, err := time.Parse(dateLayout, t.Start); err != nil {
+		return fmt.Errorf("invalid start date %q", t.Start)
+	}
+	return nil
+}
+
+// Get returns the template with the given ID.
+func (s *Store) Get(id int) (Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.templates {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return Template{}, ErrNotFound
+}
+
+// Add stores a new template and returns it with its assigned ID.
+func (s *Store) Add(t Template) (Template, error) {
+	if err := t.validate(); err != nil {
+		return Template{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.ID = 1
+	for _, existing := range s.templates {
+		if existing.ID >= t.ID {
+			t.ID = existing.ID + 1
+		}
+	}
+	s.templates = append(s.templates, t)
+	return t, s.save()
+}
+
+// Update replaces the schedule, start, category, description and amount of the
+// template with t.ID. Progress and the paused state are kept, so charges already
+// materialized are not repeated; use SetPaused to pause or resume.
+func (s *Store) Update(t Template) (Template, error) {
+	return s.UpdateIf(t, nil)
+}
+
+// UpdateIf is Update, applied only if match accepts the stored template and
+// ErrChanged otherwise; the check and the write happen under one lock. A nil
+// match always accepts.
+func (s *Store) UpdateIf(t Template, match func(Template) bool) (Template, error) {
+	if err := t.validate(); err != nil {
+		return Template{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.templates {
+		if s.templates[i].ID == t.ID {
+			if match != nil && !match(s.templates[i]) {
+				return Template{}, ErrChanged
+			}
+			t.LastRun, t.Paused = s.templates[i].LastRun, s.templates[i].Paused
+			s.templates[i] = t
+			return t, s.save()
+		}
+	}
+	return Template{}, ErrNotFound
+}
+
+// SetPaused pauses or resumes a template. Resuming does not backfill charges
+// that fell into the paused period: materialization continues after today.
+func (s *Store) SetPaused(id int, paused bool, today time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.templates {
+		if s.templates[i].ID == id {
+			s.templates[i].Paused = paused
+			if !paused {
+				s.templates[i].LastRun = today.Format(dateLayout)
+			}
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// Delete removes a template. Transactions it already produced stay in the ledger.
+func (s *Store) Delete(id int) error {
+	return s.DeleteIf(id, nil)
+}
+
+// DeleteIf is Delete, applied only if match accepts the stored template, see
+// UpdateIf.
+func (s *Store) DeleteIf(id int, match func(Template) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.templates {
+		if s.templates[i].ID == id {
+			if match != nil && !match(s.templates[i]) {
+				return ErrChanged
+			}
+			s.templates = append(s.templates[:i], s.templates[i+1:]...)
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// Recategorize sets the category of every template for which rename returns
+// true, e.g. to merge categories, and returns how many templates changed.
+func (s *Store) Recategorize(rename func(category string) (string, bool)) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for i, t := range s.templates {
+		if to, ok := rename(t.Category); ok && to != t.Category {
+			s.templates[i].Category = to
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.save()
+}
+
+// Upcoming returns not yet materialized charges of active templates within [from, to], sorted by date.
+func (s *Store) Upcoming(from, to time.Time) []Charge {
+	var res []Charge
+	for _, t := range s.List() {
+		if t.Paused {
+			continue
+		}
+		for _, d := range t.Schedule.Occurrences(t.start(), maxDate(t.pending(), from), to) {
+			res = append(res, Charge{Date: d, Template: t})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Date.Before(res[j].Date) })
+	return res
+}
+
+// Ledger is where Materialize writes the charges, see data.Data.
+type Ledger interface {
+	AddTransaction(tx data.Transaction) error
+}
+
+// Materialize appends every charge due up to and including today to the ledger
+// and records the progress, so that each occurrence is written exactly once.
+// Recurring charges are committed costs and are stored as fixed. If a write
+// fails, the progress up to it is still saved, so a retry does not repeat the
+// charges already written.
+func (s *Store) Materialize(ledger Ledger, today time.Time) ([]data.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var added []data.Transaction
+	todayStr := day(today).Format(dateLayout)
+	for i := range s.templates {
+		t := &s.templates[i]
+		if t.Paused {
+			continue
+		}
+		for _, d := range t.Schedule.Occurrences(t.start(), t.pending(), today) {
+			tx := data.Transaction{
+				Date:        d.Format(dateLayout),
+				Category:    t.Category,
+				Description: t.Description,
+				Amount:      t.Amount,
+				Fixed:       true,
+			}
+			if err := ledger.AddTransaction(tx); err != nil {
+				return added, errors.Join(err, s.save())
+			}
+			added = append(added, tx)
+			t.LastRun = tx.Date
+		}
+		if t.LastRun < todayStr {
+			t.LastRun = todayStr
+		}
+	}
+	return added, s.save()
+}
+
+// start returns the template start date, the anchor for "every N days" schedules.
+func (t Template) start() time.Time {
+	start, _ := time.Parse(dateLayout, t.Start)
+	return start
+}
+
+// pending returns the first day that has not been materialized yet.
+func (t Template) pending() time.Time {
+	if last, err := time.Parse(dateLayout, t.LastRun); err == nil {
+		return last.AddDate(0, 0, 1)
+	}
+	return t.start()
+}
+
+func maxDate(a, b time.Time) time.Time {
+	if day(a).After(day(b)) {
+		return a
+	}
+	return b
+}
+
+// day truncates t to a calendar date in UTC so that date arithmetic is timezone independent.
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
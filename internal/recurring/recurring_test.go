@@ -0,0 +1,249 @@
+package recurring
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(dateLayout, s)
+	if err != nil {
+		t.Fatalf("bad date %q: %v", s, err)
+	}
+	return d
+}
+
+func TestParseSchedule(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		in      string
+		want    Schedule
+		wantErr bool
+	}{
+		{"monthly", "monthly:15", Schedule{Kind: Monthly, Day: 15}, false},
+		{"monthly upper case", "Monthly:31", Schedule{Kind: Monthly, Day: 31}, false},
+		{"weekly short", "weekly:mon", Schedule{Kind: Weekly, Weekday: time.Monday}, false},
+		{"weekly long", "weekly:saturday", Schedule{Kind: Weekly, Weekday: time.Saturday}, false},
+		{"every", "every:14", Schedule{Kind: Every, Interval: 14}, false},
+		{"monthly out of range", "monthly:32", Schedule{}, true},
+		{"weekly unknown", "weekly:xyz", Schedule{}, true},
+		{"every zero", "every:0", Schedule{}, true},
+		{"missing argument", "monthly", Schedule{}, true},
+		{"unknown kind", "yearly:1", Schedule{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseSchedule(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSchedule(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSchedule(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+			if !tt.wantErr {
+				if again, _ := ParseSchedule(got.String()); again != got {
+					t.Errorf("round trip of %q = %+v, want %+v", got.String(), again, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		schedule string
+		start    string
+		from     string
+		to       string
+		want     []string
+	}{
+		{"monthly", "monthly:5", "2025-01-01", "2025-01-01", "2025-03-10", []string{"2025-01-05", "2025-02-05", "2025-03-05"}},
+		{"monthly clamps to month end", "monthly:31", "2025-01-01", "2025-02-01", "2025-04-30", []string{"2025-02-28", "2025-03-31", "2025-04-30"}},
+		{"monthly before start", "monthly:5", "2025-02-10", "2025-01-01", "2025-03-10", []string{"2025-03-05"}},
+		{"weekly", "weekly:fri", "2025-08-01", "2025-08-01", "2025-08-20", []string{"2025-08-01", "2025-08-08", "2025-08-15"}},
+		{"every anchored at start", "every:10", "2025-08-01", "2025-08-05", "2025-08-31", []string{"2025-08-11", "2025-08-21", "2025-08-31"}},
+		{"empty range", "monthly:5", "2025-01-01", "2025-01-06", "2025-01-31", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := ParseSchedule(tt.schedule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range s.Occurrences(mustDate(t, tt.start), mustDate(t, tt.from), mustDate(t, tt.to)) {
+				got = append(got, d.Format(dateLayout))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Occurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// failingLedger fails the nth add and every one after it.
+type failingLedger struct {
+	*data.Data
+	n int
+}
+
+func (l *failingLedger) AddTransaction(tx data.Transaction) error {
+	if l.n--; l.n <= 0 {
+		return errors.New("disk full")
+	}
+	return l.Data.AddTransaction(tx)
+}
+
+func TestMaterializeFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ledger, err := data.New(filepath.Join(dir, "data.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := New(filepath.Join(dir, "recurring.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rent, _ := ParseSchedule("monthly:1")
+	if _, err := store.Add(Template{Schedule: rent, Start: "2025-01-01", Category: "rent", Amount: 30000}); err != nil {
+		t.Fatal(err)
+	}
+
+	added, err := store.Materialize(&failingLedger{Data: ledger, n: 3}, mustDate(t, "2025-04-15"))
+	if err == nil || len(added) != 2 {
+		t.Fatalf("Materialize() = %d charges, %v; want 2 and the error of the third", len(added), err)
+	}
+
+	// After a restart only the charges not written yet are added.
+	reloaded, err := New(filepath.Join(dir, "recurring.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added, err = reloaded.Materialize(ledger, mustDate(t, "2025-04-15")); err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 2 || added[0].Date != "2025-03-01" {
+		t.Errorf("retry added %+v, want the charges from 2025-03-01", added)
+	}
+	if n := len(ledger.GetAllTransactions()); n != 4 {
+		t.Errorf("ledger has %d charges, want 4 without duplicates", n)
+	}
+}
+
+func TestMaterialize(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ledger, err := data.New(filepath.Join(dir, "data.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := New(filepath.Join(dir, "recurring.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rent, _ := ParseSchedule("monthly:1")
+	tmpl, err := store.Add(Template{Schedule: rent, Start: "2025-01-01", Category: "rent", Description: "Flat", Amount: 30000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone, _ := ParseSchedule("weekly:mon")
+	paused, err := store.Add(Template{Schedule: phone, Start: "2025-01-01", Category: "phone", Amount: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetPaused(paused.ID, true, mustDate(t, "2025-01-01")); err != nil {
+		t.Fatal(err)
+	}
+
+	added, err := store.Materialize(ledger, mustDate(t, "2025-02-15"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 2 || added[0].Date != "2025-01-01" || added[1].Date != "2025-02-01" {
+		t.Fatalf("first run added %+v, want charges on 2025-01-01 and 2025-02-01", added)
+	}
+
+	// Running again on the same day must not duplicate charges.
+	added, err = store.Materialize(ledger, mustDate(t, "2025-02-15"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 0 {
+		t.Fatalf("second run added %+v, want nothing", added)
+	}
+
+	// State survives a reload from disk.
+	reloaded, err := New(filepath.Join(dir, "recurring.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.List(); len(got) != 2 || got[0].LastRun != "2025-02-15" || !got[1].Paused {
+		t.Fatalf("reloaded templates = %+v", got)
+	}
+
+	upcoming := reloaded.Upcoming(mustDate(t, "2025-02-16"), mustDate(t, "2025-03-31"))
+	if len(upcoming) != 1 || upcoming[0].Template.ID != tmpl.ID || upcoming[0].Date.Format(dateLayout) != "2025-03-01" {
+		t.Errorf("Upcoming() = %+v, want a single rent charge on 2025-03-01", upcoming)
+	}
+	if len(ledger.GetAllTransactions()) != 2 {
+		t.Errorf("ledger has %d transactions, want 2", len(ledger.GetAllTransactions()))
+	}
+	// Updating a template keeps its progress.
+	tmpl.Amount = 32000
+	if _, err := reloaded.Update(tmpl); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reloaded.Get(tmpl.ID); err != nil || got.Amount != 32000 || got.LastRun != "2025-02-15" {
+		t.Errorf("Get() after Update = %+v, %v; want amount 32000 and LastRun 2025-02-15", got, err)
+	}
+	if _, err := reloaded.Update(Template{ID: 99, Schedule: rent, Start: "2025-01-01", Category: "rent", Amount: 1}); err != ErrNotFound {
+		t.Errorf("Update() of a missing template = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRecategorize(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "recurring.csv")
+	store, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	monthly, _ := ParseSchedule("monthly:1")
+	for _, category := range []string{"квартира", "phone"} {
+		if _, err := store.Add(Template{Schedule: monthly, Start: "2025-01-01", Category: category, Amount: 100}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := store.Recategorize(func(c string) (string, bool) { return "rent", c == "квартира" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := reloaded.List()
+	if n != 1 || list[0].Category != "rent" || list[1].Category != "phone" {
+		t.Errorf("Recategorize() = %d, templates %+v; want 1 with квартира renamed to rent", n, list)
+	}
+}
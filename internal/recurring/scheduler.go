@@ -0,0 +1,79 @@
+package recurring
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+// RunDaily materializes due recurring charges on start and then every day at the
+// configured local time, until ctx is cancelled.
+func RunDaily(ctx context.Context, store *Store, ledger *data.Data, timeOfDay string, tz string, logger *log.Logger) {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	loc := time.Local
+	if tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		} else {
+			logger.Printf("recurring: failed to load timezone %q, using local: %v", tz, err)
+		}
+	}
+
+	h, m, err := parseHHMM(timeOfDay)
+	if err != nil {
+		logger.Printf("recurring: invalid RECURRING_TIME %q, defaulting 00:05: %v", timeOfDay, err)
+		h, m = 0, 5
+	}
+
+	materialize(store, ledger, loc, logger)
+
+	for {
+		next := nextAtTime(time.Now().In(loc), h, m)
+		timer := time.NewTimer(time.Until(next))
+		logger.Printf("recurring: next run at %s (%s)", next.Format(time.RFC3339), loc.String())
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.Printf("recurring: stopping: %v", ctx.Err())
+			return
+		case <-timer.C:
+			materialize(store, ledger, loc, logger)
+		}
+	}
+}
+
+func materialize(store *Store, ledger *data.Data, loc *time.Location, logger *log.Logger) {
+	added, err := store.Materialize(ledger, time.Now().In(loc))
+	if err != nil {
+		logger.Printf("recurring: materialize failed after %d charges: %v", len(added), err)
+		return
+	}
+	for _, tx := range added {
+		logger.Printf("recurring: added %s %s %.2f", tx.Date, tx.Category, tx.Amount)
+	}
+}
+
+func parseHHMM(s string) (int, int, error) {
+	if s == "" {
+		return 0, 5, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, err
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+func nextAtTime(now time.Time, hour, minute int) time.Time {
+	n := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if !n.After(now) {
+		n = n.AddDate(0, 0, 1)
+	}
+	return n
+}
@@ -0,0 +1,73 @@
+package report
+
+import (
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+// Weekdays label the rows of a heatmap, Monday first.
+var Weekdays = [7]string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// Cell is the spending in one hour of one weekday.
+type Cell struct {
+	Amount float64 `json:"amount"`
+	Count  int     `json:"count"`
+}
+
+// Heatmap is the spending of a period by weekday and hour of day. Only
+// transactions with a time count; the others of the period are Untimed.
+type Heatmap struct {
+	Label    string       `json:"label"`
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Timezone string       `json:"timezone"`
+	Total    float64      `json:"total"`
+	Count    int          `json:"count"`
+	Untimed  int          `json:"untimed"`
+	Cells    [7][24]Cell  `json:"cells"` // by weekday, Monday first, then hour
+	Weekdays [7]float64   `json:"weekdays"`
+	Hours    [24]float64  `json:"hours"`
+	Peak     *HeatmapPeak `json:"peak"` // the busiest hour; nil without timed spending
+}
+
+// HeatmapPeak is the hour of a weekday with the most spending.
+type HeatmapPeak struct {
+	Weekday string `json:"weekday"`
+	Hour    int    `json:"hour"`
+	Cell
+}
+
+// BuildHeatmap places the spending of txs over p by the weekday and hour of
+// their time in loc, the zone their dates are taken in. With categories only
+// the split lines in them count, like in Build.
+func BuildHeatmap(txs []data.Transaction, p Period, loc *time.Location, categories []string) Heatmap {
+	h := Heatmap{Label: p.Label, From: p.Start.Format(dateLayout), To: p.Last().Format(dateLayout), Timezone: loc.String()}
+	in := within(p)
+	for _, tx := range txs {
+		if !in(tx.Date) {
+			continue
+		}
+		if tx.Time.IsZero() {
+			h.Untimed++
+			continue
+		}
+		t, amount := tx.Time.In(loc), tx.AmountIn(categories)
+		day := (int(t.Weekday()) + 6) % 7
+		c := &h.Cells[day][t.Hour()]
+		c.Amount += amount
+		c.Count++
+		h.Weekdays[day] += amount
+		h.Hours[t.Hour()] += amount
+		h.Total += amount
+		h.Count++
+	}
+	for day, hours := range h.Cells {
+		for hour, c := range hours {
+			if c.Amount > 0 && (h.Peak == nil || c.Amount > h.Peak.Amount) {
+				h.Peak = &HeatmapPeak{Weekday: Weekdays[day], Hour: hour, Cell: c}
+			}
+		}
+	}
+	return h
+}
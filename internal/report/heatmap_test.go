@@ -0,0 +1,51 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+func TestBuildHeatmap(t *testing.T) {
+	t.Parallel()
+
+	msk := time.FixedZone("MSK", 3*60*60)
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	txs := []data.Transaction{
+		// Friday 18:xx in Moscow, recorded in UTC
+		{Date: "2025-08-01", Amount: 300, Time: at("2025-08-01T15:10:00Z")},
+		{Date: "2025-08-01", Amount: 200, Time: at("2025-08-01T18:50:00+03:00")},
+		// Monday 09:xx
+		{Date: "2025-08-04", Amount: 100, Time: at("2025-08-04T09:05:00+03:00")},
+		{Date: "2025-08-04", Amount: 5000},
+		{Date: "2025-09-01", Amount: 70, Time: at("2025-09-01T12:00:00+03:00")},
+	}
+	h := BuildHeatmap(txs, Month(time.Date(2025, 8, 15, 0, 0, 0, 0, msk)), msk, nil)
+
+	if h.Count != 3 || h.Total != 600 || h.Untimed != 1 {
+		t.Errorf("count, total, untimed = %d, %.0f, %d, want 3, 600, 1", h.Count, h.Total, h.Untimed)
+	}
+	if c := h.Cells[4][18]; c.Amount != 500 || c.Count != 2 {
+		t.Errorf("Friday 18h = %+v, want 500 in 2", c)
+	}
+	if c := h.Cells[0][9]; c.Amount != 100 {
+		t.Errorf("Monday 9h = %+v, want 100", c)
+	}
+	if h.Weekdays[4] != 500 || h.Hours[9] != 100 {
+		t.Errorf("weekday and hour totals = %v, %v", h.Weekdays, h.Hours)
+	}
+	if h.Peak == nil || h.Peak.Weekday != "Fri" || h.Peak.Hour != 18 {
+		t.Errorf("peak = %+v, want Fri 18h", h.Peak)
+	}
+
+	if empty := BuildHeatmap(nil, Month(time.Date(2025, 8, 15, 0, 0, 0, 0, msk)), msk, nil); empty.Peak != nil {
+		t.Errorf("peak without spending = %+v, want nil", empty.Peak)
+	}
+}
@@ -0,0 +1,255 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+const dateLayout = "2006-01-02"
+
+const (
+	topMerchants = 5
+	topExpenses  = 5
+)
+
+// Period kinds.
+const (
+	KindMonth = "month"
+	KindCycle = "cycle"
+	KindYear  = "year"
+)
+
+// MaxCyclesBack bounds how many pay cycles back a cycle report may go, ten
+// years of monthly cycles.
+const MaxCyclesBack = 120
+
+// CycleFunc returns the start of the pay cycle containing a date and the next cycle start.
+type CycleFunc func(time.Time) (time.Time, time.Time)
+
+// Period is a reporting period from Start up to, not including, Next.
+type Period struct {
+	Kind  string
+	Label string
+	Start time.Time
+	Next  time.Time
+}
+
+// Last returns the last day of the period.
+func (p Period) Last() time.Time {
+	return p.Next.AddDate(0, 0, -1)
+}
+
+// Month returns the calendar month containing date.
+func Month(date time.Time) Period {
+	start := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	return Period{Kind: KindMonth, Label: start.Format("January 2006"), Start: start, Next: start.AddDate(0, 1, 0)}
+}
+
+// Year returns the calendar year containing date.
+func Year(date time.Time) Period {
+	start := time.Date(date.Year(), 1, 1, 0, 0, 0, 0, date.Location())
+	return Period{Kind: KindYear, Label: start.Format("2006"), Start: start, Next: start.AddDate(1, 0, 0)}
+}
+
+// Cycle returns the pay cycle containing date.
+func Cycle(cycle CycleFunc, date time.Time) Period {
+	start, next := cycle(date)
+	return Period{
+		Kind:  KindCycle,
+		Label: fmt.Sprintf("%s — %s", start.Format(dateLayout), next.AddDate(0, 0, -1).Format(dateLayout)),
+		Start: start,
+		Next:  next,
+	}
+}
+
+// Periods returns the period of the given kind containing date, the one before it and
+// the same period a year earlier. For years the last two are the same and only the
+// previous one is returned.
+func Periods(kind string, cycle CycleFunc, date time.Time) (cur Period, prev Period, lastYear *Period) {
+	switch kind {
+	case KindYear:
+		cur = Year(date)
+		return cur, Year(cur.Start.AddDate(-1, 0, 0)), nil
+	case KindCycle:
+		cur = Cycle(cycle, date)
+		ly := Cycle(cycle, cur.Start.AddDate(-1, 0, 0))
+		return cur, Cycle(cycle, cur.Start.AddDate(0, 0, -1)), &ly
+	default:
+		cur = Month(date)
+		ly := Month(cur.Start.AddDate(-1, 0, 0))
+		return cur, Month(cur.Start.AddDate(0, 0, -1)), &ly
+	}
+}
+
+// CategoryTotal is the spending of one category in the period and in the compared periods.
+type CategoryTotal struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Count    int     `json:"count"`
+	Share    float64 `json:"share"` // percent of the period total
+	Previous float64 `json:"previous"`
+	LastYear float64 `json:"last_year"`
+}
+
+// MerchantTotal is the spending at one merchant.
+type MerchantTotal struct {
+	Merchant string  `json:"merchant"`
+	Amount   float64 `json:"amount"`
+	Count    int     `json:"count"`
+}
+
+// Expense is a single transaction among the largest of the period.
+type Expense struct {
+	Date        string  `json:"date"`
+	Category    string  `json:"category"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+// Comparison is the total of a compared period.
+type Comparison struct {
+	Label  string  `json:"label"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Total  float64 `json:"total"`
+	Diff   float64 `json:"diff"`   // current total minus this total
+	Change float64 `json:"change"` // Diff in percent of this total; 0 when it is 0
+}
+
+// Report summarizes where the money of a period went.
+type Report struct {
+	Kind       string          `json:"kind"`
+	Label      string          `json:"label"`
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	Total      float64         `json:"total"`
+	Count      int             `json:"count"`
+	Categories []CategoryTotal `json:"categories"`
+	Previous   *Comparison     `json:"previous"`
+	LastYear   *Comparison     `json:"last_year,omitempty"`
+	Merchants  []MerchantTotal `json:"merchants"`
+	Largest    []Expense       `json:"largest"`
+}
+
+// Build summarizes txs over cur and compares it with prev and, if given, lastYear.
+// With the categories of the filter txs were selected by, only the split lines
+// in them count, see data.Transaction.PartsIn.
+func Build(txs []data.Transaction, cur, prev Period, lastYear *Period, filter []string) Report {
+	r := Report{
+		Kind:       cur.Kind,
+		Label:      cur.Label,
+		From:       cur.Start.Format(dateLayout),
+		To:         cur.Last().Format(dateLayout),
+		Categories: []CategoryTotal{},
+		Merchants:  []MerchantTotal{},
+		Largest:    []Expense{},
+	}
+
+	categories := map[string]*CategoryTotal{}
+	category := func(name string) *CategoryTotal {
+		key := strings.ToLower(name)
+		if c, ok := categories[key]; ok {
+			return c
+		}
+		c := &CategoryTotal{Category: key}
+		categories[key] = c
+		return c
+	}
+	merchants := map[string]*MerchantTotal{}
+	inCur, inPrev := within(cur), within(prev)
+	inLastYear := func(string) bool { return false }
+	if lastYear != nil {
+		inLastYear = within(*lastYear)
+	}
+	var prevTotal, lastYearTotal float64
+	for _, tx := range txs {
+		amount, parts := tx.AmountIn(filter), tx.PartsIn(filter)
+		switch {
+		case inCur(tx.Date):
+			r.Total += amount
+			r.Count++
+			for _, p := range parts {
+				c := category(p.Category)
+				c.Amount += p.Amount
+				c.Count++
+			}
+			m, ok := merchants[tx.Merchant()]
+			if !ok {
+				m = &MerchantTotal{Merchant: tx.Merchant()}
+				merchants[tx.Merchant()] = m
+			}
+			m.Amount += amount
+			m.Count++
+			r.Largest = append(r.Largest, Expense{Date: tx.Date, Category: tx.Category, Description: tx.Description, Amount: amount})
+		case inPrev(tx.Date):
+			prevTotal += amount
+			for _, p := range parts {
+				category(p.Category).Previous += p.Amount
+			}
+		}
+		// A cycle a year ago may overlap the previous one when cycles are long.
+		if inLastYear(tx.Date) {
+			lastYearTotal += amount
+			for _, p := range parts {
+				category(p.Category).LastYear += p.Amount
+			}
+		}
+	}
+
+	for _, c := range categories {
+		if r.Total > 0 {
+			c.Share = c.Amount / r.Total * 100
+		}
+		r.Categories = append(r.Categories, *c)
+	}
+	sort.Slice(r.Categories, func(i, j int) bool {
+		if r.Categories[i].Amount != r.Categories[j].Amount {
+			return r.Categories[i].Amount > r.Categories[j].Amount
+		}
+		return r.Categories[i].Category < r.Categories[j].Category
+	})
+
+	for _, m := range merchants {
+		r.Merchants = append(r.Merchants, *m)
+	}
+	sort.Slice(r.Merchants, func(i, j int) bool {
+		if r.Merchants[i].Amount != r.Merchants[j].Amount {
+			return r.Merchants[i].Amount > r.Merchants[j].Amount
+		}
+		return r.Merchants[i].Merchant < r.Merchants[j].Merchant
+	})
+	r.Merchants = r.Merchants[:min(len(r.Merchants), topMerchants)]
+
+	sort.SliceStable(r.Largest, func(i, j int) bool { return r.Largest[i].Amount > r.Largest[j].Amount })
+	r.Largest = r.Largest[:min(len(r.Largest), topExpenses)]
+
+	r.Previous = compare(prev, prevTotal, r.Total)
+	if lastYear != nil {
+		r.LastYear = compare(*lastYear, lastYearTotal, r.Total)
+	}
+	return r
+}
+
+// within returns a check for YYYY-MM-DD dates inside p.
+func within(p Period) func(string) bool {
+	from, to := p.Start.Format(dateLayout), p.Last().Format(dateLayout)
+	return func(date string) bool { return date >= from && date <= to }
+}
+
+func compare(p Period, total, current float64) *Comparison {
+	c := &Comparison{
+		Label: p.Label,
+		From:  p.Start.Format(dateLayout),
+		To:    p.Last().Format(dateLayout),
+		Total: total,
+		Diff:  current - total,
+	}
+	if total > 0 {
+		c.Change = c.Diff / total * 100
+	}
+	return c
+}
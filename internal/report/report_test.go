@@ -0,0 +1,137 @@
+package report
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+func TestPeriods(t *testing.T) {
+	t.Parallel()
+
+	// Cycles starting on the 10th of every month.
+	cycle := func(d time.Time) (time.Time, time.Time) {
+		start := time.Date(d.Year(), d.Month(), 10, 0, 0, 0, 0, time.UTC)
+		if d.Day() < 10 {
+			start = start.AddDate(0, -1, 0)
+		}
+		return start, start.AddDate(0, 1, 0)
+	}
+	date := time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		kind                string
+		cur, prev, lastYear string
+	}{
+		{KindMonth, "2025-03-01", "2025-02-01", "2024-03-01"},
+		{KindCycle, "2025-02-10", "2025-01-10", "2024-02-10"},
+		{KindYear, "2025-01-01", "2024-01-01", ""},
+	}
+	for _, tt := range tests {
+		cur, prev, lastYear := Periods(tt.kind, cycle, date)
+		if got := cur.Start.Format(dateLayout); got != tt.cur {
+			t.Errorf("%s: current starts %s, want %s", tt.kind, got, tt.cur)
+		}
+		if got := prev.Start.Format(dateLayout); got != tt.prev {
+			t.Errorf("%s: previous starts %s, want %s", tt.kind, got, tt.prev)
+		}
+		got := ""
+		if lastYear != nil {
+			got = lastYear.Start.Format(dateLayout)
+		}
+		if got != tt.lastYear {
+			t.Errorf("%s: a year ago starts %q, want %q", tt.kind, got, tt.lastYear)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	tx := func(date, category, description string, amount float64) data.Transaction {
+		return data.Transaction{Date: date, Category: category, Description: description, Amount: amount}
+	}
+	txs := []data.Transaction{
+		tx("2024-08-15", "groceries", "Pyaterochka", 2000),
+		tx("2025-07-20", "groceries", "Pyaterochka", 3000),
+		tx("2025-08-01", "groceries", "Pyaterochka 12", 1000),
+		tx("2025-08-02", "Groceries", "PYATEROCHKA 15", 2000),
+		tx("2025-08-03", "dining", "Coffee", 500),
+		tx("2025-08-31", "rent", "", 500),
+		tx("2025-09-01", "dining", "Coffee", 9000),
+	}
+	cur, prev, lastYear := Periods(KindMonth, nil, time.Date(2025, 8, 10, 0, 0, 0, 0, time.UTC))
+	r := Build(txs, cur, prev, lastYear, nil)
+
+	if r.Total != 4000 || r.Count != 4 {
+		t.Fatalf("total = %.2f in %d, want 4000 in 4", r.Total, r.Count)
+	}
+	if len(r.Categories) != 3 || r.Categories[0].Category != "groceries" {
+		t.Fatalf("categories = %+v, want groceries first of 3", r.Categories)
+	}
+	g := r.Categories[0]
+	if g.Amount != 3000 || math.Abs(g.Share-75) > 1e-9 || g.Previous != 3000 || g.LastYear != 2000 {
+		t.Errorf("groceries = %+v, want 3000 (75%%), previous 3000, a year ago 2000", g)
+	}
+	if r.Previous.Total != 3000 || math.Abs(r.Previous.Change-33.333) > 0.001 {
+		t.Errorf("previous = %+v, want 3000 and +33.3%%", r.Previous)
+	}
+	if r.LastYear == nil || r.LastYear.Total != 2000 || r.LastYear.Diff != 2000 {
+		t.Errorf("a year ago = %+v, want 2000 and +2000", r.LastYear)
+	}
+	if len(r.Merchants) == 0 || r.Merchants[0].Merchant != "pyaterochka" || r.Merchants[0].Count != 2 {
+		t.Errorf("top merchant = %+v, want pyaterochka with 2 expenses", r.Merchants)
+	}
+	if len(r.Largest) != 4 || r.Largest[0].Amount != 2000 {
+		t.Errorf("largest = %+v, want 4 starting at 2000", r.Largest)
+	}
+}
+
+func TestBuildSplits(t *testing.T) {
+	t.Parallel()
+
+	txs := []data.Transaction{
+		{Date: "2025-08-02", Category: "groceries", Description: "Auchan", Amount: 1200, Splits: []data.Split{{Category: "groceries", Amount: 800}, {Category: "household", Amount: 400}}},
+		{Date: "2025-08-03", Category: "household", Description: "Leroy", Amount: 600},
+	}
+	cur, prev, _ := Periods(KindMonth, nil, time.Date(2025, 8, 10, 0, 0, 0, 0, time.UTC))
+	r := Build(txs, cur, prev, nil, nil)
+
+	if r.Total != 1800 || r.Count != 2 {
+		t.Fatalf("total = %.2f in %d, want 1800 in 2", r.Total, r.Count)
+	}
+	if len(r.Categories) != 2 || r.Categories[0].Category != "household" || r.Categories[0].Amount != 1000 || r.Categories[1].Amount != 800 {
+		t.Errorf("categories = %+v, want household 1000 and groceries 800 from the split lines", r.Categories)
+	}
+}
+
+func TestBuildFilteredSplits(t *testing.T) {
+	t.Parallel()
+
+	receipt := func(date string) data.Transaction {
+		return data.Transaction{Date: date, Category: "household", Description: "Auchan", Amount: 1000, Splits: []data.Split{{Category: "household", Amount: 800}, {Category: "groceries/овощи", Amount: 200}}}
+	}
+	txs := []data.Transaction{
+		receipt("2024-08-05"),
+		receipt("2025-07-05"),
+		receipt("2025-08-02"),
+		{Date: "2025-08-03", Category: "groceries", Description: "Pyaterochka", Amount: 300},
+	}
+	cur, prev, lastYear := Periods(KindMonth, nil, time.Date(2025, 8, 10, 0, 0, 0, 0, time.UTC))
+	r := Build(txs, cur, prev, lastYear, []string{"groceries"})
+
+	if r.Total != 500 || r.Count != 2 {
+		t.Fatalf("total = %.2f in %d, want 500 in 2", r.Total, r.Count)
+	}
+	if len(r.Categories) != 2 || r.Categories[0].Category != "groceries" || r.Categories[1].Category != "groceries/овощи" || r.Categories[1].Amount != 200 {
+		t.Errorf("categories = %+v, want only the groceries lines", r.Categories)
+	}
+	if r.Previous.Total != 200 || r.LastYear.Total != 200 {
+		t.Errorf("previous = %.2f, a year ago = %.2f; want 200 each", r.Previous.Total, r.LastYear.Total)
+	}
+	if r.Largest[0].Amount != 300 || r.Largest[1].Amount != 200 {
+		t.Errorf("largest = %+v, want 300 and the 200 groceries line", r.Largest)
+	}
+}
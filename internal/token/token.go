@@ -0,0 +1,238 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// prefix marks secrets of this app, so they are easy to spot in scripts and logs.
+const prefix = "gah_"
+
+var header = []string{"ID", "Name", "Scope", "Hash", "Owner", "Created", "LastUsed"}
+
+var (
+	// ErrNotFound is returned when a token with the given ID does not exist.
+	ErrNotFound = errors.New("token not found")
+	// ErrInvalid is returned for a secret that matches no token.
+	ErrInvalid = errors.New("invalid token")
+)
+
+// Scope limits what a token may do. Each scope includes the ones below it.
+type Scope string
+
+const (
+	Read  Scope = "read"  // query data
+	Write Scope = "write" // also add, change and delete transactions and templates
+	Admin Scope = "admin" // also change settings
+)
+
+var levels = map[Scope]int{Read: 1, Write: 2, Admin: 3}
+
+// ParseScope parses a scope name; "readonly" and "ro" are accepted for read.
+func ParseScope(s string) (Scope, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "read", "readonly", "read-only", "ro":
+		return Read, nil
+	case "write", "rw":
+		return Write, nil
+	case "admin":
+		return Admin, nil
+	}
+	return "", fmt.Errorf("unknown scope %q: expected read, write or admin", s)
+}
+
+// Allows reports whether a token with scope s may perform an action needing required.
+func (s Scope) Allows(required Scope) bool {
+	return levels[s] >= levels[required]
+}
+
+// Token is an API token. Only the SHA-256 hash of the secret is kept; the secret
+// itself is shown once on creation.
+type Token struct {
+	ID       int
+	Name     string
+	Scope    Scope
+	Hash     string
+	Owner    int64  // Telegram user who created the token
+	Created  string // YYYY-MM-DD
+	LastUsed string // YYYY-MM-DD; empty if never used
+}
+
+// Store keeps API tokens in a CSV file next to the ledger.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	tokens []Token
+}
+
+func New(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if strings.Join(records[0], ",") != strings.Join(header, ",") {
+		return errors.New("tokens CSV header does not match expected format")
+	}
+	for i, r := range records[1:] {
+		id, err := strconv.Atoi(r[0])
+		if err != nil {
+			return fmt.Errorf("invalid ID on line %d: %w", i+2, err)
+		}
+		scope, err := ParseScope(r[2])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", i+2, err)
+		}
+		owner, err := strconv.ParseInt(r[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid owner on line %d: %w", i+2, err)
+		}
+		s.tokens = append(s.tokens, Token{ID: id, Name: r[1], Scope: scope, Hash: r[3], Owner: owner, Created: r[5], LastUsed: r[6]})
+	}
+	return nil
+}
+
+// save persists tokens; callers must hold s.mu. The file holds no secrets, only
+// hashes, but is still written readable by the owner only.
+func (s *Store) save() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	for _, t := range s.tokens {
+		err := writer.Write([]string{
+			strconv.Itoa(t.ID),
+			t.Name,
+			string(t.Scope),
+			t.Hash,
+			strconv.FormatInt(t.Owner, 10),
+			t.Created,
+			t.LastUsed,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Create stores a new token and returns it with its secret.
+func (s *Store) Create(name string, scope Scope, owner int64, today time.Time) (Token, string, error) {
+	if _, ok := levels[scope]; !ok {
+		return Token{}, "", fmt.Errorf("unknown scope %q", scope)
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return Token{}, "", err
+	}
+	secret := prefix + base64.RawURLEncoding.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := Token{ID: 1, Name: strings.TrimSpace(name), Scope: scope, Hash: hash(secret), Owner: owner, Created: today.Format(dateLayout)}
+	for _, existing := range s.tokens {
+		if existing.ID >= t.ID {
+			t.ID = existing.ID + 1
+		}
+	}
+	if t.Name == "" {
+		t.Name = fmt.Sprintf("token %d", t.ID)
+	}
+	s.tokens = append(s.tokens, t)
+	return t, secret, s.save()
+}
+
+// List returns all tokens ordered by ID.
+func (s *Store) List() []Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := make([]Token, len(s.tokens))
+	copy(res, s.tokens)
+	return res
+}
+
+// Revoke deletes a token; requests using it fail from then on.
+func (s *Store) Revoke(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.tokens {
+		if s.tokens[i].ID == id {
+			s.tokens = append(s.tokens[:i], s.tokens[i+1:]...)
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// Authenticate returns the token with the given secret and records the day it was
+// used, writing at most once a day per token.
+func (s *Store) Authenticate(secret string, today time.Time) (Token, error) {
+	if !strings.HasPrefix(secret, prefix) {
+		return Token{}, ErrInvalid
+	}
+	h := []byte(hash(secret))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.tokens {
+		t := &s.tokens[i]
+		if subtle.ConstantTimeCompare([]byte(t.Hash), h) != 1 {
+			continue
+		}
+		if day := today.Format(dateLayout); t.LastUsed != day {
+			t.LastUsed = day
+			if err := s.save(); err != nil {
+				return Token{}, err
+			}
+		}
+		return *t, nil
+	}
+	return Token{}, ErrInvalid
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
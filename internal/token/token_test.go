@@ -0,0 +1,84 @@
+package token
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScopeAllows(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scope, required Scope
+		want            bool
+	}{
+		{Read, Read, true},
+		{Read, Write, false},
+		{Write, Read, true},
+		{Write, Admin, false},
+		{Admin, Write, true},
+		{Scope("bogus"), Read, false},
+	}
+	for _, tt := range tests {
+		if got := tt.scope.Allows(tt.required); got != tt.want {
+			t.Errorf("%q.Allows(%q) = %v, want %v", tt.scope, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tokens.csv")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	created, secret, err := s.Create("shortcut", Write, 42, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Create("", Read, 42, day); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), secret) {
+		t.Fatal("the secret is stored in plain text")
+	}
+
+	// Reload to make sure tokens round-trip through the CSV file.
+	s, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.List(); len(got) != 2 || got[1].Name != "token 2" || got[1].Scope != Read {
+		t.Fatalf("List() = %+v, want the two created tokens", got)
+	}
+
+	got, err := s.Authenticate(secret, day.AddDate(0, 0, 3))
+	if err != nil || got.ID != created.ID || got.Scope != Write || got.LastUsed != "2025-08-04" {
+		t.Errorf("Authenticate() = %+v, %v; want token %d used on 2025-08-04", got, err, created.ID)
+	}
+	if _, err := s.Authenticate(secret+"x", day); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Authenticate() with a wrong secret = %v, want ErrInvalid", err)
+	}
+
+	if err := s.Revoke(created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(secret, day); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Authenticate() with a revoked token = %v, want ErrInvalid", err)
+	}
+	if err := s.Revoke(created.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Revoke() twice = %v, want ErrNotFound", err)
+	}
+}
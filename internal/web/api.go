@@ -0,0 +1,753 @@
+package web
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/idempotency"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/report"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/token"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// apiPrefix is the base path of the versioned resource API. Breaking changes go
+// into a new version; fields may be added within a version.
+const apiPrefix = "/expenses/api/v1"
+
+// apiRoute is an endpoint of the resource API. The route table drives both the
+// router and the OpenAPI document, so the two cannot drift apart.
+type apiRoute struct {
+	Method   string
+	Path     string // gin syntax, e.g. /transactions/:id
+	Summary  string
+	Params   []apiParam  // query parameters; path parameters are derived from Path
+	Body     interface{} // request body DTO, nil if none
+	File     string      // multipart field of an uploaded file, instead of Body
+	Response interface{} // response DTO, nil for 204 No Content
+	Download bool        // the response is a stored file, instead of Response
+	Status   int         // success status
+	Scope    token.Scope // required token scope; read for GET, write otherwise if empty
+	Handler  gin.HandlerFunc
+}
+
+// scope returns the token scope required by the route.
+func (rt apiRoute) scope() token.Scope {
+	switch {
+	case rt.Scope != "":
+		return rt.Scope
+	case rt.Method == http.MethodGet:
+		return token.Read
+	}
+	return token.Write
+}
+
+type apiParam struct {
+	Name        string
+	Type        string // OpenAPI type of the value
+	Description string
+	In          string // query (default) or header
+}
+
+// APIError is the envelope of every error response of the API.
+type APIError struct {
+	Error APIErrorDetail `json:"error"`
+}
+
+type APIErrorDetail struct {
+	Code    string `json:"code"` // invalid_request, unauthorized, forbidden, not_found, conflict, precondition_failed or internal
+	Message string `json:"message"`
+}
+
+type TransactionInput struct {
+	// Date is YYYY-MM-DD; it may be left out when Time is given
+	Date        string   `json:"date"`
+	Category    string   `json:"category" binding:"required"`
+	Description string   `json:"description"`
+	Amount      float64  `json:"amount" binding:"required"`
+	Fixed       bool     `json:"fixed"`
+	Payer       string   `json:"payer"`
+	Tags        []string `json:"tags"`
+	// Splits divide the amount across categories; they must add up to it
+	Splits []SplitInput `json:"splits"`
+	// Time is when it happened, RFC 3339 with a zone offset, if known; the date
+	// is then its day in the time zone of the reports
+	Time string `json:"time"`
+}
+
+type SplitInput struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+}
+
+type TransactionResource struct {
+	ID string `json:"id"`
+	TransactionInput
+	Merchant string `json:"merchant"`
+	Fiscal   string `json:"fiscal"` // key of the fiscal receipt it was entered from; kept on replace
+}
+
+type TransactionList struct {
+	Transactions []TransactionResource `json:"transactions"`
+	Totals       data.Totals           `json:"totals"`
+	NextCursor   string                `json:"next_cursor"`
+}
+
+type CategoryInput struct {
+	ID       string   `json:"id" binding:"required"`
+	Name     string   `json:"name" binding:"required"`
+	Emoji    string   `json:"emoji"`
+	Color    string   `json:"color"`  // #rrggbb
+	Parent   string   `json:"parent"` // ID of the parent category
+	Archived bool     `json:"archived"`
+	Aliases  []string `json:"aliases"` // other spellings, resolved to this category on entry
+}
+
+// CategoryResource is a managed category with its use in the ledger. Spellings
+// found in the ledger that are not managed are listed with managed false, so
+// they can be merged.
+type CategoryResource struct {
+	CategoryInput
+	Managed bool    `json:"managed"`
+	Count   int     `json:"count"`
+	Total   float64 `json:"total"`
+	Fixed   bool    `json:"fixed"`
+	Last    string  `json:"last"` // date of the latest transaction
+}
+
+type CategoryMerge struct {
+	Sources []string `json:"sources" binding:"required"` // category IDs or any spellings
+	Target  string   `json:"target" binding:"required"`
+}
+
+type CategoryMergeResult struct {
+	Target    CategoryResource `json:"target"`
+	Rewritten int              `json:"rewritten"` // transactions moved to the target
+}
+
+// ReceiptResource is a photo or PDF attached to a transaction. Its content is
+// downloaded from URL.
+type ReceiptResource struct {
+	Hash        string `json:"hash"` // SHA-256 of the content
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Added       string `json:"added"`
+	URL         string `json:"url"`
+}
+
+type BudgetResource struct {
+	MonthlyBudget float64 `json:"monthly_budget"`
+	Date          string  `json:"date"`
+	CycleStart    string  `json:"cycle_start"`
+	CycleEnd      string  `json:"cycle_end"`
+	DaysInCycle   int     `json:"days_in_cycle"`
+	DayIndex      int     `json:"day_index"`
+	Profile       string  `json:"profile"`
+	CycleBudget   float64 `json:"cycle_budget"`
+	Fixed         float64 `json:"fixed"`
+	Carry         float64 `json:"carry"`
+	Discretionary float64 `json:"discretionary"`
+	Spent         float64 `json:"spent"`
+	Allowed       float64 `json:"allowed"`
+	Saldo         float64 `json:"saldo"`
+	Tomorrow      float64 `json:"tomorrow"`
+}
+
+type RecurringInput struct {
+	Schedule    string  `json:"schedule" binding:"required"` // monthly:N, weekly:DAY or every:N
+	Start       string  `json:"start" binding:"required"`
+	Category    string  `json:"category" binding:"required"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount" binding:"required"`
+	Paused      bool    `json:"paused"`
+}
+
+type RecurringResource struct {
+	ID int `json:"id"`
+	RecurringInput
+	LastRun string `json:"last_run"`
+}
+
+type SettingsResource struct {
+	MonthlyBudget         float64  `json:"monthly_budget"`
+	MonthlyBudgetOverride bool     `json:"monthly_budget_override"`
+	Mode                  string   `json:"mode"`
+	Profile               string   `json:"profile"`
+	DefaultProfile        string   `json:"default_profile"`
+	Profiles              []string `json:"profiles"`
+	Cycles                string   `json:"cycles"`
+	Rollover              string   `json:"rollover"`
+	RolloverCap           float64  `json:"rollover_cap"`
+	FixedCategories       []string `json:"fixed_categories"`
+}
+
+// SettingsInput changes the runtime settings; omitted fields are kept. Overrides
+// last until restart, like /budget and /profile in the bot.
+type SettingsInput struct {
+	MonthlyBudget *float64 `json:"monthly_budget"` // 0 resets to MONTHLY_BUDGET_RUB
+	Profile       *string  `json:"profile"`        // empty resets to ALLOWANCE_PROFILE
+}
+
+// apiRoutes is the route table of the v1 API.
+func (s *Server) apiRoutes() []apiRoute {
+	return []apiRoute{
+		{Method: http.MethodGet, Path: "/transactions", Summary: "Query transactions", Params: transactionParams, Response: TransactionList{}, Status: http.StatusOK, Handler: s.apiListTransactions},
+		{Method: http.MethodPost, Path: "/transactions", Summary: "Add a transaction", Params: []apiParam{idempotencyParam}, Body: TransactionInput{}, Response: TransactionResource{}, Status: http.StatusCreated, Handler: s.apiCreateTransaction},
+		{Method: http.MethodGet, Path: "/transactions/:id", Summary: "Get a transaction", Response: TransactionResource{}, Status: http.StatusOK, Handler: s.apiGetTransaction},
+		{Method: http.MethodPut, Path: "/transactions/:id", Summary: "Replace a transaction", Body: TransactionInput{}, Response: TransactionResource{}, Status: http.StatusOK, Handler: s.apiUpdateTransaction},
+		{Method: http.MethodDelete, Path: "/transactions/:id", Summary: "Delete a transaction", Status: http.StatusNoContent, Handler: s.apiDeleteTransaction},
+		{Method: http.MethodGet, Path: "/transactions/:id/receipts", Summary: "List the receipts of a transaction", Response: []ReceiptResource{}, Status: http.StatusOK, Handler: s.apiListReceipts},
+		{Method: http.MethodPost, Path: "/transactions/:id/receipts", Summary: "Attach a receipt photo or PDF, up to 20 MB", File: "file", Response: ReceiptResource{}, Status: http.StatusCreated, Handler: s.apiAttachReceipt},
+		{Method: http.MethodGet, Path: "/transactions/:id/receipts/:hash", Summary: "Download a receipt", Download: true, Status: http.StatusOK, Handler: s.apiGetReceipt},
+		{Method: http.MethodDelete, Path: "/transactions/:id/receipts/:hash", Summary: "Remove a receipt from a transaction", Status: http.StatusNoContent, Handler: s.apiDeleteReceipt},
+		{Method: http.MethodGet, Path: "/categories", Summary: "List the managed categories and other spellings used in the ledger", Params: []apiParam{{Name: "include_archived", Type: "boolean", Description: "Also list archived categories"}}, Response: []CategoryResource{}, Status: http.StatusOK, Handler: s.apiListCategories},
+		{Method: http.MethodPost, Path: "/categories", Summary: "Add a category", Body: CategoryInput{}, Response: CategoryResource{}, Status: http.StatusCreated, Handler: s.apiCreateCategory},
+		{Method: http.MethodPost, Path: "/categories/merge", Summary: "Merge categories or spellings into a target and rewrite the ledger", Body: CategoryMerge{}, Response: CategoryMergeResult{}, Status: http.StatusOK, Scope: token.Admin, Handler: s.apiMergeCategories},
+		{Method: http.MethodGet, Path: "/categories/:id", Summary: "Get a category", Response: CategoryResource{}, Status: http.StatusOK, Handler: s.apiGetCategory},
+		{Method: http.MethodPut, Path: "/categories/:id", Summary: "Replace a category; the ID cannot change", Body: CategoryInput{}, Response: CategoryResource{}, Status: http.StatusOK, Handler: s.apiUpdateCategory},
+		{Method: http.MethodDelete, Path: "/categories/:id", Summary: "Delete a category that no transaction uses", Status: http.StatusNoContent, Handler: s.apiDeleteCategory},
+		{Method: http.MethodGet, Path: "/budget", Summary: "Budget status of the pay cycle containing a day", Params: []apiParam{{Name: "date", Type: "string", Description: "YYYY-MM-DD, default today"}}, Response: BudgetResource{}, Status: http.StatusOK, Handler: s.apiGetBudget},
+		{Method: http.MethodGet, Path: "/reports/heatmap", Summary: "Spending of a month or year by weekday and hour of day, from transactions with a time", Params: heatmapParams, Response: report.Heatmap{}, Status: http.StatusOK, Handler: s.apiGetHeatmap},
+		{Method: http.MethodGet, Path: "/recurring", Summary: "List recurring templates", Response: []RecurringResource{}, Status: http.StatusOK, Handler: s.apiListRecurring},
+		{Method: http.MethodPost, Path: "/recurring", Summary: "Add a recurring template", Body: RecurringInput{}, Response: RecurringResource{}, Status: http.StatusCreated, Handler: s.apiCreateRecurring},
+		{Method: http.MethodGet, Path: "/recurring/:id", Summary: "Get a recurring template", Response: RecurringResource{}, Status: http.StatusOK, Handler: s.apiGetRecurring},
+		{Method: http.MethodPut, Path: "/recurring/:id", Summary: "Replace a recurring template", Body: RecurringInput{}, Response: RecurringResource{}, Status: http.StatusOK, Handler: s.apiUpdateRecurring},
+		{Method: http.MethodDelete, Path: "/recurring/:id", Summary: "Delete a recurring template; its past charges stay in the ledger", Status: http.StatusNoContent, Handler: s.apiDeleteRecurring},
+		{Method: http.MethodGet, Path: "/settings", Summary: "Get the budget settings", Response: SettingsResource{}, Status: http.StatusOK, Handler: s.apiGetSettings},
+		{Method: http.MethodPut, Path: "/settings", Summary: "Change the runtime budget settings", Body: SettingsInput{}, Response: SettingsResource{}, Status: http.StatusOK, Scope: token.Admin, Handler: s.apiUpdateSettings},
+	}
+}
+
+var idempotencyParam = apiParam{Name: "Idempotency-Key", Type: "string", In: "header", Description: "Client-generated key, e.g. a UUID; a repeat within 24 hours returns the original transaction instead of adding another"}
+
+// transactionParams are the query parameters of GET /transactions, see parseQuery.
+var transactionParams = []apiParam{
+	{Name: "date", Type: "string", Description: "Exact day, YYYY-MM-DD"},
+	{Name: "from", Type: "string", Description: "First day, YYYY-MM-DD"},
+	{Name: "to", Type: "string", Description: "Last day, YYYY-MM-DD"},
+	{Name: "category", Type: "string", Description: "Any of these categories and their subcategories; repeat or comma separate"},
+	{Name: "tag", Type: "string", Description: "Any of these tags; repeat or comma separate"},
+	{Name: "min_amount", Type: "number", Description: "Minimum amount, inclusive"},
+	{Name: "max_amount", Type: "number", Description: "Maximum amount, inclusive"},
+	{Name: "q", Type: "string", Description: "Description substring, case-insensitive"},
+	{Name: "regex", Type: "string", Description: "Description regular expression"},
+	{Name: "merchant", Type: "string", Description: "Merchant, normalized"},
+	{Name: "payer", Type: "string", Description: "Payer, case-insensitive"},
+	{Name: "sort", Type: "string", Description: "date, amount, category, description, merchant or payer; prefix with - for descending"},
+	{Name: "limit", Type: "integer", Description: "Page size, 1 to 1000"},
+	{Name: "cursor", Type: "string", Description: "next_cursor of the previous page"},
+}
+
+// heatmapParams are the query parameters of GET /reports/heatmap, see apiGetHeatmap.
+var heatmapParams = []apiParam{
+	{Name: "month", Type: "string", Description: "YYYY-MM, default the current month"},
+	{Name: "year", Type: "string", Description: "YYYY, instead of a month"},
+	{Name: "category", Type: "string", Description: "Any of these categories and their subcategories; repeat or comma separate"},
+	{Name: "tag", Type: "string", Description: "Any of these tags; repeat or comma separate"},
+}
+
+// registerAPI mounts the API. Every route needs a Bearer token with the route's
+// scope; the OpenAPI document is public.
+func (s *Server) registerAPI(r *gin.Engine) {
+	api := r.Group(apiPrefix)
+	for _, rt := range s.apiRoutes() {
+		api.Handle(rt.Method, rt.Path, s.requireScope(rt.scope()), rt.Handler)
+	}
+	api.GET("/openapi.json", s.handleOpenAPI)
+}
+
+// --- Errors and concurrency ---
+
+func apiError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, APIError{Error: APIErrorDetail{Code: code, Message: message}})
+}
+
+// etag returns a strong entity tag of the JSON representation of v.
+func etag(v interface{}) string {
+	b, _ := json.Marshal(v)
+	sum := sha1.Sum(b)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// respond writes v with its ETag, or 304 when the client already has it.
+func respond(c *gin.Context, status int, v interface{}) {
+	tag := etag(v)
+	c.Header("ETag", tag)
+	if status == http.StatusOK && matchesETag(c.GetHeader("If-None-Match"), tag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(status, v)
+}
+
+// precondition checks If-Match against the current representation of a resource.
+// Without the header the write is unconditional. It reports whether to proceed.
+// Writes to a store pass ifMatch instead, so the check is atomic with the write.
+func precondition(c *gin.Context, current interface{}) bool {
+	if ifMatch(c)(current) {
+		return true
+	}
+	preconditionFailed(c)
+	return false
+}
+
+// ifMatch returns the If-Match check of a representation, for a store to run
+// under its lock together with the write. Without the header everything matches.
+func ifMatch(c *gin.Context) func(current interface{}) bool {
+	header := c.GetHeader("If-Match")
+	return func(current interface{}) bool {
+		return header == "" || matchesETag(header, etag(current))
+	}
+}
+
+func preconditionFailed(c *gin.Context) {
+	apiError(c, http.StatusPreconditionFailed, "precondition_failed", "Resource has changed, fetch it again and retry")
+}
+
+func matchesETag(header, tag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		if v = strings.TrimSpace(v); v == "*" || strings.TrimPrefix(v, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func bindBody(c *gin.Context, dst interface{}) bool {
+	err := c.ShouldBindJSON(dst)
+	var missing validator.ValidationErrors
+	switch {
+	case errors.As(err, &missing):
+		names := make([]string, len(missing))
+		for i, f := range missing {
+			names[i] = strings.ToLower(f.Field())
+		}
+		apiError(c, http.StatusBadRequest, "invalid_request", "Missing required fields: "+strings.Join(names, ", "))
+		return false
+	case err != nil:
+		apiError(c, http.StatusBadRequest, "invalid_request", "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// --- Transactions ---
+
+func transactionResource(tx data.Transaction) TransactionResource {
+	if tx.Tags == nil {
+		tx.Tags = []string{}
+	}
+	splits := []SplitInput{}
+	for _, s := range tx.Splits {
+		splits = append(splits, SplitInput{Category: s.Category, Amount: s.Amount})
+	}
+	at := ""
+	if !tx.Time.IsZero() {
+		at = tx.Time.Format(data.TimeLayout)
+	}
+	return TransactionResource{
+		ID: tx.ID,
+		TransactionInput: TransactionInput{
+			Date:        tx.Date,
+			Category:    tx.Category,
+			Description: tx.Description,
+			Amount:      tx.Amount,
+			Fixed:       tx.Fixed,
+			Payer:       tx.Payer,
+			Tags:        tx.Tags,
+			Splits:      splits,
+			Time:        at,
+		},
+		Merchant: tx.Merchant(),
+		Fiscal:   tx.Fiscal,
+	}
+}
+
+func (in TransactionInput) transaction(id string) (data.Transaction, error) {
+	var at time.Time
+	if in.Time != "" {
+		var err error
+		if at, err = time.Parse(data.TimeLayout, in.Time); err != nil {
+			return data.Transaction{}, errors.New("Invalid time, expected RFC 3339 such as 2025-08-01T18:45:00+03:00")
+		}
+	} else if _, err := time.Parse("2006-01-02", in.Date); err != nil {
+		return data.Transaction{}, errors.New("Invalid date, expected YYYY-MM-DD")
+	}
+	if strings.TrimSpace(in.Category) == "" {
+		return data.Transaction{}, errors.New("Category is required")
+	}
+	if in.Amount <= 0 {
+		return data.Transaction{}, errors.New("Amount must be positive")
+	}
+	tx := data.Transaction{
+		ID:          id,
+		Date:        in.Date,
+		Category:    strings.TrimSpace(in.Category),
+		Description: in.Description,
+		Amount:      in.Amount,
+		Fixed:       in.Fixed,
+		Payer:       in.Payer,
+		Tags:        in.Tags,
+		Splits:      splits(in.Splits),
+		Time:        at,
+	}
+	return tx, tx.ValidateSplits()
+}
+
+func splits(in []SplitInput) []data.Split {
+	var res []data.Split
+	for _, s := range in {
+		res = append(res, data.Split{Category: strings.TrimSpace(s.Category), Amount: s.Amount})
+	}
+	return res
+}
+
+func (s *Server) apiListTransactions(c *gin.Context) {
+	q, err := s.parseQuery(c)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	page, err := s.data.Query(q)
+	if errors.Is(err, data.ErrInvalidCursor) {
+		apiError(c, http.StatusBadRequest, "invalid_request", "Invalid or stale cursor, start from the first page")
+		return
+	}
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	list := TransactionList{Transactions: make([]TransactionResource, len(page.Transactions)), Totals: page.Totals, NextCursor: page.NextCursor}
+	for i, tx := range page.Transactions {
+		list.Transactions[i] = transactionResource(tx)
+	}
+	respond(c, http.StatusOK, list)
+}
+
+func (s *Server) apiCreateTransaction(c *gin.Context) {
+	var in TransactionInput
+	if !bindBody(c, &in) {
+		return
+	}
+	tx, err := in.transaction("")
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	key := c.GetHeader("Idempotency-Key")
+	if len(key) > idempotency.MaxKeyLength {
+		apiError(c, http.StatusBadRequest, "invalid_request", "Idempotency-Key is too long")
+		return
+	}
+	tx, replayed, err := s.createTransaction(tx, key)
+	if err != nil {
+		apiError(c, http.StatusInternalServerError, "internal", "Failed to save transaction")
+		return
+	}
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	res := transactionResource(tx)
+	c.Header("Location", apiPrefix+"/transactions/"+res.ID)
+	respond(c, http.StatusCreated, res)
+}
+
+// currentTransaction loads the transaction named in the path, writing a 404 if it
+// does not exist.
+func (s *Server) currentTransaction(c *gin.Context) (TransactionResource, bool) {
+	tx, err := s.data.GetTransaction(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "not_found", "Transaction not found")
+		return TransactionResource{}, false
+	}
+	return transactionResource(tx), true
+}
+
+func (s *Server) apiGetTransaction(c *gin.Context) {
+	if cur, ok := s.currentTransaction(c); ok {
+		respond(c, http.StatusOK, cur)
+	}
+}
+
+func (s *Server) apiUpdateTransaction(c *gin.Context) {
+	cur, ok := s.currentTransaction(c)
+	if !ok || !precondition(c, cur) {
+		return
+	}
+	match := ifMatch(c)
+	var in TransactionInput
+	if !bindBody(c, &in) {
+		return
+	}
+	tx, err := in.transaction(cur.ID)
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	tx.Fiscal = cur.Fiscal
+	err = s.data.UpdateTransactionIf(tx, func(stored data.Transaction) bool { return match(transactionResource(stored)) })
+	if errors.Is(err, data.ErrNotFound) {
+		apiError(c, http.StatusNotFound, "not_found", "Transaction not found")
+		return
+	} else if errors.Is(err, data.ErrChanged) {
+		preconditionFailed(c)
+		return
+	} else if err != nil {
+		apiError(c, http.StatusInternalServerError, "internal", "Failed to save transaction")
+		return
+	}
+	// As stored, with the date of a timed transaction derived
+	if stored, err := s.data.GetTransaction(tx.ID); err == nil {
+		tx = stored
+	}
+	respond(c, http.StatusOK, transactionResource(tx))
+}
+
+func (s *Server) apiDeleteTransaction(c *gin.Context) {
+	cur, ok := s.currentTransaction(c)
+	if !ok || !precondition(c, cur) {
+		return
+	}
+	match := ifMatch(c)
+	err := s.data.DeleteTransactionIf(cur.ID, func(stored data.Transaction) bool { return match(transactionResource(stored)) })
+	if errors.Is(err, data.ErrNotFound) {
+		apiError(c, http.StatusNotFound, "not_found", "Transaction not found")
+		return
+	} else if errors.Is(err, data.ErrChanged) {
+		preconditionFailed(c)
+		return
+	} else if err != nil {
+		apiError(c, http.StatusInternalServerError, "internal", "Failed to save transactions")
+		return
+	}
+	s.detachReceipts(cur.ID)
+	c.Status(http.StatusNoContent)
+}
+
+// --- Budget and settings ---
+
+func (s *Server) apiGetBudget(c *gin.Context) {
+	date := s.now()
+	if v := c.Query("date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "invalid_request", "Invalid date, expected YYYY-MM-DD")
+			return
+		}
+		date = d
+	}
+	monthly := s.planner.MonthlyBudget()
+	st := s.planner.Status(date, monthly)
+	respond(c, http.StatusOK, BudgetResource{
+		MonthlyBudget: monthly,
+		Date:          st.Date.Format("2006-01-02"),
+		CycleStart:    st.CycleStart.Format("2006-01-02"),
+		CycleEnd:      st.NextCycleStart.AddDate(0, 0, -1).Format("2006-01-02"),
+		DaysInCycle:   st.DaysInCycle,
+		DayIndex:      st.DayIndex,
+		Profile:       st.Profile,
+		CycleBudget:   st.Budget,
+		Fixed:         st.Fixed,
+		Carry:         st.Carry,
+		Discretionary: st.Discretionary,
+		Spent:         st.Spent,
+		Allowed:       st.Allowed,
+		Saldo:         st.Saldo,
+		Tomorrow:      st.Tomorrow,
+	})
+}
+
+func (s *Server) settingsResource() SettingsResource {
+	settings := s.planner.Settings()
+	return SettingsResource{
+		MonthlyBudget:         s.planner.MonthlyBudget(),
+		MonthlyBudgetOverride: s.planner.MonthlyBudgetOverridden(),
+		Mode:                  settings.Mode,
+		Profile:               s.planner.Profile().Name,
+		DefaultProfile:        settings.Profile,
+		Profiles:              settings.ProfileNames(),
+		Cycles:                s.planner.Cycles().Describe(),
+		Rollover:              settings.Rollover.Policy,
+		RolloverCap:           settings.Rollover.Cap,
+		FixedCategories:       fixedCategories(settings),
+	}
+}
+
+func (s *Server) apiGetSettings(c *gin.Context) {
+	respond(c, http.StatusOK, s.settingsResource())
+}
+
+func (s *Server) apiUpdateSettings(c *gin.Context) {
+	if !precondition(c, s.settingsResource()) {
+		return
+	}
+	var in SettingsInput
+	if !bindBody(c, &in) {
+		return
+	}
+	if in.Profile != nil {
+		if err := s.planner.SetProfile(*in.Profile); err != nil {
+			apiError(c, http.StatusBadRequest, "invalid_request", "Unknown profile "+*in.Profile)
+			return
+		}
+	}
+	if in.MonthlyBudget != nil {
+		if err := s.planner.SetMonthlyBudget(*in.MonthlyBudget); err != nil {
+			apiError(c, http.StatusBadRequest, "invalid_request", "Monthly budget must not be negative")
+			return
+		}
+	}
+	respond(c, http.StatusOK, s.settingsResource())
+}
+
+// --- Recurring templates ---
+
+func recurringResource(t recurring.Template) RecurringResource {
+	return RecurringResource{
+		ID: t.ID,
+		RecurringInput: RecurringInput{
+			Schedule:    t.Schedule.String(),
+			Start:       t.Start,
+			Category:    t.Category,
+			Description: t.Description,
+			Amount:      t.Amount,
+			Paused:      t.Paused,
+		},
+		LastRun: t.LastRun,
+	}
+}
+
+func (in RecurringInput) template(id int) (recurring.Template, error) {
+	sched, err := recurring.ParseSchedule(in.Schedule)
+	if err != nil {
+		return recurring.Template{}, err
+	}
+	return recurring.Template{
+		ID:          id,
+		Schedule:    sched,
+		Start:       in.Start,
+		Category:    strings.TrimSpace(in.Category),
+		Description: in.Description,
+		Amount:      in.Amount,
+	}, nil
+}
+
+func (s *Server) apiListRecurring(c *gin.Context) {
+	templates := s.templates.List()
+	res := make([]RecurringResource, len(templates))
+	for i, t := range templates {
+		res[i] = recurringResource(t)
+	}
+	respond(c, http.StatusOK, res)
+}
+
+func (s *Server) apiCreateRecurring(c *gin.Context) {
+	var in RecurringInput
+	if !bindBody(c, &in) {
+		return
+	}
+	t, err := in.template(0)
+	if err == nil {
+		t, err = s.templates.Add(t)
+	}
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if in.Paused {
+		if err := s.templates.SetPaused(t.ID, true, s.now()); err != nil {
+			apiError(c, http.StatusInternalServerError, "internal", "Failed to save template")
+			return
+		}
+		t.Paused = true
+	}
+	c.Header("Location", apiPrefix+"/recurring/"+strconv.Itoa(t.ID))
+	respond(c, http.StatusCreated, recurringResource(t))
+}
+
+// currentTemplate loads the template named in the path, writing a 404 if it does
+// not exist.
+func (s *Server) currentTemplate(c *gin.Context) (recurring.Template, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err == nil {
+		var t recurring.Template
+		if t, err = s.templates.Get(id); err == nil {
+			return t, true
+		}
+	}
+	apiError(c, http.StatusNotFound, "not_found", "Recurring template not found")
+	return recurring.Template{}, false
+}
+
+func (s *Server) apiGetRecurring(c *gin.Context) {
+	if t, ok := s.currentTemplate(c); ok {
+		respond(c, http.StatusOK, recurringResource(t))
+	}
+}
+
+// apiUpdateRecurring replaces a template. Resuming a paused template does not
+// backfill the charges of the paused period, as with /recurring resume.
+func (s *Server) apiUpdateRecurring(c *gin.Context) {
+	cur, ok := s.currentTemplate(c)
+	if !ok || !precondition(c, recurringResource(cur)) {
+		return
+	}
+	var in RecurringInput
+	if !bindBody(c, &in) {
+		return
+	}
+	match := ifMatch(c)
+	t, err := in.template(cur.ID)
+	if err == nil {
+		t, err = s.templates.UpdateIf(t, func(stored recurring.Template) bool { return match(recurringResource(stored)) })
+	}
+	if errors.Is(err, recurring.ErrNotFound) {
+		apiError(c, http.StatusNotFound, "not_found", "Recurring template not found")
+		return
+	}
+	if errors.Is(err, recurring.ErrChanged) {
+		preconditionFailed(c)
+		return
+	}
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if in.Paused != cur.Paused {
+		if err := s.templates.SetPaused(t.ID, in.Paused, s.now()); err != nil {
+			apiError(c, http.StatusInternalServerError, "internal", "Failed to save template")
+			return
+		}
+	}
+	if t, err = s.templates.Get(t.ID); err != nil {
+		apiError(c, http.StatusNotFound, "not_found", "Recurring template not found")
+		return
+	}
+	respond(c, http.StatusOK, recurringResource(t))
+}
+
+func (s *Server) apiDeleteRecurring(c *gin.Context) {
+	cur, ok := s.currentTemplate(c)
+	if !ok || !precondition(c, recurringResource(cur)) {
+		return
+	}
+	match := ifMatch(c)
+	err := s.templates.DeleteIf(cur.ID, func(stored recurring.Template) bool { return match(recurringResource(stored)) })
+	if errors.Is(err, recurring.ErrChanged) {
+		preconditionFailed(c)
+		return
+	} else if err != nil {
+		apiError(c, http.StatusNotFound, "not_found", "Recurring template not found")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,422 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/budget"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/category"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/envelope"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/goals"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/idempotency"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/receipt"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/token"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	// New loads the templates and static files relative to the repository root.
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeBot accepts the initData "valid" only.
+type fakeBot struct{}
+
+func (fakeBot) HandleWebAppData(chatID int64, data string) error { return nil }
+func (fakeBot) Location() *time.Location                         { return time.UTC }
+func (fakeBot) VerifyInitData(initData string) (int64, error) {
+	if initData != "valid" {
+		return 0, errors.New("invalid Telegram init data")
+	}
+	return 1, nil
+}
+
+// testServer is a Server over empty stores in a temporary directory, with a
+// token of every scope.
+type testServer struct {
+	*Server
+	secrets map[token.Scope]string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ledger, err := data.New(path("data.csv"))
+	must(err)
+	templates, err := recurring.New(path("recurring.csv"))
+	must(err)
+	envelopes, err := envelope.New(path("envelopes.csv"), path("envelope_moves.csv"))
+	must(err)
+	goalStore, err := goals.New(path("goals.csv"), path("goal_contributions.csv"))
+	must(err)
+	tokens, err := token.New(path("tokens.csv"))
+	must(err)
+	keys, err := idempotency.New(path("idempotency_keys.csv"))
+	must(err)
+	categories, err := category.New(path("categories.csv"))
+	must(err)
+	receipts, err := receipt.New(path("receipts"))
+	must(err)
+
+	planner := budget.NewPlanner(ledger, templates, budget.Settings{})
+	s := &testServer{
+		Server:  New(ledger, fakeBot{}, templates, planner, envelopes, goalStore, tokens, keys, categories, receipts),
+		secrets: map[token.Scope]string{},
+	}
+	for _, scope := range []token.Scope{token.Read, token.Write, token.Admin} {
+		_, secret, err := tokens.Create(string(scope), scope, 1, time.Now())
+		must(err)
+		s.secrets[scope] = secret
+	}
+	return s
+}
+
+// do sends a request with the Bearer token of scope, if any, and extra headers
+// given as name, value pairs.
+func (s *testServer) do(method, path string, scope token.Scope, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if scope != "" {
+		req.Header.Set("Authorization", "Bearer "+s.secrets[scope])
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// decode unmarshals the response body, failing the test on invalid JSON.
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", w.Body.String(), err)
+	}
+}
+
+// errorCode returns the code of an API error envelope.
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var e APIError
+	decode(t, w, &e)
+	if e.Error.Message == "" {
+		t.Errorf("error %q has no message", e.Error.Code)
+	}
+	return e.Error.Code
+}
+
+const coffee = `{"date":"2025-08-01","category":"coffee","description":"flat white","amount":250}`
+
+func TestRequireScope(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		scope      token.Scope
+		auth       string // Authorization header instead of the scope's token
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "no token", method: http.MethodGet, path: "/transactions", wantStatus: http.StatusUnauthorized, wantCode: "unauthorized"},
+		{name: "unknown token", method: http.MethodGet, path: "/transactions", auth: "Bearer nope", wantStatus: http.StatusUnauthorized, wantCode: "unauthorized"},
+		{name: "read may query", method: http.MethodGet, path: "/transactions", scope: token.Read, wantStatus: http.StatusOK},
+		{name: "read may not add", method: http.MethodPost, path: "/transactions", scope: token.Read, wantStatus: http.StatusForbidden, wantCode: "forbidden"},
+		{name: "write may not change settings", method: http.MethodPut, path: "/settings", scope: token.Write, wantStatus: http.StatusForbidden, wantCode: "forbidden"},
+		{name: "write may add", method: http.MethodPost, path: "/transactions", scope: token.Write, wantStatus: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.auth != "" {
+				headers = []string{"Authorization", tt.auth}
+			}
+			w := s.do(tt.method, apiPrefix+tt.path, tt.scope, coffee, headers...)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantCode != "" {
+				if got := errorCode(t, w); got != tt.wantCode {
+					t.Errorf("error code = %q, want %q", got, tt.wantCode)
+				}
+			}
+			if tt.wantStatus == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+
+	// The OpenAPI document stays public.
+	if w := s.do(http.MethodGet, apiPrefix+"/openapi.json", "", ""); w.Code != http.StatusOK {
+		t.Errorf("GET /openapi.json status = %d, want 200", w.Code)
+	}
+}
+
+func TestBindBody(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+	tests := []struct {
+		name        string
+		body        string
+		wantMessage string
+	}{
+		{"malformed", `{"category":`, "Invalid request body"},
+		{"wrong type", `{"category":"coffee","amount":"a lot"}`, "Invalid request body"},
+		{"missing fields", `{"date":"2025-08-01"}`, "Missing required fields: category, amount"},
+		{"invalid date", `{"date":"01.08.2025","category":"coffee","amount":250}`, "Invalid date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodPost, apiPrefix+"/transactions", token.Write, tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+			}
+			var e APIError
+			decode(t, w, &e)
+			if e.Error.Code != "invalid_request" || !strings.HasPrefix(e.Error.Message, tt.wantMessage) {
+				t.Errorf("error = %+v, want invalid_request starting with %q", e.Error, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+	w := s.do(http.MethodPost, apiPrefix+"/transactions", token.Write, coffee)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want 201: %s", w.Code, w.Body)
+	}
+	var created TransactionResource
+	decode(t, w, &created)
+	path := w.Header().Get("Location")
+	if path != apiPrefix+"/transactions/"+created.ID {
+		t.Fatalf("Location = %q, want the new transaction", path)
+	}
+
+	w = s.do(http.MethodGet, path, token.Read, "")
+	tag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || tag == "" {
+		t.Fatalf("GET status = %d, ETag = %q; want 200 with an ETag", w.Code, tag)
+	}
+	if w := s.do(http.MethodGet, path, token.Read, "", "If-None-Match", tag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("GET with a matching If-None-Match: status = %d, body %q; want 304 without a body", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodGet, path, token.Read, "", "If-None-Match", `"stale"`); w.Code != http.StatusOK {
+		t.Errorf("GET with a stale If-None-Match: status = %d, want 200", w.Code)
+	}
+
+	tea := strings.Replace(coffee, "flat white", "green tea", 1)
+	w = s.do(http.MethodPut, path, token.Write, tea, "If-Match", `"stale"`)
+	if w.Code != http.StatusPreconditionFailed || errorCode(t, w) != "precondition_failed" {
+		t.Fatalf("PUT with a stale If-Match: status = %d, want 412 precondition_failed: %s", w.Code, w.Body)
+	}
+	w = s.do(http.MethodPut, path, token.Write, tea, "If-Match", tag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT with a matching If-Match: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if newTag := w.Header().Get("ETag"); newTag == tag || newTag == "" {
+		t.Errorf("ETag after PUT = %q, want a new one", newTag)
+	}
+
+	// The tag read before the PUT no longer matches.
+	if w := s.do(http.MethodDelete, path, token.Write, "", "If-Match", tag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with an outdated If-Match: status = %d, want 412", w.Code)
+	}
+	if w := s.do(http.MethodDelete, path, token.Write, "", "If-Match", "*"); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE with If-Match *: status = %d, want 204", w.Code)
+	}
+	if w := s.do(http.MethodGet, path, token.Read, ""); w.Code != http.StatusNotFound || errorCode(t, w) != "not_found" {
+		t.Errorf("GET after DELETE: status = %d, want 404 not_found", w.Code)
+	}
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+	var first, second TransactionResource
+	w := s.do(http.MethodPost, apiPrefix+"/transactions", token.Write, coffee, "Idempotency-Key", "key-1")
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first POST: status = %d, Idempotent-Replayed = %q; want 201 and none", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	decode(t, w, &first)
+
+	w = s.do(http.MethodPost, apiPrefix+"/transactions", token.Write, coffee, "Idempotency-Key", "key-1")
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("repeated POST: status = %d, Idempotent-Replayed = %q; want 201 and true", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	decode(t, w, &second)
+	if second.ID != first.ID {
+		t.Errorf("repeated POST returned %s, want the original %s", second.ID, first.ID)
+	}
+
+	if w := s.do(http.MethodPost, apiPrefix+"/transactions", token.Write, coffee, "Idempotency-Key", "key-2"); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("POST with another key: status = %d, want 201 without a replay", w.Code)
+	}
+	if n := len(s.data.GetAllTransactions()); n != 2 {
+		t.Errorf("ledger has %d transactions, want 2", n)
+	}
+
+	long := strings.Repeat("k", idempotency.MaxKeyLength+1)
+	if w := s.do(http.MethodPost, apiPrefix+"/transactions", token.Write, coffee, "Idempotency-Key", long); w.Code != http.StatusBadRequest {
+		t.Errorf("POST with a too long key: status = %d, want 400", w.Code)
+	}
+}
+
+func TestTransactionsBatch(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+	batch := `{"transactions":[
+		{"date":"2025-08-01","category":"coffee","amount":250,"idempotency_key":"a"},
+		{"date":"2025-08-01","category":"","amount":100,"idempotency_key":"b"},
+		{"date":"2025-08-02","category":"groceries","amount":1200,"idempotency_key":"c"}
+	]}`
+	type response struct {
+		Results    []BatchResult `json:"results"`
+		Created    int           `json:"created"`
+		Duplicates int           `json:"duplicates"`
+		Failed     int           `json:"failed"`
+	}
+	statuses := func(r response) string {
+		var res []string
+		for i, item := range r.Results {
+			if item.Index != i {
+				t.Errorf("result %d has index %d", i, item.Index)
+			}
+			res = append(res, item.Status)
+		}
+		return strings.Join(res, ",")
+	}
+
+	w := s.do(http.MethodPost, "/expenses/transactions:batch", "", batch, initDataHeader, "valid")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var got response
+	decode(t, w, &got)
+	if s := statuses(got); s != "created,error,created" || got.Created != 2 || got.Failed != 1 {
+		t.Errorf("results = %s, created %d, failed %d; want created,error,created, 2, 1", s, got.Created, got.Failed)
+	}
+	if got.Results[1].Error == "" {
+		t.Error("invalid item has no error message")
+	}
+
+	// A batch resent after a lost response adds nothing.
+	w = s.do(http.MethodPost, "/expenses/transactions:batch", "", batch, initDataHeader, "valid")
+	got = response{}
+	decode(t, w, &got)
+	if s := statuses(got); s != "duplicate,error,duplicate" || got.Duplicates != 2 {
+		t.Errorf("resent results = %s, duplicates %d; want duplicate,error,duplicate, 2", s, got.Duplicates)
+	}
+	if n := len(s.data.GetAllTransactions()); n != 2 {
+		t.Errorf("ledger has %d transactions, want 2", n)
+	}
+
+	if w := s.do(http.MethodPost, "/expenses/transactions:purge", "", batch, initDataHeader, "valid"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown action: status = %d, want 404", w.Code)
+	}
+
+	// Without a key a resent item would be added twice, so the batch is refused.
+	unkeyed := `{"transactions":[{"date":"2025-08-03","category":"coffee","amount":250,"idempotency_key":"d"},{"date":"2025-08-03","category":"coffee","amount":250}]}`
+	if w := s.do(http.MethodPost, "/expenses/transactions:batch", "", unkeyed, initDataHeader, "valid"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "idempotency_key") {
+		t.Errorf("item without a key: status = %d, body %s; want 400 naming idempotency_key", w.Code, w.Body)
+	}
+	for _, initData := range []string{"", "forged"} {
+		if w := s.do(http.MethodPost, "/expenses/transactions:batch", "", batch, initDataHeader, initData); w.Code != http.StatusUnauthorized {
+			t.Errorf("batch with initData %q: status = %d, want 401", initData, w.Code)
+		}
+	}
+	if n := len(s.data.GetAllTransactions()); n != 2 {
+		t.Errorf("ledger has %d transactions after the refused batches, want 2", n)
+	}
+}
+
+func TestRequireInitData(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+	tx, err := s.data.CreateTransaction(data.Transaction{Date: "2025-08-01", Category: "coffee", Amount: 250})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/expenses/transactions/" + tx.ID
+	tea := strings.Replace(coffee, "flat white", "green tea", 1)
+
+	for _, initData := range []string{"", "forged"} {
+		if w := s.do(http.MethodPut, path, "", tea, initDataHeader, initData); w.Code != http.StatusUnauthorized {
+			t.Errorf("PUT with initData %q: status = %d, want 401", initData, w.Code)
+		}
+		if w := s.do(http.MethodDelete, path, "", "", initDataHeader, initData); w.Code != http.StatusUnauthorized {
+			t.Errorf("DELETE with initData %q: status = %d, want 401", initData, w.Code)
+		}
+		if w := s.do(http.MethodGet, path+"/receipts", "", "", initDataHeader, initData); w.Code != http.StatusUnauthorized {
+			t.Errorf("GET receipts with initData %q: status = %d, want 401", initData, w.Code)
+		}
+	}
+	if got, _ := s.data.GetTransaction(tx.ID); got.Description != "" {
+		t.Errorf("unauthenticated PUT changed the description to %q", got.Description)
+	}
+
+	if w := s.do(http.MethodPut, path, "", tea, initDataHeader, "valid"); w.Code != http.StatusOK {
+		t.Errorf("PUT with valid initData: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodDelete, path, "", "", initDataHeader, "valid"); w.Code != http.StatusOK && w.Code != http.StatusNoContent {
+		t.Errorf("DELETE with valid initData: status = %d, want success: %s", w.Code, w.Body)
+	}
+
+	// Goal writes
+	goal := `{"name":"vacation","target":60000,"deadline":"2027-06-01"}`
+	if w := s.do(http.MethodPost, "/expenses/goals", "", goal); w.Code != http.StatusUnauthorized {
+		t.Errorf("POST /goals without initData: status = %d, want 401", w.Code)
+	}
+	if w := s.do(http.MethodPost, "/expenses/goals", "", goal, initDataHeader, "valid"); w.Code != http.StatusOK {
+		t.Fatalf("POST /goals with valid initData: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	id := strconv.Itoa(s.goals.List()[0].ID)
+	if w := s.do(http.MethodPost, "/expenses/goals/"+id+"/contribute", "", `{"amount":500}`, initDataHeader, "forged"); w.Code != http.StatusUnauthorized {
+		t.Errorf("contribute with forged initData: status = %d, want 401", w.Code)
+	}
+	if w := s.do(http.MethodDelete, "/expenses/goals/"+id, "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("DELETE /goals without initData: status = %d, want 401", w.Code)
+	}
+	if n := len(s.goals.List()); n != 1 {
+		t.Errorf("%d goals after the refused delete, want 1", n)
+	}
+	// Envelope moves; envelope budgeting is off here, so an accepted move gets a 400
+	move := `{"amount":500,"from":"cafes","to":"groceries"}`
+	if w := s.do(http.MethodPost, "/expenses/envelopes/move", "", move); w.Code != http.StatusUnauthorized {
+		t.Errorf("envelope move without initData: status = %d, want 401", w.Code)
+	}
+	if w := s.do(http.MethodPost, "/expenses/envelopes/move", "", move, initDataHeader, "valid"); w.Code == http.StatusUnauthorized {
+		t.Errorf("envelope move with valid initData: status = %d, want it past the check", w.Code)
+	}
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/token"
+	"github.com/gin-gonic/gin"
+)
+
+// tokenKey is the gin context key of the authenticated token.
+const tokenKey = "token"
+
+// requireScope authenticates a request by its Bearer token and checks that the
+// token has the required scope. Tokens are created with /token in the bot.
+func (s *Server) requireScope(required token.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, secret, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || secret == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="expenses"`)
+			apiError(c, http.StatusUnauthorized, "unauthorized", "Missing Bearer token, create one with /token in the bot")
+			return
+		}
+		t, err := s.tokens.Authenticate(strings.TrimSpace(secret), time.Now())
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="expenses", error="invalid_token"`)
+			apiError(c, http.StatusUnauthorized, "unauthorized", "Invalid or revoked token")
+			return
+		}
+		if !t.Scope.Allows(required) {
+			apiError(c, http.StatusForbidden, "forbidden", "Token scope "+string(t.Scope)+" does not allow this, "+string(required)+" is required")
+			return
+		}
+		c.Set(tokenKey, t)
+		c.Next()
+	}
+}
+
+// initDataHeader carries the initData of the Mini App launch, see requireInitData.
+const initDataHeader = "X-Telegram-Init-Data"
+
+// requireInitData authenticates a Mini App request by the initData Telegram
+// signed with the bot token when the app was opened from the chat.
+func (s *Server) requireInitData() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := s.bot.VerifyInitData(c.GetHeader(initDataHeader)); err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
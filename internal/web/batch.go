@@ -0,0 +1,91 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxBatchSize = 500
+
+type BatchRequest struct {
+	Transactions []TransactionRequest `json:"transactions"`
+}
+
+// BatchResult is the outcome of one item of a batch, in request order.
+type BatchResult struct {
+	Index       int         `json:"index"`
+	Status      string      `json:"status"` // created, duplicate or error
+	Transaction interface{} `json:"transaction,omitempty"`
+	Error       string      `json:"error,omitempty"`
+}
+
+func (s *Server) handleTransactionsAction(c *gin.Context) {
+	if c.Param("action") != ":batch" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	s.handleTransactionsBatch(c)
+}
+
+// handleTransactionsBatch adds the expenses queued by the Mini App while offline.
+// Every item must carry its idempotency_key: a batch resent after a lost
+// response reports the items already added as duplicates instead of adding
+// them again. Items are independent, one invalid item does not fail the rest.
+// The route needs the signed initData, see requireInitData.
+func (s *Server) handleTransactionsBatch(c *gin.Context) {
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if len(req.Transactions) > maxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many transactions, send at most 500 per batch"})
+		return
+	}
+	for i, item := range req.Transactions {
+		if item.IdempotencyKey == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Transaction %d has no idempotency_key, every item of a batch needs one", i)})
+			return
+		}
+	}
+
+	results := make([]BatchResult, len(req.Transactions))
+	var created, duplicates, failed int
+	for i, item := range req.Transactions {
+		results[i].Index = i
+		if err := item.validate(); err != nil {
+			results[i].Status, results[i].Error = "error", err.Error()
+			failed++
+			continue
+		}
+		tx, replayed, err := s.createTransaction(item.transaction(), item.IdempotencyKey)
+		switch {
+		case err != nil:
+			results[i].Status, results[i].Error = "error", "Failed to save transaction"
+			failed++
+		case replayed:
+			results[i].Status, results[i].Transaction = "duplicate", tx
+			duplicates++
+		default:
+			results[i].Status, results[i].Transaction = "created", tx
+			created++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":    results,
+		"created":    created,
+		"duplicates": duplicates,
+		"failed":     failed,
+	})
+}
+
+// handleServiceWorker serves the service worker from the app root, so its scope
+// covers the pages under /expenses/ and not only the static files.
+func (s *Server) handleServiceWorker(c *gin.Context) {
+	c.Header("Content-Type", "application/javascript")
+	c.Header("Cache-Control", "no-cache")
+	c.File("./static/sw.js")
+}
@@ -0,0 +1,239 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/category"
+	"github.com/gin-gonic/gin"
+)
+
+// categoryOption is an entry of the category picker of the Mini App form.
+type categoryOption struct {
+	ID    string
+	Label string // indented with no-break spaces by depth, so subcategories appear under their parent
+}
+
+// categoryOptions returns the active categories for the picker.
+func (s *Server) categoryOptions() []categoryOption {
+	depth := map[string]int{}
+	var res []categoryOption
+	for _, c := range s.categories.List(false) {
+		if c.Parent != "" {
+			depth[c.ID] = depth[c.Parent] + 1
+		}
+		res = append(res, categoryOption{ID: c.ID, Label: strings.Repeat("\u00a0\u00a0\u00a0", depth[c.ID]) + c.Label()})
+	}
+	return res
+}
+
+// categoryUsage is how a category is used in the ledger.
+type categoryUsage struct {
+	Count int
+	Total float64
+	Last  string
+	Name  string // spelling of the latest use
+}
+
+// categoryUsages groups the ledger, split lines apart, by managed category ID,
+// or by lower-cased spelling for categories that are not managed.
+func (s *Server) categoryUsages() map[string]*categoryUsage {
+	res := map[string]*categoryUsage{}
+	for _, tx := range s.data.GetAllTransactions() {
+		for _, p := range tx.Parts() {
+			k := strings.ToLower(s.categories.Normalize(p.Category))
+			u, ok := res[k]
+			if !ok {
+				u = &categoryUsage{}
+				res[k] = u
+			}
+			u.Count++
+			u.Total += p.Amount
+			if tx.Date >= u.Last {
+				u.Name, u.Last = p.Category, tx.Date
+			}
+		}
+	}
+	return res
+}
+
+func (s *Server) categoryResource(c category.Category, usages map[string]*categoryUsage) CategoryResource {
+	fixed := s.planner.Settings().FixedCategories
+	res := CategoryResource{
+		CategoryInput: CategoryInput{
+			ID:       c.ID,
+			Name:     c.Name,
+			Emoji:    c.Emoji,
+			Color:    c.Color,
+			Parent:   c.Parent,
+			Archived: c.Archived,
+			Aliases:  c.Aliases,
+		},
+		Managed: true,
+		Fixed:   fixed[c.ID] || fixed[strings.ToLower(c.Name)],
+	}
+	// Not Settings.FixedCategory, which resolves through the store and would
+	// lock it again under UpdateIf
+	for _, alias := range c.Aliases {
+		res.Fixed = res.Fixed || fixed[alias]
+	}
+	if res.Aliases == nil {
+		res.Aliases = []string{}
+	}
+	if u := usages[c.ID]; u != nil {
+		res.Count, res.Total, res.Last = u.Count, u.Total, u.Last
+	}
+	return res
+}
+
+func (in CategoryInput) category() category.Category {
+	return category.Category{
+		ID:       in.ID,
+		Name:     in.Name,
+		Emoji:    in.Emoji,
+		Color:    in.Color,
+		Parent:   in.Parent,
+		Archived: in.Archived,
+		Aliases:  in.Aliases,
+	}
+}
+
+// apiListCategories lists the managed categories as a tree, followed by the
+// other spellings found in the ledger, by name, so they can be merged.
+func (s *Server) apiListCategories(c *gin.Context) {
+	includeArchived := c.Query("include_archived") == "true"
+	usages := s.categoryUsages()
+	res := []CategoryResource{}
+	for _, cat := range s.categories.List(true) {
+		if !cat.Archived || includeArchived {
+			res = append(res, s.categoryResource(cat, usages))
+		}
+		delete(usages, cat.ID)
+	}
+
+	fixed := s.planner.Settings().FixedCategories
+	var other []CategoryResource
+	for k, u := range usages {
+		other = append(other, CategoryResource{
+			CategoryInput: CategoryInput{ID: u.Name, Name: u.Name, Aliases: []string{}},
+			Count:         u.Count,
+			Total:         u.Total,
+			Fixed:         fixed[k],
+			Last:          u.Last,
+		})
+	}
+	sort.Slice(other, func(i, j int) bool { return strings.ToLower(other[i].Name) < strings.ToLower(other[j].Name) })
+	respond(c, http.StatusOK, append(res, other...))
+}
+
+func (s *Server) apiCreateCategory(c *gin.Context) {
+	var in CategoryInput
+	if !bindBody(c, &in) {
+		return
+	}
+	cat, err := s.categories.Create(in.category())
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	c.Header("Location", apiPrefix+"/categories/"+cat.ID)
+	respond(c, http.StatusCreated, s.categoryResource(cat, s.categoryUsages()))
+}
+
+// currentCategory loads the category named in the path, writing a 404 if it is
+// not managed.
+func (s *Server) currentCategory(c *gin.Context) (CategoryResource, bool) {
+	cat, err := s.categories.Get(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "not_found", "Category not found")
+		return CategoryResource{}, false
+	}
+	return s.categoryResource(cat, s.categoryUsages()), true
+}
+
+func (s *Server) apiGetCategory(c *gin.Context) {
+	if cur, ok := s.currentCategory(c); ok {
+		respond(c, http.StatusOK, cur)
+	}
+}
+
+func (s *Server) apiUpdateCategory(c *gin.Context) {
+	cur, ok := s.currentCategory(c)
+	if !ok || !precondition(c, cur) {
+		return
+	}
+	var in CategoryInput
+	if !bindBody(c, &in) {
+		return
+	}
+	if strings.ToLower(strings.TrimSpace(in.ID)) != cur.ID {
+		apiError(c, http.StatusBadRequest, "invalid_request", "The ID cannot change; merge into a new category instead")
+		return
+	}
+	match, usages := ifMatch(c), s.categoryUsages()
+	cat, err := s.categories.UpdateIf(in.category(), func(stored category.Category) bool { return match(s.categoryResource(stored, usages)) })
+	if errors.Is(err, category.ErrNotFound) {
+		apiError(c, http.StatusNotFound, "not_found", "Category not found")
+		return
+	}
+	if errors.Is(err, category.ErrChanged) {
+		preconditionFailed(c)
+		return
+	}
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	respond(c, http.StatusOK, s.categoryResource(cat, s.categoryUsages()))
+}
+
+// apiDeleteCategory only deletes categories without transactions, so that
+// history never refers to a category that is gone; archive or merge it instead.
+func (s *Server) apiDeleteCategory(c *gin.Context) {
+	cur, ok := s.currentCategory(c)
+	if !ok || !precondition(c, cur) {
+		return
+	}
+	if cur.Count > 0 {
+		apiError(c, http.StatusConflict, "conflict", "Category is used by transactions; merge or archive it instead")
+		return
+	}
+	match, usages := ifMatch(c), s.categoryUsages()
+	err := s.categories.DeleteIf(cur.ID, func(stored category.Category) bool { return match(s.categoryResource(stored, usages)) })
+	switch {
+	case errors.Is(err, category.ErrChanged):
+		preconditionFailed(c)
+	case errors.Is(err, category.ErrNotFound):
+		apiError(c, http.StatusNotFound, "not_found", "Category not found")
+	case errors.Is(err, category.ErrHasChildren):
+		apiError(c, http.StatusConflict, "conflict", "Category has subcategories; move or delete them first")
+	case err != nil:
+		apiError(c, http.StatusInternalServerError, "internal", "Failed to delete category")
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (s *Server) apiMergeCategories(c *gin.Context) {
+	var in CategoryMerge
+	if !bindBody(c, &in) {
+		return
+	}
+	n, err := s.categories.Merge(s.data, in.Sources, in.Target, s.envelopes, s.templates)
+	if errors.Is(err, category.ErrNotFound) {
+		apiError(c, http.StatusNotFound, "not_found", "Target category not found")
+		return
+	}
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	cat, err := s.categories.Get(in.Target)
+	if err != nil {
+		apiError(c, http.StatusNotFound, "not_found", "Target category not found")
+		return
+	}
+	respond(c, http.StatusOK, CategoryMergeResult{Target: s.categoryResource(cat, s.categoryUsages()), Rewritten: n})
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/envelope"
+	"github.com/gin-gonic/gin"
+)
+
+type MoveRequest struct {
+	Amount float64 `json:"amount"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+}
+
+func (s *Server) handleEnvelopes(c *gin.Context) {
+	c.HTML(http.StatusOK, "envelopes.html", gin.H{
+		"title": "Envelopes",
+	})
+}
+
+// handleEnvelopesData returns envelope balances for ?date=YYYY-MM-DD (default today).
+func (s *Server) handleEnvelopesData(c *gin.Context) {
+	if !s.planner.Settings().Envelopes() {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	date := s.now()
+	if d, err := time.Parse("2006-01-02", c.Query("date")); err == nil {
+		date = d
+	}
+
+	start, next := s.planner.Cycle(date)
+	sum := s.envelopes.Summary(s.data.GetAllTransactions(), s.planner.Cycle, date, s.planner.CycleBudget(start, next, s.planner.MonthlyBudget()))
+
+	type item struct {
+		Name       string   `json:"name"`
+		Categories []string `json:"categories"`
+		Allocation float64  `json:"allocation"`
+		Allocated  float64  `json:"allocated"`
+		Moved      float64  `json:"moved"`
+		Spent      float64  `json:"spent"`
+		CycleSpent float64  `json:"cycle_spent"`
+		Balance    float64  `json:"balance"`
+	}
+	items := make([]item, 0, len(sum.Envelopes))
+	for _, e := range sum.Envelopes {
+		items = append(items, item{
+			Name:       e.Name,
+			Categories: e.Categories,
+			Allocation: e.Allocation,
+			Allocated:  e.Allocated,
+			Moved:      e.Moved,
+			Spent:      e.Spent,
+			CycleSpent: e.CycleSpent,
+			Balance:    e.Balance,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":     true,
+		"date":        date.Format("2006-01-02"),
+		"cycleStart":  sum.CycleStart.Format("2006-01-02"),
+		"cycleEnd":    sum.NextCycleStart.AddDate(0, 0, -1).Format("2006-01-02"),
+		"budget":      sum.Budget,
+		"unallocated": sum.Unallocated,
+		"unassigned":  sum.Unassigned,
+		"envelopes":   items,
+	})
+}
+
+func (s *Server) handleEnvelopeMove(c *gin.Context) {
+	if !s.planner.Settings().Envelopes() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Envelope budgeting is off"})
+		return
+	}
+	var req MoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	err := s.envelopes.Move(envelope.Move{Date: s.now().Format("2006-01-02"), Amount: req.Amount, From: req.From, To: req.To})
+	if errors.Is(err, envelope.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
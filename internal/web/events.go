@@ -0,0 +1,52 @@
+package web
+
+import (
+	"io"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval keeps idle streams from being cut by proxies.
+const heartbeatInterval = 25 * time.Second
+
+// ChangeEvent is the payload of a ledger change event.
+type ChangeEvent struct {
+	Seq          uint64             `json:"seq"`
+	Kind         data.ChangeKind    `json:"kind,omitempty"`
+	Transactions []data.Transaction `json:"transactions,omitempty"`
+}
+
+// handleEvents streams ledger changes as Server-Sent Events, so open pages
+// refresh when someone else adds an expense. The event name is the change kind
+// (add, update, delete or replace). The stream starts with a ready event and
+// ends when the client falls too far behind; EventSource then reconnects and
+// the page should reload its data.
+func (s *Server) handleEvents(c *gin.Context) {
+	changes, stop := s.data.Changes().Subscribe()
+	defer stop()
+
+	c.Header("Cache-Control", "no-cache")
+	// Nginx buffers responses by default, which would hold events back
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("ready", ChangeEvent{Seq: s.data.Changes().Seq()})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case change, ok := <-changes:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(change.Kind), ChangeEvent{Seq: change.Seq, Kind: change.Kind, Transactions: change.Transactions})
+		case <-heartbeat.C:
+			c.SSEvent("ping", ChangeEvent{Seq: s.data.Changes().Seq()})
+		}
+		return true
+	})
+}
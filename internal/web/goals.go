@@ -0,0 +1,120 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/goals"
+	"github.com/gin-gonic/gin"
+)
+
+type GoalRequest struct {
+	Name     string  `json:"name"`
+	Target   float64 `json:"target"`
+	Deadline string  `json:"deadline"`
+}
+
+type ContributionRequest struct {
+	Amount float64 `json:"amount"`
+}
+
+// handleGoals returns the progress of all goals.
+func (s *Server) handleGoals(c *gin.Context) {
+	today := s.now()
+	surpluses := goals.SurplusFrom(s.planner.Results(today, s.planner.MonthlyBudget()))
+
+	type item struct {
+		ID          int     `json:"id"`
+		Name        string  `json:"name"`
+		Target      float64 `json:"target"`
+		Deadline    string  `json:"deadline"`
+		Manual      float64 `json:"manual"`
+		FromSurplus float64 `json:"from_surplus"`
+		Saved       float64 `json:"saved"`
+		Remaining   float64 `json:"remaining"`
+		CyclesLeft  int     `json:"cycles_left"`
+		PerCycle    float64 `json:"per_cycle"`
+		AvgPerCycle float64 `json:"avg_per_cycle"`
+		Projected   string  `json:"projected,omitempty"`
+		Done        bool    `json:"done"`
+		OnTrack     bool    `json:"on_track"`
+	}
+	progress := s.goals.Progress(surpluses, s.planner.Cycle, today)
+	items := make([]item, 0, len(progress))
+	for _, p := range progress {
+		it := item{
+			ID:          p.ID,
+			Name:        p.Name,
+			Target:      p.Target,
+			Deadline:    p.Deadline,
+			Manual:      p.Manual,
+			FromSurplus: p.FromSurplus,
+			Saved:       p.Saved,
+			Remaining:   p.Remaining,
+			CyclesLeft:  p.CyclesLeft,
+			PerCycle:    p.PerCycle,
+			AvgPerCycle: p.AvgPerCycle,
+			Done:        p.Done,
+			OnTrack:     p.OnTrack,
+		}
+		if !p.Projected.IsZero() {
+			it.Projected = p.Projected.Format("2006-01-02")
+		}
+		items = append(items, it)
+	}
+	c.JSON(http.StatusOK, gin.H{"goals": items})
+}
+
+func (s *Server) handleAddGoal(c *gin.Context) {
+	var req GoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	g, err := s.goals.Add(goals.Goal{Name: req.Name, Target: req.Target, Deadline: req.Deadline, Created: s.now().Format("2006-01-02")})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "id": g.ID})
+}
+
+func (s *Server) handleContribute(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goal ID"})
+		return
+	}
+	var req ContributionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	err = s.goals.Contribute(goals.Contribution{Date: s.now().Format("2006-01-02"), GoalID: id, Amount: req.Amount})
+	if errors.Is(err, goals.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+func (s *Server) handleDeleteGoal(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goal ID"})
+		return
+	}
+	if err := s.goals.Delete(id); errors.Is(err, goals.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete goal"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
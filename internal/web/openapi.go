@@ -0,0 +1,247 @@
+package web
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIDoc is generated on first use; the route table does not change at runtime.
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  map[string]interface{}
+)
+
+// handleOpenAPI serves the OpenAPI 3 document of the v1 API.
+func (s *Server) handleOpenAPI(c *gin.Context) {
+	openAPIOnce.Do(func() { openAPIDoc = openAPI(s.apiRoutes()) })
+	c.JSON(http.StatusOK, openAPIDoc)
+}
+
+// openAPI builds the OpenAPI document from the route table. Request and response
+// schemas are reflected from the DTO types and their json tags; a field tagged
+// binding:"required" is required.
+func openAPI(routes []apiRoute) map[string]interface{} {
+	g := schemaGen{schemas: map[string]interface{}{}}
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content":     jsonContent(g.schema(reflect.TypeOf(APIError{}))),
+	}
+
+	paths := map[string]map[string]interface{}{}
+	for _, rt := range routes {
+		path, params := openAPIPath(rt.Path)
+		for _, p := range rt.Params {
+			in := p.In
+			if in == "" {
+				in = "query"
+			}
+			params = append(params, map[string]interface{}{
+				"name":        p.Name,
+				"in":          in,
+				"description": p.Description,
+				"schema":      map[string]interface{}{"type": p.Type},
+			})
+		}
+
+		success := map[string]interface{}{"description": http.StatusText(rt.Status)}
+		if rt.Download {
+			success["content"] = map[string]interface{}{"*/*": map[string]interface{}{"schema": binarySchema}}
+			success["headers"] = map[string]interface{}{
+				"ETag": map[string]interface{}{"description": "SHA-256 of the content, for If-None-Match", "schema": map[string]interface{}{"type": "string"}},
+			}
+		}
+		if rt.Response != nil {
+			success["content"] = jsonContent(g.schema(reflect.TypeOf(rt.Response)))
+			success["headers"] = map[string]interface{}{
+				"ETag": map[string]interface{}{"description": "Version of the representation, for If-Match and If-None-Match", "schema": map[string]interface{}{"type": "string"}},
+			}
+		}
+		op := map[string]interface{}{
+			"summary":     rt.Summary,
+			"description": "Requires a token with the " + string(rt.scope()) + " scope.",
+			"operationId": operationID(rt),
+			"security":    []interface{}{map[string]interface{}{"bearerAuth": []string{}}},
+			"responses": map[string]interface{}{
+				strconv.Itoa(rt.Status): success,
+				"default":               errorResponse,
+			},
+		}
+		if rt.Method == http.MethodPut || rt.Method == http.MethodDelete {
+			params = append(params, map[string]interface{}{
+				"name":        "If-Match",
+				"in":          "header",
+				"description": "ETag of the version being changed; 412 if the resource has changed since",
+				"schema":      map[string]interface{}{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if rt.Body != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(g.schema(reflect.TypeOf(rt.Body))),
+			}
+		}
+		if rt.File != "" {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{"multipart/form-data": map[string]interface{}{"schema": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{rt.File: binarySchema},
+					"required":   []string{rt.File},
+				}}},
+			}
+		}
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(rt.Method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Expenses Tracker API",
+			"version": "1",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": apiPrefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "API token created with /token in the Telegram bot; scopes are read, write and admin",
+				},
+			},
+		},
+	}
+}
+
+// openAPIPath converts a gin path to OpenAPI syntax and returns its path parameters.
+func openAPIPath(path string) (string, []interface{}) {
+	var params []interface{}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if name, ok := strings.CutPrefix(seg, ":"); ok {
+			segments[i] = "{" + name + "}"
+			params = append(params, map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID names an operation after its method and path, e.g. getTransactionsById.
+func operationID(rt apiRoute) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(rt.Method))
+	for _, seg := range strings.Split(rt.Path, "/") {
+		if name, ok := strings.CutPrefix(seg, ":"); ok {
+			seg = "by_" + name
+		}
+		for _, part := range strings.Split(seg, "_") {
+			if part != "" {
+				sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+			}
+		}
+	}
+	return sb.String()
+}
+
+// binarySchema describes file content in requests and responses.
+var binarySchema = map[string]interface{}{"type": "string", "format": "binary"}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// schemaGen reflects Go types into JSON schemas. Named structs become components
+// referenced by name.
+type schemaGen struct {
+	schemas map[string]interface{}
+}
+
+func (g *schemaGen) schema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := g.schema(t.Elem())
+		if _, ref := s["$ref"]; ref {
+			return map[string]interface{}{"allOf": []interface{}{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = nil // placeholder for recursive types
+			g.schemas[t.Name()] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]interface{}{}
+}
+
+func (g *schemaGen) object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	g.fields(t, props, &required)
+	s := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// fields adds the JSON properties of struct t, flattening embedded structs like
+// encoding/json does.
+func (g *schemaGen) fields(t reflect.Type, props map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.fields(f.Type, props, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+		if strings.Contains(f.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
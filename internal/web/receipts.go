@@ -0,0 +1,208 @@
+package web
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/receipt"
+	"github.com/gin-gonic/gin"
+)
+
+// uploadOverhead leaves room for the multipart framing around a receipt file.
+const uploadOverhead = 1 << 20
+
+func receiptResource(r receipt.Receipt, base string) ReceiptResource {
+	return ReceiptResource{
+		Hash:        r.Hash,
+		Name:        r.FileName(),
+		ContentType: r.ContentType,
+		Size:        r.Size,
+		Added:       r.Added,
+		URL:         base + "/transactions/" + r.TransactionID + "/receipts/" + r.Hash,
+	}
+}
+
+func (s *Server) receiptResources(txID, base string) []ReceiptResource {
+	res := []ReceiptResource{}
+	for _, r := range s.receipts.List(txID) {
+		res = append(res, receiptResource(r, base))
+	}
+	return res
+}
+
+// attachReceipt stores the file uploaded in the "file" form field of c with the
+// transaction. On failure it returns the status and a message for the client.
+func (s *Server) attachReceipt(c *gin.Context, txID string) (receipt.Receipt, int, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, receipt.MaxSize+uploadOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		return receipt.Receipt{}, http.StatusBadRequest, errors.New("Expected a receipt file in the file field")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return receipt.Receipt{}, http.StatusBadRequest, errors.New("Failed to read the uploaded file")
+	}
+	defer file.Close()
+
+	r, err := s.receipts.Attach(txID, header.Filename, header.Header.Get("Content-Type"), file, time.Now())
+	switch {
+	case errors.Is(err, receipt.ErrUnsupported):
+		return receipt.Receipt{}, http.StatusUnsupportedMediaType, err
+	case errors.Is(err, receipt.ErrTooLarge):
+		return receipt.Receipt{}, http.StatusRequestEntityTooLarge, err
+	case err != nil:
+		log.Printf("Failed to store receipt: %v", err)
+		return receipt.Receipt{}, http.StatusInternalServerError, errors.New("Failed to save receipt")
+	}
+	return r, http.StatusCreated, nil
+}
+
+// receiptETag is the entity tag of a receipt: its content never changes, so
+// the hash is a strong one.
+func receiptETag(r receipt.Receipt) string {
+	return `"` + r.Hash + `"`
+}
+
+// serveReceipt writes the content of a receipt. It returns an error, for the
+// caller to report, only if nothing was written.
+func (s *Server) serveReceipt(c *gin.Context, r receipt.Receipt) error {
+	tag := receiptETag(r)
+	if matchesETag(c.GetHeader("If-None-Match"), tag) {
+		c.Header("ETag", tag)
+		c.Status(http.StatusNotModified)
+		return nil
+	}
+	f, err := os.Open(s.receipts.Path(r))
+	if err != nil {
+		log.Printf("Failed to open receipt %s: %v", r.Hash, err)
+		return errors.New("Receipt file is missing")
+	}
+	defer f.Close()
+	c.Header("ETag", tag)
+	c.DataFromReader(http.StatusOK, r.Size, r.ContentType, f, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("inline", map[string]string{"filename": r.FileName()}),
+	})
+	return nil
+}
+
+// detachReceipts removes the receipts of a deleted transaction. The deletion
+// itself has succeeded, so a failure is only logged.
+func (s *Server) detachReceipts(txID string) {
+	if err := s.receipts.DetachAll(txID); err != nil {
+		log.Printf("Failed to remove receipts of deleted transaction %s: %v", txID, err)
+	}
+}
+
+// --- Mini App ---
+
+func (s *Server) handleListReceipts(c *gin.Context) {
+	if _, err := s.data.GetTransaction(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"receipts": s.receiptResources(c.Param("id"), "/expenses")})
+}
+
+func (s *Server) handleAttachReceipt(c *gin.Context) {
+	tx, err := s.data.GetTransaction(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+	r, status, err := s.attachReceipt(c, tx.ID)
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Receipt attached", "receipt": receiptResource(r, "/expenses")})
+}
+
+func (s *Server) handleGetReceipt(c *gin.Context) {
+	r, err := s.receipts.Get(c.Param("id"), c.Param("hash"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Receipt not found"})
+		return
+	}
+	if err := s.serveReceipt(c, r); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (s *Server) handleDeleteReceipt(c *gin.Context) {
+	if err := s.receipts.Detach(c.Param("id"), c.Param("hash")); errors.Is(err, receipt.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Receipt not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove receipt"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Receipt removed"})
+}
+
+// --- API ---
+
+func (s *Server) apiListReceipts(c *gin.Context) {
+	if cur, ok := s.currentTransaction(c); ok {
+		respond(c, http.StatusOK, s.receiptResources(cur.ID, apiPrefix))
+	}
+}
+
+func (s *Server) apiAttachReceipt(c *gin.Context) {
+	cur, ok := s.currentTransaction(c)
+	if !ok {
+		return
+	}
+	r, status, err := s.attachReceipt(c, cur.ID)
+	if err != nil {
+		code := "invalid_request"
+		if status == http.StatusInternalServerError {
+			code = "internal"
+		}
+		apiError(c, status, code, err.Error())
+		return
+	}
+	res := receiptResource(r, apiPrefix)
+	c.Header("Location", res.URL)
+	respond(c, http.StatusCreated, res)
+}
+
+// currentReceipt loads the receipt named in the path, writing a 404 if the
+// transaction has no such receipt.
+func (s *Server) currentReceipt(c *gin.Context) (receipt.Receipt, bool) {
+	r, err := s.receipts.Get(c.Param("id"), c.Param("hash"))
+	if err != nil {
+		apiError(c, http.StatusNotFound, "not_found", "Receipt not found")
+		return receipt.Receipt{}, false
+	}
+	return r, true
+}
+
+func (s *Server) apiGetReceipt(c *gin.Context) {
+	r, ok := s.currentReceipt(c)
+	if !ok {
+		return
+	}
+	if err := s.serveReceipt(c, r); err != nil {
+		apiError(c, http.StatusInternalServerError, "internal", err.Error())
+	}
+}
+
+func (s *Server) apiDeleteReceipt(c *gin.Context) {
+	r, ok := s.currentReceipt(c)
+	if !ok {
+		return
+	}
+	if h := c.GetHeader("If-Match"); h != "" && !matchesETag(h, receiptETag(r)) {
+		apiError(c, http.StatusPreconditionFailed, "precondition_failed", "Resource has changed, fetch it again and retry")
+		return
+	}
+	if err := s.receipts.Detach(r.TransactionID, r.Hash); err != nil {
+		apiError(c, http.StatusInternalServerError, "internal", "Failed to remove receipt")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
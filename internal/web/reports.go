@@ -0,0 +1,99 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/chart"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/report"
+	"github.com/gin-gonic/gin"
+)
+
+// handleReports returns a period summary.
+// Query: period=month|cycle|year (default month); month=YYYY-MM, n=<cycles back> or year=YYYY;
+// category and tag narrow it down like the transaction query. With format=svg
+// it returns the category pie of the summary as an SVG image instead.
+func (s *Server) handleReports(c *gin.Context) {
+	date := s.now()
+	kind := c.DefaultQuery("period", report.KindMonth)
+	switch kind {
+	case report.KindMonth:
+		if v := c.Query("month"); v != "" {
+			t, err := time.Parse("2006-01", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid month, expected YYYY-MM"})
+				return
+			}
+			date = t
+		}
+	case report.KindCycle:
+		if v := c.Query("n"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || n > report.MaxCyclesBack {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid n, expected the number of cycles back, 0 to %d", report.MaxCyclesBack)})
+				return
+			}
+			for i := 0; i < n; i++ {
+				start, _ := s.planner.Cycle(date)
+				date = start.AddDate(0, 0, -1)
+			}
+		}
+	case report.KindYear:
+		if v := c.Query("year"); v != "" {
+			t, err := time.Parse("2006", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year, expected YYYY"})
+				return
+			}
+			date = t
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period, expected month, cycle or year"})
+		return
+	}
+
+	cur, prev, lastYear := report.Periods(kind, s.planner.Cycle, date)
+	filter := s.parseFilter(c)
+	r := report.Build(s.filterTransactions(filter), cur, prev, lastYear, filter.Categories)
+	if c.Query("format") == "svg" {
+		c.Data(http.StatusOK, "image/svg+xml", chart.SVG(categoryPie(r)))
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// categoryPie draws the categories of r, like the pie the bot sends with a report.
+func categoryPie(r report.Report) chart.Pie {
+	pie := chart.Pie{Title: "By category, " + r.Label}
+	for _, cat := range r.Categories {
+		pie.Slices = append(pie.Slices, chart.Point{Label: cat.Category, Value: cat.Amount})
+	}
+	return pie
+}
+
+// apiGetHeatmap returns the spending of a month (month=YYYY-MM, default the
+// current one) or a year (year=YYYY) by weekday and hour of day, in the time
+// zone of the bot.
+func (s *Server) apiGetHeatmap(c *gin.Context) {
+	loc := s.bot.Location()
+	p := report.Month(s.now())
+	if v := c.Query("month"); v != "" {
+		t, err := time.ParseInLocation("2006-01", v, loc)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "invalid_request", "Invalid month, expected YYYY-MM")
+			return
+		}
+		p = report.Month(t)
+	} else if v := c.Query("year"); v != "" {
+		t, err := time.ParseInLocation("2006", v, loc)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "invalid_request", "Invalid year, expected YYYY")
+			return
+		}
+		p = report.Year(t)
+	}
+	filter := s.parseFilter(c)
+	respond(c, http.StatusOK, report.BuildHeatmap(s.filterTransactions(filter), p, loc, filter.Categories))
+}
@@ -0,0 +1,28 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+func TestReportsSVG(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+	if err := s.data.AddTransactions([]data.Transaction{
+		{Date: "2025-08-02", Category: "groceries", Amount: 1200},
+		{Date: "2025-08-03", Category: "dining", Amount: 400},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	w := s.do(http.MethodGet, "/expenses/reports?month=2025-08&format=svg", "", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Fatalf("status = %d, Content-Type = %q; want 200 image/svg+xml", w.Code, w.Header().Get("Content-Type"))
+	}
+	if body := w.Body.String(); !strings.HasPrefix(body, "<svg") || !strings.Contains(body, "groceries") {
+		t.Errorf("body = %.80q..., want an SVG pie with the categories", body)
+	}
+}
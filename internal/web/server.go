@@ -3,36 +3,111 @@ package web
 import (
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"strconv"
+	"sort"
 	"time"
 
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/budget"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/category"
 	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/envelope"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/goals"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/idempotency"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/receipt"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/recurring"
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/token"
 	"github.com/gin-gonic/gin"
 )
 
 type Server struct {
-	router *gin.Engine
-	data   *data.Data
-	bot    BotHandler
+	router    *gin.Engine
+	data      *data.Data
+	bot       BotHandler
+	templates *recurring.Store
+	planner   *budget.Planner
+	envelopes *envelope.Store
+	goals     *goals.Store
+	tokens    *token.Store
+	// Keys of submissions already applied, shared with the bot
+	idempotency *idempotency.Store
+	// Managed categories, offered by the Mini App picker
+	categories *category.Store
+	// Receipt photos and PDFs attached to transactions
+	receipts *receipt.Store
 }
 
 type BotHandler interface {
 	HandleWebAppData(chatID int64, data string) error
+	// Location is the time zone days are counted in, see Server.now
+	Location() *time.Location
+	// VerifyInitData checks the Mini App initData signed by Telegram and
+	// returns the user it was issued to, see requireInitData
+	VerifyInitData(initData string) (int64, error)
 }
 
 type TransactionRequest struct {
-	Date        string  `json:"date"`
-	Category    string  `json:"category"`
-	Description string  `json:"description"`
-	Amount      float64 `json:"amount"`
-	ChatID      int64   `json:"chat_id"`
+	Date        string       `json:"date"`
+	Category    string       `json:"category"`
+	Description string       `json:"description"`
+	Amount      float64      `json:"amount"`
+	Fixed       bool         `json:"fixed"`
+	Payer       string       `json:"payer"`
+	Tags        []string     `json:"tags"`
+	Splits      []SplitInput `json:"splits"`
+	// Time is an optional RFC 3339 timestamp; Date is then its day
+	Time   string `json:"time"`
+	ChatID int64  `json:"chat_id"`
+	// IdempotencyKey makes retries safe, see idempotency.Store; the Idempotency-Key header works too
+	IdempotencyKey string `json:"idempotency_key"`
 }
 
-func New(data *data.Data, bot BotHandler) *Server {
+// validate checks the required fields of a submitted expense.
+func (req TransactionRequest) validate() error {
+	if req.Time != "" {
+		if _, err := time.Parse(data.TimeLayout, req.Time); err != nil {
+			return errors.New("Invalid time, expected RFC 3339")
+		}
+	} else if req.Date == "" {
+		return errors.New("Date is required")
+	}
+	if req.Category == "" {
+		return errors.New("Category is required")
+	}
+	if req.Amount <= 0 {
+		return errors.New("Amount must be positive")
+	}
+	if len(req.IdempotencyKey) > idempotency.MaxKeyLength {
+		return errors.New("Idempotency key is too long")
+	}
+	return req.transaction().ValidateSplits()
+}
+
+func (req TransactionRequest) transaction() data.Transaction {
+	at, _ := time.Parse(data.TimeLayout, req.Time)
+	return data.Transaction{
+		Date:        req.Date,
+		Category:    req.Category,
+		Description: req.Description,
+		Amount:      req.Amount,
+		Fixed:       req.Fixed,
+		Payer:       req.Payer,
+		Tags:        req.Tags,
+		Splits:      splits(req.Splits),
+		Time:        at,
+	}
+}
+
+// now is the current time in the time zone of the bot, so "today" agrees with
+// the dates of timed transactions and the chat.
+func (s *Server) now() time.Time {
+	return time.Now().In(s.bot.Location())
+}
+
+func New(data *data.Data, bot BotHandler, templates *recurring.Store, planner *budget.Planner, envelopes *envelope.Store, goalStore *goals.Store, tokens *token.Store, keys *idempotency.Store, categories *category.Store, receipts *receipt.Store) *Server {
 	r := gin.Default()
 
 	// Load HTML templates
@@ -42,21 +117,49 @@ func New(data *data.Data, bot BotHandler) *Server {
 	r.Static("/expenses/static", "./static")
 
 	s := &Server{
-		router: r,
-		data:   data,
-		bot:    bot,
+		router:      r,
+		data:        data,
+		bot:         bot,
+		templates:   templates,
+		planner:     planner,
+		envelopes:   envelopes,
+		goals:       goalStore,
+		tokens:      tokens,
+		idempotency: keys,
+		categories:  categories,
+		receipts:    receipts,
 	}
 
 	// Routes
 	expenses := r.Group("/expenses")
 	{
 		expenses.GET("/", s.handleIndex)
+		expenses.GET("/sw.js", s.handleServiceWorker)
 		expenses.GET("/graph", s.handleGraph)
 		expenses.GET("/graph-data", s.handleGraphData)
+		expenses.GET("/envelopes", s.handleEnvelopes)
+		expenses.GET("/envelopes-data", s.handleEnvelopesData)
+		expenses.POST("/envelopes/move", s.requireInitData(), s.handleEnvelopeMove)
+		expenses.GET("/goals", s.handleGoals)
+		expenses.POST("/goals", s.requireInitData(), s.handleAddGoal)
+		expenses.POST("/goals/:id/contribute", s.requireInitData(), s.handleContribute)
+		expenses.DELETE("/goals/:id", s.requireInitData(), s.handleDeleteGoal)
+		expenses.GET("/reports", s.handleReports)
+		expenses.GET("/events", s.handleEvents)
 		expenses.POST("/transaction", s.handleTransaction)
+		// Gin has no literal colons in paths, so the :batch suffix is matched by handleTransactionsAction
+		expenses.POST("/transactions:action", s.requireInitData(), s.handleTransactionsAction)
 		expenses.POST("/upload-csv", s.handleCSVUpload)
 		expenses.GET("/transactions", s.handleGetTransactions)
+		expenses.PUT("/transactions/:id", s.requireInitData(), s.handleUpdateTransaction)
+		expenses.DELETE("/transactions/:id", s.requireInitData(), s.handleDeleteTransaction)
+		expenses.GET("/transactions/:id/receipts", s.requireInitData(), s.handleListReceipts)
+		expenses.POST("/transactions/:id/receipts", s.requireInitData(), s.handleAttachReceipt)
+		expenses.GET("/transactions/:id/receipts/:hash", s.requireInitData(), s.handleGetReceipt)
+		expenses.DELETE("/transactions/:id/receipts/:hash", s.requireInitData(), s.handleDeleteReceipt)
+		expenses.GET("/days", s.handleDays)
 	}
+	s.registerAPI(r)
 
 	return s
 }
@@ -76,27 +179,36 @@ func (s *Server) handleGraphData(c *gin.Context) {
 		Cumulative float64 `json:"cumulative"`
 		BudgetCum  float64 `json:"budget_cum"`
 		Saldo      float64 `json:"saldo"`
+		Fixed      float64 `json:"fixed"`
+		Carry      float64 `json:"carry"`
+		// Forecast of the cumulative spend for the current cycle, from today on
+		Forecast     *float64 `json:"forecast,omitempty"`
+		ForecastLow  *float64 `json:"forecast_low,omitempty"`
+		ForecastHigh *float64 `json:"forecast_high,omitempty"`
+		Future       bool     `json:"future,omitempty"`
 	}
 
 	fromStr := c.Query("from")
 	toStr := c.Query("to")
 
-	// Read budget from env; default 12000 (see OVERVIEW.md)
-	budgetMonthly := 12000.0
-	if v := os.Getenv("MONTHLY_BUDGET_RUB"); v != "" {
-		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
-			budgetMonthly = f
-		}
-	}
+	// Budget from env (default 12000, see OVERVIEW.md) unless overridden with /budget
+	settings := s.planner.Settings()
+	budgetMonthly := s.planner.MonthlyBudget()
 
-	// Build daily sum map
-	txs := s.data.GetAllTransactions()
+	// Build daily sum map of discretionary spending; fixed costs are reserved from the budget instead.
+	// The category and tag filters narrow the spend down; the budget line stays
+	// and the forecast, which covers all spending, is left out.
+	filter := s.parseFilter(c)
+	filtered := len(filter.Categories) > 0 || len(filter.Tags) > 0
+	txs := s.filterTransactions(filter)
 	daySum := map[string]float64{}
 	const layout = "2006-01-02"
 	minDate, maxDate := "", ""
 
 	for _, tx := range txs {
-		daySum[tx.Date] += tx.Amount
+		if !settings.IsFixed(tx) {
+			daySum[tx.Date] += tx.AmountIn(filter.Categories)
+		}
 		if minDate == "" || tx.Date < minDate {
 			minDate = tx.Date
 		}
@@ -107,7 +219,7 @@ func (s *Server) handleGraphData(c *gin.Context) {
 
 	// Default window: last 90 days (or full range if fewer)
 	var from, to time.Time
-	now := time.Now()
+	now := s.now()
 	defaultFrom := now.AddDate(0, 0, -90)
 
 	if fromStr != "" {
@@ -147,45 +259,85 @@ func (s *Server) handleGraphData(c *gin.Context) {
 		from, to = to, from
 	}
 
+	// Forecast of the current cycle; without an explicit end the window is extended to the cycle end
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	forecast := s.planner.Forecast(today, budgetMonthly)
+	if last := forecast.NextCycleStart.AddDate(0, 0, -1); toStr == "" && !to.Before(forecast.CycleStart) && to.Before(last) {
+		to = last
+	}
+
 	// Walk inclusive date range and compute series
 	var res []point
-	var cum float64
+	var cum, fixed, carry, cycleBudget, cycleWeight, forecastBase float64
+	var cycleStart, nextCycle time.Time
+	profile := s.planner.Profile()
 	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
 		key := d.Format(layout)
 		spend := daySum[key]
 
-		// Per-month budget curve: monthly budget * (dayIndex / daysInMonth)
-		firstOfMonth := time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
-		daysInMonth := firstOfMonth.AddDate(0, 1, -1).Day()
-		dayIndex := d.Day()
-		budgetCum := budgetMonthly * float64(dayIndex) / float64(daysInMonth)
-
-		// Reset cumulative at month start to reflect budget period
-		if dayIndex == 1 {
-			cum = 0
+		// Per-cycle budget curve: (cycle budget - fixed costs of the cycle + carry) spread by the allowance profile weights
+		if d.Equal(from) || !d.Before(nextCycle) {
+			cycleStart, nextCycle = s.planner.Cycle(d)
+			cycleBudget = s.planner.CycleBudget(cycleStart, nextCycle, budgetMonthly)
+			fixed = s.planner.FixedIn(cycleStart, nextCycle.AddDate(0, 0, -1))
+			carry = s.planner.Carry(cycleStart, budgetMonthly)
+			cycleWeight = profile.WeightBetween(cycleStart, nextCycle)
+			if cycleWeight <= 0 {
+				cycleWeight = nextCycle.Sub(cycleStart).Hours() / 24
+			}
+			// Reset cumulative at cycle start to reflect budget period
+			if d.Equal(cycleStart) {
+				cum = 0
+			}
 		}
+		discretionary := max(max(cycleBudget-fixed, 0)+carry, 0)
+		budgetCum := discretionary * profile.WeightBetween(cycleStart, d.AddDate(0, 0, 1)) / cycleWeight
 		cum += spend
 
-		res = append(res, point{
+		p := point{
 			Date:       key,
 			Spend:      spend,
 			Cumulative: cum,
 			BudgetCum:  budgetCum,
 			Saldo:      budgetCum - cum,
-		})
+			Fixed:      fixed,
+			Carry:      carry,
+			Future:     d.After(today),
+		}
+		if !filtered && !d.Before(today) && d.Before(forecast.NextCycleStart) {
+			if d.Equal(today) {
+				forecastBase = cum
+			}
+			ahead := d.Sub(today).Hours() / 24
+			expected, low, high := forecastBase+forecast.Rate*ahead, forecastBase+forecast.LowRate*ahead, forecastBase+forecast.HighRate*ahead
+			p.Forecast, p.ForecastLow, p.ForecastHigh = &expected, &low, &high
+		}
+		res = append(res, p)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"from":          from.Format(layout),
-		"to":            to.Format(layout),
-		"monthlyBudget": budgetMonthly,
-		"points":        res,
+		"from":            from.Format(layout),
+		"to":              to.Format(layout),
+		"monthlyBudget":   budgetMonthly,
+		"cycles":          s.planner.Cycles().Describe(),
+		"rollover":        settings.Rollover.Policy,
+		"fixedCategories": fixedCategories(settings),
+		"profile":         profile.Name,
+		"forecast": gin.H{
+			"cycleEnd":    forecast.NextCycleStart.AddDate(0, 0, -1).Format(layout),
+			"budget":      forecast.Budget,
+			"optimistic":  forecast.Optimistic,
+			"expected":    forecast.Expected,
+			"pessimistic": forecast.Pessimistic,
+		},
+		"points": res,
 	})
 }
 
 func (s *Server) handleIndex(c *gin.Context) {
 	c.HTML(http.StatusOK, "index.html", gin.H{
-		"title": "Expense Tracker",
+		"title":      "Expense Tracker",
+		"categories": s.categoryOptions(),
 	})
 }
 
@@ -196,27 +348,28 @@ func (s *Server) handleTransaction(c *gin.Context) {
 		return
 	}
 
-	// Validate required fields
-	if req.Date == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Date is required"})
-		return
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = c.GetHeader("Idempotency-Key")
 	}
-	if req.Category == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Category is required"})
-		return
-	}
-	if req.Amount <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount must be positive"})
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// If chat ID is provided, let the bot handle persistence + confirmation to avoid duplicate saves
+	// If chat ID is provided, let the bot handle persistence + confirmation to avoid duplicate saves;
+	// the bot applies the idempotency key, shared with the same submission sent by tg.sendData
 	if req.ChatID != 0 {
 		transactionData := map[string]interface{}{
-			"date":        req.Date,
-			"category":    req.Category,
-			"description": req.Description,
-			"amount":      req.Amount,
+			"date":            req.Date,
+			"category":        req.Category,
+			"description":     req.Description,
+			"amount":          req.Amount,
+			"fixed":           req.Fixed,
+			"payer":           req.Payer,
+			"tags":            req.Tags,
+			"splits":          req.Splits,
+			"time":            req.Time,
+			"idempotency_key": req.IdempotencyKey,
 		}
 
 		jsonData, _ := json.Marshal(transactionData)
@@ -232,20 +385,44 @@ func (s *Server) handleTransaction(c *gin.Context) {
 	}
 
 	// Otherwise, persist directly
-	tx := data.Transaction{
-		Date:        req.Date,
-		Category:    req.Category,
-		Description: req.Description,
-		Amount:      req.Amount,
-	}
-	if err := s.data.AddTransaction(tx); err != nil {
+	tx, replayed, err := s.createTransaction(req.transaction(), req.IdempotencyKey)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save transaction"})
 		return
 	}
+	if replayed {
+		c.JSON(http.StatusOK, gin.H{"message": "Transaction already added", "transaction": tx})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Transaction added successfully", "transaction": tx})
 }
 
+// createTransaction adds tx once per idempotency key; a repeated key returns the
+// transaction added first. An empty key always adds.
+func (s *Server) createTransaction(tx data.Transaction, key string) (data.Transaction, bool, error) {
+	if key == "" {
+		tx, err := s.data.CreateTransaction(tx)
+		return tx, false, err
+	}
+	result, replayed, err := s.idempotency.Do(key, time.Now(), func() (string, error) {
+		stored, err := s.data.CreateTransaction(tx)
+		if err != nil {
+			return "", err
+		}
+		b, err := json.Marshal(stored)
+		return string(b), err
+	})
+	if err != nil {
+		return data.Transaction{}, false, err
+	}
+	var stored data.Transaction
+	if err := json.Unmarshal([]byte(result), &stored); err != nil {
+		return data.Transaction{}, false, err
+	}
+	return stored, replayed, nil
+}
+
 func (s *Server) handleCSVUpload(c *gin.Context) {
 	file, err := c.FormFile("csv")
 	if err != nil {
@@ -279,54 +456,45 @@ func (s *Server) handleCSVUpload(c *gin.Context) {
 	}
 
 	// Validate header
-	expectedHeader := []string{"Date", "Category", "Description", "Amount"}
-	if len(records[0]) != 4 || !compareStringSlices(records[0], expectedHeader) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV header must be: Date,Category,Description,Amount"})
+	columns, err := data.ParseHeader(records[0])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV header must be: Date,Category,Description,Amount (optionally followed by Fixed, Payer, ID, Tags, Splits, Fiscal and Time)"})
 		return
 	}
 
 	// Process transactions
 	var transactions []data.Transaction
-	var errors []string
+	var problems []string
 
 	for i, record := range records[1:] {
-		if len(record) != 4 {
-			errors = append(errors, fmt.Sprintf("Line %d: Invalid number of fields", i+2))
-			continue
-		}
-
-		amount, err := strconv.ParseFloat(record[3], 64)
+		tx, err := columns.Parse(record, i+2)
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("Line %d: Invalid amount '%s'", i+2, record[3]))
+			problems = append(problems, err.Error())
 			continue
 		}
 
-		if amount <= 0 {
-			errors = append(errors, fmt.Sprintf("Line %d: Amount must be positive", i+2))
+		if tx.Amount <= 0 {
+			problems = append(problems, fmt.Sprintf("Line %d: Amount must be positive", i+2))
 			continue
 		}
 
-		tx := data.Transaction{
-			Date:        record[0],
-			Category:    record[1],
-			Description: record[2],
-			Amount:      amount,
-		}
-
 		transactions = append(transactions, tx)
 	}
 
 	// If there are validation errors, return them
-	if len(errors) > 0 {
+	if len(problems) > 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":  "CSV validation failed",
-			"errors": errors,
+			"errors": problems,
 		})
 		return
 	}
 
 	// Replace existing data with uploaded set atomically
-	if err := s.data.ReplaceAll(transactions); err != nil {
+	if err := s.data.ReplaceAll(transactions); errors.Is(err, data.ErrDuplicateReceipt) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save transactions"})
 		return
 	}
@@ -337,22 +505,6 @@ func (s *Server) handleCSVUpload(c *gin.Context) {
 	})
 }
 
-func (s *Server) handleGetTransactions(c *gin.Context) {
-	date := c.Query("date")
-
-	var transactions []data.Transaction
-	if date != "" {
-		transactions = s.data.GetTransactionsByDate(date)
-	} else {
-		transactions = s.data.GetAllTransactions()
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"transactions": transactions,
-		"count":        len(transactions),
-	})
-}
-
 func (s *Server) Start(address string, certPath string, keyPath string) error {
 	// Check if we're running in Docker with mounted certificates
 	if certPath == "" && keyPath == "" {
@@ -385,14 +537,12 @@ func (s *Server) Start(address string, certPath string, keyPath string) error {
 	return s.router.Run(address)
 }
 
-func compareStringSlices(a, b []string) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i, v := range a {
-		if v != b[i] {
-			return false
-		}
+// fixedCategories returns the configured fixed categories in a stable order.
+func fixedCategories(settings budget.Settings) []string {
+	res := make([]string, 0, len(settings.FixedCategories))
+	for c := range settings.FixedCategories {
+		res = append(res, c)
 	}
-	return true
+	sort.Strings(res)
+	return res
 }
@@ -0,0 +1,42 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+)
+
+func TestGraphDataFilteredSplits(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer(t)
+	if err := s.data.AddTransactions([]data.Transaction{
+		{Date: "2025-08-02", Category: "household", Description: "Auchan", Amount: 1000, Splits: []data.Split{{Category: "household", Amount: 800}, {Category: "groceries", Amount: 200}}},
+		{Date: "2025-08-03", Category: "groceries", Description: "Pyaterochka", Amount: 300},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := s.do(http.MethodGet, "/expenses/graph-data?from=2025-08-01&to=2025-08-03&category=groceries", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var got struct {
+		Points []struct {
+			Date       string  `json:"date"`
+			Spend      float64 `json:"spend"`
+			Cumulative float64 `json:"cumulative"`
+		} `json:"points"`
+	}
+	decode(t, w, &got)
+	want := map[string]float64{"2025-08-01": 0, "2025-08-02": 200, "2025-08-03": 300}
+	if len(got.Points) != len(want) {
+		t.Fatalf("points = %+v, want 3 days", got.Points)
+	}
+	for _, p := range got.Points {
+		if p.Spend != want[p.Date] {
+			t.Errorf("spend on %s = %.2f, want %.2f counting only the groceries line", p.Date, p.Spend, want[p.Date])
+		}
+	}
+}
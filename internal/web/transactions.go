@@ -0,0 +1,286 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NumeroQuadro/goofy-ahh-expenses-tracker/internal/data"
+	"github.com/gin-gonic/gin"
+)
+
+const maxPageSize = 1000
+
+// transactionFields are the field names of a transaction in JSON, by lower-case name.
+var transactionFields = map[string]string{
+	"date":        "Date",
+	"category":    "Category",
+	"description": "Description",
+	"amount":      "Amount",
+	"fixed":       "Fixed",
+	"payer":       "Payer",
+	"merchant":    "Merchant",
+	"tags":        "Tags",
+	"splits":      "Splits",
+}
+
+// handleGetTransactions queries the ledger.
+// Query parameters, all optional:
+//
+//	date=YYYY-MM-DD               exact day (same as from=to=date)
+//	from, to=YYYY-MM-DD           inclusive date range
+//	category=a&category=b         any of, also comma separated; includes subcategories
+//	tag=a&tag=b                   any of these tags, also comma separated
+//	min_amount, max_amount        inclusive amount range
+//	q=text                        description substring, case-insensitive
+//	regex=expr                    description regular expression
+//	merchant, payer               exact merchant (normalized) and payer
+//	sort=-amount                  date, amount, category, description, merchant or payer; "-" for descending
+//	limit=N, cursor=...           page size (up to 1000) and the next_cursor of the previous page
+//	fields=date,amount            project each transaction to these fields
+//
+// The response also maps the IDs of listed transactions with receipts to their
+// number of receipts.
+func (s *Server) handleGetTransactions(c *gin.Context) {
+	q, err := s.parseQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var fields []string
+	if v := c.Query("fields"); v != "" {
+		for _, f := range strings.Split(v, ",") {
+			name, ok := transactionFields[strings.ToLower(strings.TrimSpace(f))]
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown field " + strings.TrimSpace(f)})
+				return
+			}
+			fields = append(fields, name)
+		}
+	}
+
+	page, err := s.data.Query(q)
+	if errors.Is(err, data.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or stale cursor, start from the first page"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Receipt counts let the list mark the expenses that have any
+	counts := s.receipts.Counts()
+	receipts := map[string]int{}
+	for _, tx := range page.Transactions {
+		if n := counts[tx.ID]; n > 0 {
+			receipts[tx.ID] = n
+		}
+	}
+
+	var transactions interface{} = page.Transactions
+	if fields != nil {
+		projected := make([]map[string]interface{}, len(page.Transactions))
+		for i, tx := range page.Transactions {
+			projected[i] = project(tx, fields)
+		}
+		transactions = projected
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": transactions,
+		"count":        len(page.Transactions),
+		"totals":       page.Totals,
+		"next_cursor":  page.NextCursor,
+		"receipts":     receipts,
+	})
+}
+
+// parseFilter reads the category and tag filters shared by the transaction
+// query, the reports and the graph. A managed category also matches its
+// subcategories, and a path such as food/cafes everything below it.
+func (s *Server) parseFilter(c *gin.Context) data.Query {
+	var q data.Query
+	for _, cat := range queryList(c, "category") {
+		q.Categories = append(q.Categories, s.categories.Expand(cat)...)
+	}
+	q.Tags = queryList(c, "tag")
+	return q
+}
+
+// filterTransactions returns the transactions of the ledger that match q.
+func (s *Server) filterTransactions(q data.Query) []data.Transaction {
+	var res []data.Transaction
+	for _, tx := range s.data.GetAllTransactions() {
+		if q.Match(tx) {
+			res = append(res, tx)
+		}
+	}
+	return res
+}
+
+// queryList returns the values of a repeatable, comma separated query parameter.
+func queryList(c *gin.Context, name string) []string {
+	var res []string
+	for _, v := range c.QueryArray(name) {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				res = append(res, item)
+			}
+		}
+	}
+	return res
+}
+
+func (s *Server) parseQuery(c *gin.Context) (data.Query, error) {
+	q := s.parseFilter(c)
+	q.From, q.To = c.Query("from"), c.Query("to")
+	q.Description = c.Query("q")
+	q.Merchant = c.Query("merchant")
+	q.Payer = c.Query("payer")
+	q.Sort = c.Query("sort")
+	q.Cursor = c.Query("cursor")
+	if date := c.Query("date"); date != "" {
+		q.From, q.To = date, date
+	}
+	for name, dst := range map[string]**float64{"min_amount": &q.MinAmount, "max_amount": &q.MaxAmount} {
+		if v := c.Query(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return q, errors.New("Invalid " + name)
+			}
+			*dst = &f
+		}
+	}
+	if v := c.Query("regex"); v != "" {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return q, errors.New("Invalid regex: " + err.Error())
+		}
+		q.Pattern = re
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			return q, errors.New("Invalid limit, expected 1 to 1000")
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
+
+func project(tx data.Transaction, fields []string) map[string]interface{} {
+	res := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		switch f {
+		case "Date":
+			res[f] = tx.Date
+		case "Category":
+			res[f] = tx.Category
+		case "Description":
+			res[f] = tx.Description
+		case "Amount":
+			res[f] = tx.Amount
+		case "Fixed":
+			res[f] = tx.Fixed
+		case "Payer":
+			res[f] = tx.Payer
+		case "Merchant":
+			res[f] = tx.Merchant()
+		case "Tags":
+			res[f] = tx.Tags
+		case "Splits":
+			res[f] = tx.Splits
+		}
+	}
+	return res
+}
+
+// handleUpdateTransaction replaces the transaction with the given ID, e.g. after
+// an edit in the Mini App list. The payer is kept unless a new one is sent, and
+// the fiscal receipt it was entered from is always kept.
+func (s *Server) handleUpdateTransaction(c *gin.Context) {
+	cur, err := s.data.GetTransaction(c.Param("id"))
+	if errors.Is(err, data.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+	var req TransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx := req.transaction()
+	tx.ID = cur.ID
+	if tx.Payer == "" {
+		tx.Payer = cur.Payer
+	}
+	tx.Fiscal = cur.Fiscal
+	if err := s.data.UpdateTransaction(tx); errors.Is(err, data.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transaction"})
+		return
+	}
+	if stored, err := s.data.GetTransaction(tx.ID); err == nil {
+		tx = stored
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Transaction updated", "transaction": tx})
+}
+
+func (s *Server) handleDeleteTransaction(c *gin.Context) {
+	if err := s.data.DeleteTransaction(c.Param("id")); errors.Is(err, data.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transaction"})
+		return
+	}
+	s.detachReceipts(c.Param("id"))
+	c.JSON(http.StatusOK, gin.H{"message": "Transaction deleted"})
+}
+
+// maxDays bounds the range of handleDays.
+const maxDays = 366
+
+// handleDays returns the discretionary spend and allowance of each day from from
+// through to (default: the last 14 days), for the daily subtotals of the list.
+func (s *Server) handleDays(c *gin.Context) {
+	const layout = "2006-01-02"
+	now := s.now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -13)
+	for name, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(layout, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + " date, expected YYYY-MM-DD"})
+				return
+			}
+			*dst = t
+		}
+	}
+	if from.After(to) || to.Sub(from).Hours()/24 >= maxDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid range, from must not be after to and span at most 366 days"})
+		return
+	}
+
+	type day struct {
+		Date      string  `json:"date"`
+		Spent     float64 `json:"spent"`
+		Allowance float64 `json:"allowance"`
+	}
+	var res []day
+	for _, d := range s.planner.Days(from, to, s.planner.MonthlyBudget()) {
+		res = append(res, day{Date: d.Date.Format(layout), Spent: d.Spent, Allowance: d.Allowance})
+	}
+	c.JSON(http.StatusOK, gin.H{"days": res})
+}
@@ -0,0 +1,94 @@
+(function () {
+  const q = (s) => document.querySelector(s);
+  const listEl = q('#envelopes');
+  const periodEl = q('#period');
+  const totalsEl = q('#totals');
+  const form = q('#move-form');
+  const fromEl = q('#move-from');
+  const toEl = q('#move-to');
+  const statusEl = q('#move-status');
+
+  const fmt = (v) => v.toFixed(2);
+  // Moves are signed with the initData of the launch, like edits in script.js
+  const tg = window.Telegram ? window.Telegram.WebApp : null;
+
+  function render(data) {
+    listEl.innerHTML = '';
+    totalsEl.textContent = '';
+    if (!data.enabled) {
+      periodEl.textContent = 'Envelope budgeting is off. Set BUDGET_MODE=envelope to enable it.';
+      form.style.display = 'none';
+      return;
+    }
+    periodEl.textContent = `Cycle ${data.cycleStart} — ${data.cycleEnd}, budget ${fmt(data.budget)} RUB`;
+    if (data.envelopes.length === 0) {
+      listEl.textContent = 'No envelopes yet. Create one in the bot: /envelopes set groceries 6000';
+    }
+
+    fromEl.innerHTML = '';
+    toEl.innerHTML = '';
+    data.envelopes.forEach(e => {
+      const el = document.createElement('div');
+      el.className = 'envelope' + (e.balance < 0 ? ' negative' : '');
+      const available = e.balance + e.cycle_spent;
+      const share = available > 0 ? Math.max(0, Math.min(1, e.balance / available)) : 0;
+      const categories = e.categories && e.categories.length ? e.categories.join(', ') : e.name;
+      el.innerHTML = `
+        <div class="row"><span></span><span class="balance"></span></div>
+        <div class="meta"></div>
+        <div class="bar"><span style="width:${(share * 100).toFixed(0)}%"></span></div>`;
+      el.querySelector('.row span').textContent = e.name;
+      el.querySelector('.balance').textContent = `${fmt(e.balance)} RUB`;
+      el.querySelector('.meta').textContent =
+        `${categories} · spent ${fmt(e.cycle_spent)} this cycle · +${fmt(e.allocation)} per cycle`;
+      listEl.appendChild(el);
+
+      [fromEl, toEl].forEach(sel => {
+        const opt = document.createElement('option');
+        opt.value = e.name;
+        opt.textContent = e.name;
+        sel.appendChild(opt);
+      });
+    });
+    if (toEl.options.length > 1) toEl.selectedIndex = 1;
+
+    const totals = [];
+    if (data.unallocated !== 0) totals.push(`Unallocated: ${fmt(data.unallocated)} RUB`);
+    if (data.unassigned > 0) totals.push(`Spent outside envelopes: ${fmt(data.unassigned)} RUB`);
+    totalsEl.textContent = totals.join(' · ');
+  }
+
+  function load() {
+    return fetch('/expenses/envelopes-data').then(r => r.json()).then(render);
+  }
+
+  form.addEventListener('submit', (e) => {
+    e.preventDefault();
+    statusEl.textContent = '';
+    fetch('/expenses/envelopes/move', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json', 'X-Telegram-Init-Data': tg ? tg.initData : '' },
+      body: JSON.stringify({
+        amount: parseFloat(q('#move-amount').value),
+        from: fromEl.value,
+        to: toEl.value,
+      }),
+    })
+      .then(r => r.json().then(body => ({ ok: r.ok, body })))
+      .then(({ ok, body }) => {
+        if (!ok) {
+          statusEl.textContent = `❌ ${body.error}`;
+          return;
+        }
+        statusEl.textContent = '✅ Moved';
+        form.reset();
+        return load();
+      })
+      .catch(() => { statusEl.textContent = '❌ Network error'; });
+  });
+
+  document.addEventListener('DOMContentLoaded', () => {
+    if (window.Telegram && window.Telegram.WebApp) window.Telegram.WebApp.ready();
+    load();
+  });
+})();
@@ -3,6 +3,8 @@
   const chartEl = q('#chart');
   const fromEl = q('#from');
   const toEl = q('#to');
+  const categoryEl = q('#filter-category');
+  const tagEl = q('#filter-tag');
   const applyBtn = q('#apply');
   const resetBtn = q('#reset');
 
@@ -10,6 +12,8 @@
   const tCum = q('#toggle-cum');
   const tBudget = q('#toggle-budget');
   const tSaldo = q('#toggle-saldo');
+  const tCarry = q('#toggle-carry');
+  const tForecast = q('#toggle-forecast');
 
   let u = null;
   let raw = null;
@@ -30,6 +34,9 @@
     const params = new URLSearchParams();
     if (fromEl.value) params.set('from', fromEl.value);
     if (toEl.value) params.set('to', toEl.value);
+    // Narrow the spend down to a category subtree or tags, e.g. food or #kazan,#trip
+    if (categoryEl.value.trim()) params.set('category', categoryEl.value.trim());
+    if (tagEl.value.trim()) params.set('tag', tagEl.value.replace(/#/g, '').trim());
 
     return fetch(`/expenses/graph-data?${params}`).then(r => r.json());
   }
@@ -37,11 +44,31 @@
   function toUplotSeries(data) {
     // x in ms timestamps
     const x = data.points.map(p => new Date(p.date + 'T00:00:00Z').getTime());
-    const daily = data.points.map(p => p.spend);
-    const cum = data.points.map(p => p.cumulative);
+    // Days after today have no actuals yet, only the forecast
+    const actual = (p, v) => (p.future ? null : v);
+    const daily = data.points.map(p => actual(p, p.spend));
+    const cum = data.points.map(p => actual(p, p.cumulative));
     const budget = data.points.map(p => p.budget_cum);
-    const saldo = data.points.map(p => p.saldo);
-    return { x, daily, cum, budget, saldo, meta: data };
+    const saldo = data.points.map(p => actual(p, p.saldo));
+    const carry = data.points.map(p => p.carry);
+    const forecast = data.points.map(p => p.forecast ?? null);
+    const forecastLow = data.points.map(p => p.forecast_low ?? null);
+    const forecastHigh = data.points.map(p => p.forecast_high ?? null);
+    return { x, daily, cum, budget, saldo, carry, forecast, forecastLow, forecastHigh, meta: data };
+  }
+
+  function chartData(series) {
+    return [
+      series.x,
+      series.daily,
+      series.cum,
+      series.budget,
+      series.saldo,
+      series.carry,
+      series.forecast,
+      series.forecastLow,
+      series.forecastHigh,
+    ];
   }
 
   function buildChart(series) {
@@ -60,6 +87,10 @@
         { label: 'Cumulative', stroke: '#f28e2b', width: 2, points: { show: false } },
         { label: 'Budget', stroke: '#76b7b2', width: 2, dash: [6, 6], points: { show: false } },
         { label: 'Saldo', stroke: '#e15759', width: 2, points: { show: false } },
+        { label: 'Carry', stroke: '#b07aa1', width: 2, dash: [2, 4], points: { show: false } },
+        { label: 'Forecast', stroke: '#f28e2b', width: 2, dash: [8, 4], points: { show: false } },
+        { label: 'Optimistic', stroke: 'rgba(242,142,43,0.4)', width: 1, dash: [2, 4], points: { show: false } },
+        { label: 'Pessimistic', stroke: 'rgba(242,142,43,0.4)', width: 1, dash: [2, 4], points: { show: false } },
       ],
       legend: { show: true },
       axes: [
@@ -106,15 +137,7 @@
       }
     };
 
-    const data = [
-      series.x,
-      series.daily,
-      series.cum,
-      series.budget,
-      series.saldo,
-    ];
-
-    u = new uPlot(opts, data, chartEl);
+    u = new uPlot(opts, chartData(series), chartEl);
 
     // Visibility toggles
     const updateVis = () => {
@@ -122,8 +145,10 @@
       u.setSeries(2, { show: tCum.checked });
       u.setSeries(3, { show: tBudget.checked });
       u.setSeries(4, { show: tSaldo.checked });
+      u.setSeries(5, { show: tCarry.checked });
+      [6, 7, 8].forEach(i => u.setSeries(i, { show: tForecast.checked }));
     };
-    [tDaily, tCum, tBudget, tSaldo].forEach(cb => cb.addEventListener('change', updateVis));
+    [tDaily, tCum, tBudget, tSaldo, tCarry, tForecast].forEach(cb => cb.addEventListener('change', updateVis));
     updateVis();
 
     // Resize handler
@@ -133,6 +158,53 @@
     window.addEventListener('resize', onResize);
   }
 
+  // Goals progress: saved share of the target; goals behind their deadline are highlighted
+  function renderGoals(data) {
+    const el = q('#goals');
+    el.innerHTML = '';
+    if (!data.goals || data.goals.length === 0) return;
+    const title = document.createElement('h3');
+    title.textContent = '🎯 Goals';
+    el.appendChild(title);
+    data.goals.forEach(g => {
+      const item = document.createElement('div');
+      item.className = 'goal' + (g.on_track ? '' : ' behind');
+      const saved = Math.min(1, g.saved / g.target);
+      item.innerHTML = `
+        <div class="row"><span class="name"></span><span class="amount"></span></div>
+        <div class="bar"><span class="saved" style="width:${(saved * 100).toFixed(1)}%"></span></div>
+        <div class="meta"></div>`;
+      item.querySelector('.name').textContent = `${g.name} · by ${g.deadline}`;
+      item.querySelector('.amount').textContent = `${g.saved.toFixed(0)} / ${g.target.toFixed(0)} RUB`;
+      const pace = g.done ? 'reached' :
+        `${g.per_cycle.toFixed(0)} RUB per cycle needed` + (g.projected ? `, projected ${g.projected}` : ', no pace yet');
+      item.querySelector('.meta').textContent = pace;
+      el.appendChild(item);
+    });
+  }
+
+  function fetchGoals() {
+    return fetch('/expenses/goals').then(r => r.json()).then(renderGoals).catch(() => {});
+  }
+
+  // Live updates: any ledger change can move the saldo, carry and forecast, so
+  // the window is refetched (debounced) and swapped in without resetting the zoom
+  let refreshTimer = null;
+  function refresh() {
+    clearTimeout(refreshTimer);
+    refreshTimer = setTimeout(() => {
+      fetchData().then(data => {
+        raw = data;
+        const s = toUplotSeries(data);
+        if (u) {
+          u.setData(chartData(s), false);
+        } else {
+          buildChart(s);
+        }
+      });
+    }, 300);
+  }
+
   function init() {
     // Default: last 90 days
     const now = new Date();
@@ -162,6 +234,7 @@
     });
 
     // Initial load
+    fetchGoals();
     fetchData().then(data => {
       raw = data;
       // Set actual window from response if empty inputs
@@ -172,6 +245,8 @@
       const s = toUplotSeries(data);
       buildChart(s);
     });
+
+    subscribeLedger(refresh);
   }
 
   document.addEventListener('DOMContentLoaded', init);
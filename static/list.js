@@ -0,0 +1,408 @@
+// Expense list: the entered expenses grouped by day, newest first, with the
+// day's discretionary spend against its allowance. Tap an expense to edit it in
+// the form above, swipe it left to delete it.
+const LIST_PAGE_DAYS = 14;
+const SWIPE_DELETE = 80; // px
+
+let listFrom = null;
+let listTo = null;
+let editingId = null;
+let listTimer = null;
+
+function isoDate(d) {
+    const month = String(d.getMonth() + 1).padStart(2, '0');
+    const day = String(d.getDate()).padStart(2, '0');
+    return `${d.getFullYear()}-${month}-${day}`;
+}
+
+function categoryLabel(category) {
+    const option = document.querySelector(`#category option[value="${CSS.escape(category)}"]`);
+    return option ? option.textContent.trim() : category;
+}
+
+// fetchTransactions follows next_cursor until the window is complete.
+async function fetchTransactions(params) {
+    const all = [];
+    let cursor = '';
+    do {
+        if (cursor) {
+            params.set('cursor', cursor);
+        }
+        const result = await fetch(`/expenses/transactions?${params}`).then(r => r.json());
+        if (result.error) {
+            throw new Error(result.error);
+        }
+        // Receipt counts come beside the transactions, by ID
+        result.transactions.forEach(tx => { tx.Receipts = (result.receipts || {})[tx.ID] || 0; });
+        all.push(...result.transactions);
+        cursor = result.next_cursor;
+    } while (cursor);
+    return all;
+}
+
+async function loadList() {
+    const params = new URLSearchParams({ from: listFrom, to: listTo, sort: '-date', limit: '1000' });
+    const category = document.getElementById('list-category').value;
+    if (category) {
+        params.set('category', category);
+    }
+    try {
+        const [transactions, days] = await Promise.all([
+            fetchTransactions(params),
+            fetch(`/expenses/days?from=${daysFrom()}&to=${listTo}`).then(r => r.json()),
+        ]);
+        renderList(transactions, days.days || []);
+    } catch (error) {
+        console.error('List error:', error);
+    }
+}
+
+// Subtotals are available for up to a year back from listTo
+function daysFrom() {
+    const to = new Date(`${listTo}T00:00:00`);
+    const earliest = isoDate(new Date(to.getFullYear(), to.getMonth(), to.getDate() - 365));
+    return listFrom < earliest ? earliest : listFrom;
+}
+
+// Live updates and edits reload the shown window, debounced
+function refreshList() {
+    clearTimeout(listTimer);
+    listTimer = setTimeout(loadList, 300);
+}
+
+function renderList(transactions, days) {
+    const list = document.getElementById('tx-list');
+    list.innerHTML = '';
+
+    const byDate = new Map();
+    transactions.forEach(tx => {
+        if (!byDate.has(tx.Date)) {
+            byDate.set(tx.Date, []);
+        }
+        byDate.get(tx.Date).push(tx);
+    });
+    const budgets = new Map(days.map(d => [d.date, d]));
+
+    if (byDate.size === 0) {
+        const empty = document.createElement('div');
+        empty.className = 'tx-empty';
+        empty.textContent = `No expenses from ${listFrom} to ${listTo}`;
+        list.appendChild(empty);
+        return;
+    }
+
+    byDate.forEach((txs, date) => {
+        const group = document.createElement('div');
+        group.className = 'tx-day';
+
+        const header = document.createElement('div');
+        header.className = 'tx-day-header';
+        const title = document.createElement('span');
+        title.textContent = new Date(`${date}T00:00:00`).toLocaleDateString(undefined, { weekday: 'short', day: 'numeric', month: 'short' });
+        const subtotal = document.createElement('span');
+        subtotal.className = 'tx-subtotal';
+        const budget = budgets.get(date);
+        if (budget) {
+            subtotal.textContent = `${budget.spent.toFixed(0)} / ${budget.allowance.toFixed(0)} RUB`;
+            subtotal.classList.toggle('over', budget.spent > budget.allowance);
+        }
+        header.append(title, subtotal);
+        group.appendChild(header);
+
+        txs.forEach(tx => group.appendChild(renderItem(tx)));
+        list.appendChild(group);
+    });
+}
+
+function renderItem(tx) {
+    const item = document.createElement('div');
+    item.className = 'tx-item';
+    item.innerHTML = `
+        <div class="tx-delete">🗑️ Delete</div>
+        <div class="tx-content">
+            <div class="tx-main"><span class="tx-category"></span><span class="tx-amount"></span></div>
+            <div class="tx-meta"></div>
+        </div>`;
+    item.querySelector('.tx-category').textContent = categoryLabel(tx.Category);
+    item.querySelector('.tx-amount').textContent = `${tx.Amount.toFixed(2)} RUB`;
+    const tags = (tx.Tags || []).map(tag => `#${tag}`).join(' ');
+    const splits = (tx.Splits || []).map(s => `${categoryLabel(s.Category)} ${s.Amount.toFixed(2)}`).join(' + ');
+    const receipts = tx.Receipts ? `📎 ${tx.Receipts}` : '';
+    const time = tx.Time ? `🕒 ${new Date(tx.Time).toTimeString().slice(0, 5)}` : '';
+    const meta = [time, tx.Description, splits && `➗ ${splits}`, tags, tx.Payer, tx.Fixed ? '📌 fixed' : '', receipts].filter(Boolean).join(' · ');
+    item.querySelector('.tx-meta').textContent = meta;
+    attachGestures(item, tx);
+    return item;
+}
+
+// A tap edits, a swipe left past SWIPE_DELETE deletes; pointer events cover
+// both touch and mouse.
+function attachGestures(item, tx) {
+    const content = item.querySelector('.tx-content');
+    let startX = null;
+    let dx = 0;
+
+    content.addEventListener('pointerdown', e => {
+        startX = e.clientX;
+        dx = 0;
+        content.setPointerCapture(e.pointerId);
+        content.style.transition = 'none';
+    });
+    content.addEventListener('pointermove', e => {
+        if (startX === null) {
+            return;
+        }
+        dx = Math.min(0, e.clientX - startX);
+        content.style.transform = `translateX(${dx}px)`;
+    });
+    const end = () => {
+        if (startX === null) {
+            return;
+        }
+        startX = null;
+        content.style.transition = '';
+        content.style.transform = '';
+        if (dx <= -SWIPE_DELETE) {
+            confirmDelete(tx);
+        } else if (Math.abs(dx) < 5) {
+            startEdit(tx);
+        }
+    };
+    content.addEventListener('pointerup', end);
+    content.addEventListener('pointercancel', () => {
+        startX = null;
+        content.style.transition = '';
+        content.style.transform = '';
+    });
+}
+
+function confirmDelete(tx) {
+    askConfirm(`Delete ${categoryLabel(tx.Category)} ${tx.Amount.toFixed(2)} RUB on ${tx.Date}?`, () => deleteTransaction(tx));
+}
+
+// askConfirm runs action once the user agrees, in Telegram's dialog if available
+function askConfirm(text, action) {
+    const run = ok => {
+        if (ok) {
+            action();
+        }
+    };
+    if (tg && tg.showConfirm) {
+        try {
+            tg.showConfirm(text, run);
+            return;
+        } catch (_) {
+            // showConfirm is not supported outside Telegram
+        }
+    }
+    run(window.confirm(text));
+}
+
+function deleteTransaction(tx) {
+    fetch(`/expenses/transactions/${encodeURIComponent(tx.ID)}`, { method: 'DELETE', headers: authHeaders() })
+        .then(response => response.json())
+        .then(result => {
+            if (result.error) {
+                showMessage(`❌ ${result.error}`, 'error');
+                return;
+            }
+            showMessage('🗑️ Expense deleted', 'success');
+            if (editingId === tx.ID) {
+                cancelEdit();
+            }
+            refreshList();
+        })
+        .catch(() => showMessage('❌ Failed to delete expense. Please try again.', 'error'));
+}
+
+// Editing reuses the add form: it is filled with the expense and saved with PUT
+function startEdit(tx) {
+    const form = document.getElementById('expense-form');
+    editingId = tx.ID;
+    // Imported expenses may use a category the form does not offer
+    const select = form.elements.category;
+    if (![...select.options].some(option => option.value === tx.Category)) {
+        select.appendChild(new Option(tx.Category, tx.Category));
+    }
+    form.elements.date.value = tx.Date;
+    form.elements.time.value = '';
+    if (tx.Time) {
+        // Shown on this device's clock, which timestamp() sends back
+        const at = new Date(tx.Time);
+        form.elements.date.value = isoDate(at);
+        form.elements.time.value = at.toTimeString().slice(0, 5);
+    }
+    form.elements.category.value = tx.Category;
+    form.elements.description.value = tx.Description;
+    form.elements.tags.value = (tx.Tags || []).map(tag => `#${tag}`).join(' ');
+    form.elements.amount.value = tx.Amount;
+    form.elements.fixed.checked = tx.Fixed;
+    setSplits(tx.Splits);
+    loadReceipts();
+    form.querySelector('.submit-btn').textContent = '💾 Save changes';
+    document.getElementById('cancel-edit').hidden = false;
+    form.scrollIntoView({ behavior: 'smooth' });
+}
+
+function cancelEdit() {
+    const form = document.getElementById('expense-form');
+    editingId = null;
+    form.reset();
+    form.querySelector('.submit-btn').textContent = '➕ Add Expense';
+    document.getElementById('cancel-edit').hidden = true;
+    document.getElementById('receipts').hidden = true;
+    updateDateInput();
+}
+
+function saveEdit(data) {
+    fetch(`/expenses/transactions/${encodeURIComponent(editingId)}`, {
+        method: 'PUT',
+        headers: authHeaders({ 'Content-Type': 'application/json' }),
+        body: JSON.stringify({
+            date: data.date,
+            category: data.category,
+            description: data.description,
+            tags: data.tags,
+            amount: data.amount,
+            fixed: data.fixed,
+            splits: data.splits,
+            time: data.time,
+        })
+    })
+    .then(response => response.json())
+    .then(result => {
+        if (result.error) {
+            showMessage(`❌ ${result.error}`, 'error');
+            return;
+        }
+        showMessage('✅ Expense updated', 'success');
+        cancelEdit();
+        refreshList();
+    })
+    .catch(() => showMessage('❌ Failed to update expense. Please try again.', 'error'));
+}
+
+// Receipts can be attached to an expense while it is edited: photos or PDFs
+// are uploaded right away, independent of saving the form.
+function receiptsURL() {
+    return `/expenses/transactions/${encodeURIComponent(editingId)}/receipts`;
+}
+
+function loadReceipts() {
+    const section = document.getElementById('receipts');
+    section.hidden = false;
+    fetch(receiptsURL(), { headers: authHeaders() })
+        .then(response => response.json())
+        .then(result => renderReceipts(result.receipts || []))
+        .catch(() => renderReceipts([]));
+}
+
+function renderReceipts(receipts) {
+    const list = document.getElementById('receipt-list');
+    list.innerHTML = '';
+    if (!receipts.length) {
+        list.textContent = 'No receipts yet.';
+        return;
+    }
+    receipts.forEach(r => {
+        const row = document.createElement('div');
+        row.className = 'receipt-row';
+        const link = document.createElement('a');
+        link.href = '#';
+        link.addEventListener('click', event => {
+            event.preventDefault();
+            openReceipt(r);
+        });
+        link.textContent = `${r.content_type === 'application/pdf' ? '📄' : '🖼️'} ${r.name}`;
+        const remove = document.createElement('button');
+        remove.type = 'button';
+        remove.className = 'split-remove';
+        remove.textContent = '✖️';
+        remove.addEventListener('click', () => deleteReceipt(r));
+        row.append(link, remove);
+        list.appendChild(row);
+    });
+}
+
+// openReceipt downloads the file with the auth header a plain link cannot send.
+function openReceipt(r) {
+    fetch(r.url, { headers: authHeaders() })
+        .then(response => {
+            if (!response.ok) {
+                throw new Error(response.statusText);
+            }
+            return response.blob();
+        })
+        .then(blob => window.open(URL.createObjectURL(blob), '_blank'))
+        .catch(() => showMessage('❌ Failed to open receipt. Please try again.', 'error'));
+}
+
+function uploadReceipt(file) {
+    const body = new FormData();
+    body.append('file', file);
+    fetch(receiptsURL(), { method: 'POST', headers: authHeaders(), body })
+        .then(response => response.json())
+        .then(result => {
+            if (result.error) {
+                showMessage(`❌ ${result.error}`, 'error');
+                return;
+            }
+            showMessage('📎 Receipt attached', 'success');
+            loadReceipts();
+            refreshList();
+        })
+        .catch(() => showMessage('❌ Failed to upload receipt. Please try again.', 'error'));
+}
+
+function deleteReceipt(r) {
+    askConfirm(`Remove ${r.name}?`, () => removeReceipt(r));
+}
+
+function removeReceipt(r) {
+    fetch(r.url, { method: 'DELETE', headers: authHeaders() })
+        .then(response => response.json())
+        .then(result => {
+            if (result.error) {
+                showMessage(`❌ ${result.error}`, 'error');
+                return;
+            }
+            loadReceipts();
+            refreshList();
+        })
+        .catch(() => showMessage('❌ Failed to remove receipt. Please try again.', 'error'));
+}
+
+function initList() {
+    const today = new Date();
+    listTo = isoDate(today);
+    listFrom = isoDate(new Date(today.getFullYear(), today.getMonth(), today.getDate() - LIST_PAGE_DAYS + 1));
+
+    // The filter offers the categories of the form
+    const filter = document.getElementById('list-category');
+    document.querySelectorAll('#category option').forEach(option => {
+        if (option.value) {
+            filter.appendChild(new Option(option.textContent.trim(), option.value));
+        }
+    });
+    filter.addEventListener('change', loadList);
+
+    document.getElementById('list-more').addEventListener('click', () => {
+        const from = new Date(`${listFrom}T00:00:00`);
+        from.setDate(from.getDate() - LIST_PAGE_DAYS);
+        listFrom = isoDate(from);
+        loadList();
+    });
+    document.getElementById('cancel-edit').addEventListener('click', cancelEdit);
+    document.getElementById('receipt-file').addEventListener('change', event => {
+        const file = event.target.files[0];
+        event.target.value = '';
+        if (file && editingId) {
+            uploadReceipt(file);
+        }
+    });
+
+    loadList();
+    subscribeLedger(refreshList);
+}
+
+document.addEventListener('DOMContentLoaded', initList);
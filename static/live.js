@@ -0,0 +1,38 @@
+// Live ledger updates over Server-Sent Events (/expenses/events).
+// onChange(event) gets {seq, kind, transactions} for every add, update or delete;
+// kind is 'reload' when changes may have been missed (a replaced ledger, a gap
+// in sequence numbers or a reconnect), and the page should refetch everything.
+// All subscribers of a page share one connection.
+const ledgerListeners = [];
+
+function subscribeLedger(onChange) {
+    ledgerListeners.push(onChange);
+    if (ledgerListeners.length > 1 || !window.EventSource) {
+        return;
+    }
+    const source = new EventSource('/expenses/events');
+    const emit = change => ledgerListeners.forEach(fn => fn(change));
+    let lastSeq = null;
+
+    source.addEventListener('ready', e => {
+        const seq = JSON.parse(e.data).seq;
+        // A reconnect after missed changes
+        if (lastSeq !== null && seq !== lastSeq) {
+            emit({ seq, kind: 'reload', transactions: [] });
+        }
+        lastSeq = seq;
+    });
+
+    ['add', 'update', 'delete', 'replace'].forEach(kind => {
+        source.addEventListener(kind, e => {
+            const change = JSON.parse(e.data);
+            const missed = lastSeq !== null && change.seq !== lastSeq + 1;
+            lastSeq = change.seq;
+            if (missed || kind === 'replace') {
+                emit({ seq: change.seq, kind: 'reload', transactions: [] });
+            } else {
+                emit({ seq: change.seq, kind, transactions: change.transactions || [] });
+            }
+        });
+    });
+}
@@ -1,4 +1,5 @@
-const tg = window.Telegram.WebApp;
+// The Telegram script may be missing when the app is opened offline before it was cached
+const tg = window.Telegram ? window.Telegram.WebApp : null;
 
 // Initialize Telegram Web App
 if (tg) {
@@ -10,6 +11,12 @@ if (tg) {
     }
 }
 
+// authHeaders signs requests that change stored expenses with the initData
+// Telegram passed when the app was opened; the server checks it.
+function authHeaders(headers = {}) {
+    return { ...headers, 'X-Telegram-Init-Data': tg ? tg.initData : '' };
+}
+
 // Calendar functionality
 let currentDate = new Date();
 let selectedDate = new Date();
@@ -99,6 +106,89 @@ function updateDateInput() {
     const month = String(selectedDate.getMonth() + 1).padStart(2, '0');
     const day = String(selectedDate.getDate()).padStart(2, '0');
     dateInput.value = `${year}-${month}-${day}`;
+    loadDayTotal();
+}
+
+// Total of the selected day; kept current by live updates from other devices
+let dayTotal = null;
+
+function loadDayTotal() {
+    const date = document.getElementById('date').value;
+    if (!date) {
+        return;
+    }
+    fetch(`/expenses/transactions?date=${date}&limit=1&fields=amount`)
+        .then(response => response.json())
+        .then(result => {
+            // Skip answers for a date that is no longer selected
+            if (!result.totals || date !== document.getElementById('date').value) {
+                return;
+            }
+            dayTotal = { date, amount: result.totals.amount, count: result.totals.count };
+            renderDayTotal();
+        })
+        .catch(() => {});
+}
+
+function renderDayTotal() {
+    const el = document.getElementById('day-total');
+    if (!el || !dayTotal) {
+        return;
+    }
+    el.textContent = `💸 ${dayTotal.date}: ${dayTotal.amount.toFixed(2)} RUB in ${dayTotal.count} expense(s)`;
+}
+
+// Adds and deletes are applied in place; an update may have moved an expense
+// from another day, so it refetches like a reload
+function applyLedgerChange(change) {
+    if (!dayTotal) {
+        return;
+    }
+    const sameDay = change.transactions.filter(tx => tx.Date === dayTotal.date);
+    if (change.kind === 'add' || change.kind === 'delete') {
+        const sign = change.kind === 'add' ? 1 : -1;
+        sameDay.forEach(tx => {
+            dayTotal.amount += sign * tx.Amount;
+            dayTotal.count += sign;
+        });
+        renderDayTotal();
+    } else {
+        loadDayTotal();
+    }
+}
+
+// Idempotency key of the expense being submitted. It is kept until the server
+// confirms, so a retry after a network error cannot save the expense twice.
+let pendingKey = null;
+
+function newIdempotencyKey() {
+    if (window.crypto && crypto.randomUUID) {
+        return crypto.randomUUID();
+    }
+    return `${Date.now().toString(36)}-${Math.random().toString(36).slice(2)}`;
+}
+
+// Editing the form makes it a different expense
+document.getElementById('expense-form').addEventListener('input', function() {
+    pendingKey = null;
+});
+
+document.getElementById('date').addEventListener('change', loadDayTotal);
+
+// parseTags turns "#kazan trip" into ["kazan", "trip"]; the server normalizes the rest
+function parseTags(text) {
+    return (text || '').split(/[\s,]+/).map(tag => tag.replace(/^#+/, '')).filter(Boolean);
+}
+
+// timestamp joins the date and the optional time of the form into RFC 3339 with
+// the offset of this device, or '' without a time
+function timestamp(date, time) {
+    if (!date || !time) {
+        return '';
+    }
+    const offset = -new Date(`${date}T${time}:00`).getTimezoneOffset();
+    const pad = n => String(Math.floor(Math.abs(n))).padStart(2, '0');
+    return `${date}T${time}:00${offset < 0 ? '-' : '+'}${pad(offset / 60)}:${pad(offset % 60)}`;
 }
 
 // Form handling
@@ -106,26 +196,56 @@ document.getElementById('expense-form').addEventListener('submit', function(e) {
     e.preventDefault();
     
     const formData = new FormData(this);
+    if (!pendingKey) {
+        pendingKey = newIdempotencyKey();
+    }
     const data = {
         date: formData.get('date'),
         category: formData.get('category'),
         description: formData.get('description'),
+        tags: parseTags(formData.get('tags')),
         amount: parseFloat(formData.get('amount')),
+        fixed: formData.get('fixed') === 'on',
+        splits: readSplits(),
+        time: timestamp(formData.get('date'), formData.get('time')),
+        payer: tg && tg.initDataUnsafe && tg.initDataUnsafe.user ? tg.initDataUnsafe.user.first_name : undefined,
         // optional: include chatId if running inside Telegram WA
-        chat_id: tg && tg.initDataUnsafe && tg.initDataUnsafe.user ? tg.initDataUnsafe.user.id : undefined
+        chat_id: tg && tg.initDataUnsafe && tg.initDataUnsafe.user ? tg.initDataUnsafe.user.id : undefined,
+        // the same key goes with tg.sendData below, so the bot saves the expense once
+        idempotency_key: pendingKey
     };
     
     // Validate data
+    if (!data.category && data.splits && data.splits.length) {
+        data.category = data.splits[0].category;
+    }
     if (!data.date || !data.category || !data.amount || data.amount <= 0) {
         showMessage('Please fill in all required fields correctly.', 'error');
         return;
     }
+    const splitError = validateSplits(data.splits, data.amount);
+    if (splitError) {
+        showMessage(splitError, 'error');
+        return;
+    }
+
+    // The list (list.js) put the form into edit mode
+    if (editingId) {
+        saveEdit(data);
+        return;
+    }
     
+    if (!navigator.onLine) {
+        saveOffline(this, data);
+        return;
+    }
+
     // Send to server
     fetch('/expenses/transaction', {
         method: 'POST',
         headers: {
             'Content-Type': 'application/json',
+            'Idempotency-Key': pendingKey,
         },
         body: JSON.stringify(data)
     })
@@ -135,13 +255,16 @@ document.getElementById('expense-form').addEventListener('submit', function(e) {
             showMessage(result.error, 'error');
         } else {
             showMessage('✅ Expense added successfully!', 'success');
+            pendingKey = null;
             this.reset();
             updateDateInput(); // Reset to selected date
         }
     })
     .catch(error => {
+        // The request never reached the server (or its answer was lost): queue it,
+        // the idempotency key makes the later sync safe either way
         console.error('Error:', error);
-        showMessage('❌ Failed to add expense. Please try again.', 'error');
+        saveOffline(this, data);
     });
     
     // Send to Telegram if available
@@ -150,6 +273,90 @@ document.getElementById('expense-form').addEventListener('submit', function(e) {
     }
 });
 
+// Offline queue: expenses that could not be sent are kept in localStorage and
+// synced through the batch endpoint when the connection returns. Each keeps its
+// idempotency key, so an expense that did reach the server is not added twice.
+const QUEUE_KEY = 'pendingExpenses';
+let syncing = false;
+
+function loadQueue() {
+    try {
+        return JSON.parse(localStorage.getItem(QUEUE_KEY)) || [];
+    } catch (_) {
+        return [];
+    }
+}
+
+function storeQueue(queue) {
+    localStorage.setItem(QUEUE_KEY, JSON.stringify(queue));
+    updateSyncStatus();
+}
+
+function saveOffline(form, data) {
+    const queue = loadQueue();
+    if (!queue.some(item => item.idempotency_key === data.idempotency_key)) {
+        queue.push(data);
+        storeQueue(queue);
+    }
+    showMessage('📴 Saved offline, it will be synced when you are back online.', 'success');
+    pendingKey = null;
+    form.reset();
+    updateDateInput();
+}
+
+function updateSyncStatus() {
+    const status = document.getElementById('sync-status');
+    if (!status) {
+        return;
+    }
+    const count = loadQueue().length;
+    status.hidden = count === 0;
+    status.textContent = count === 1
+        ? '📴 1 expense waiting to sync'
+        : `📴 ${count} expenses waiting to sync`;
+}
+
+function syncPending() {
+    const queue = loadQueue();
+    if (syncing || queue.length === 0 || !navigator.onLine) {
+        return;
+    }
+    syncing = true;
+    fetch('/expenses/transactions:batch', {
+        method: 'POST',
+        headers: authHeaders({ 'Content-Type': 'application/json' }),
+        body: JSON.stringify({ transactions: queue })
+    })
+    .then(response => response.json())
+    .then(result => {
+        if (!result.results) {
+            showMessage(`❌ Sync failed: ${result.error || 'unexpected response'}`, 'error');
+            return;
+        }
+        // Every item got an answer; only items added meanwhile stay queued
+        const answered = new Set(queue.map(item => item.idempotency_key));
+        storeQueue(loadQueue().filter(item => !answered.has(item.idempotency_key)));
+        result.results
+            .filter(r => r.status === 'error')
+            .forEach(r => showMessage(`❌ Offline expense from ${queue[r.index].date} was not saved: ${r.error}`, 'error'));
+        if (result.created > 0) {
+            showMessage(`✅ Synced ${result.created} offline expense(s)`, 'success');
+        }
+    })
+    .catch(error => console.error('Sync error:', error))
+    .finally(() => {
+        syncing = false;
+    });
+}
+
+window.addEventListener('online', syncPending);
+setInterval(syncPending, 30000);
+
+if ('serviceWorker' in navigator) {
+    navigator.serviceWorker.register('/expenses/sw.js', { scope: '/expenses/' })
+        .catch(error => console.error('Service worker registration failed:', error));
+}
+
 // CSV upload handling
 document.getElementById('csv-form').addEventListener('submit', function(e) {
     e.preventDefault();
@@ -239,4 +446,8 @@ document.addEventListener('DOMContentLoaded', function() {
     selectedDate = new Date();
     updateCalendar();
     updateDateInput();
+
+    updateSyncStatus();
+    syncPending();
+    subscribeLedger(applyLedgerChange);
 });
@@ -0,0 +1,113 @@
+// Split editor of the expense form: divides the amount across categories, e.g.
+// the household goods on a supermarket receipt. Each line offers the categories
+// of the form; a split needs at least two lines adding up to the amount.
+
+function splitEditor() {
+    return document.getElementById('splits');
+}
+
+function addSplitLine(category, amount) {
+    const line = document.createElement('div');
+    line.className = 'split-line';
+    const select = document.getElementById('category').cloneNode(true);
+    select.removeAttribute('id');
+    select.removeAttribute('name');
+    select.required = true;
+    if (category && ![...select.options].some(option => option.value === category)) {
+        select.appendChild(new Option(category, category));
+    }
+    select.value = category || '';
+    const input = document.createElement('input');
+    input.type = 'number';
+    input.step = '0.01';
+    input.min = '0';
+    input.required = true;
+    input.placeholder = '0.00';
+    if (amount) {
+        input.value = amount;
+    }
+    const remove = document.createElement('button');
+    remove.type = 'button';
+    remove.className = 'split-remove';
+    remove.textContent = '✖️';
+    remove.addEventListener('click', () => {
+        line.remove();
+        updateSplitRest();
+    });
+    line.append(select, input, remove);
+    document.getElementById('split-lines').appendChild(line);
+    updateSplitRest();
+}
+
+// readSplits returns the split lines, or undefined when the expense is not split
+function readSplits() {
+    if (!document.getElementById('split').checked) {
+        return undefined;
+    }
+    return [...document.querySelectorAll('#split-lines .split-line')].map(line => ({
+        category: line.querySelector('select').value,
+        amount: parseFloat(line.querySelector('input').value) || 0,
+    }));
+}
+
+// setSplits shows the lines of an expense being edited, or closes the editor
+function setSplits(splits) {
+    document.getElementById('split-lines').innerHTML = '';
+    const split = Boolean(splits && splits.length);
+    document.getElementById('split').checked = split;
+    document.getElementById('category').required = !split;
+    splitEditor().hidden = !split;
+    (splits || []).forEach(s => addSplitLine(s.Category, s.Amount));
+}
+
+// updateSplitRest shows how much of the amount is not assigned to a line yet
+function updateSplitRest() {
+    const total = parseFloat(document.getElementById('amount').value) || 0;
+    const splits = readSplits() || [];
+    const rest = Math.round((total - splits.reduce((sum, s) => sum + s.amount, 0)) * 100) / 100;
+    document.getElementById('split-rest').textContent = rest === 0
+        ? '✅ Split adds up'
+        : `Left to split: ${rest.toFixed(2)} RUB`;
+}
+
+// validateSplits returns an error message, or '' when the split is fine
+function validateSplits(splits, amount) {
+    if (!splits) {
+        return '';
+    }
+    if (splits.length < 2) {
+        return 'A split needs at least two lines.';
+    }
+    if (splits.some(s => !s.category || s.amount <= 0)) {
+        return 'Every split line needs a category and an amount.';
+    }
+    const sum = splits.reduce((total, s) => total + s.amount, 0);
+    if (Math.abs(sum - amount) >= 0.005) {
+        return `Split lines add up to ${sum.toFixed(2)}, not ${amount.toFixed(2)}.`;
+    }
+    return '';
+}
+
+function initSplits() {
+    const toggle = document.getElementById('split');
+    toggle.addEventListener('change', () => {
+        splitEditor().hidden = !toggle.checked;
+        // The main category defaults to the first line of a split
+        document.getElementById('category').required = !toggle.checked;
+        if (!toggle.checked) {
+            document.getElementById('split-lines').innerHTML = '';
+        } else if (!document.querySelector('#split-lines .split-line')) {
+            // Start from the chosen category and one more line for the rest
+            addSplitLine(document.getElementById('category').value, document.getElementById('amount').value);
+            addSplitLine('', '');
+        }
+        updateSplitRest();
+    });
+    document.getElementById('split-add').addEventListener('click', () => addSplitLine('', ''));
+    splitEditor().addEventListener('input', updateSplitRest);
+    document.getElementById('amount').addEventListener('input', updateSplitRest);
+    // form.reset() clears the checkbox but not the lines added here
+    document.getElementById('expense-form').addEventListener('reset', () => setSplits(null));
+}
+
+initSplits();
@@ -0,0 +1,82 @@
+// Service worker of the Mini App: keeps the expense form usable offline.
+// Expenses added while offline are queued by script.js and synced later;
+// API calls are never cached here.
+const CACHE = 'expenses-v10';
+
+const PRECACHE = [
+    '/expenses/',
+    '/expenses/static/styles.css',
+    '/expenses/static/script.js',
+    '/expenses/static/live.js',
+    '/expenses/static/list.js',
+    '/expenses/static/splits.js',
+];
+
+const TELEGRAM_SCRIPT = 'https://telegram.org/js/telegram-web-app.js';
+
+self.addEventListener('install', event => {
+    event.waitUntil(
+        caches.open(CACHE)
+            .then(cache => cache.addAll(PRECACHE))
+            .then(() => self.skipWaiting())
+    );
+});
+
+self.addEventListener('activate', event => {
+    event.waitUntil(
+        caches.keys()
+            .then(keys => Promise.all(keys.filter(key => key !== CACHE).map(key => caches.delete(key))))
+            .then(() => self.clients.claim())
+    );
+});
+
+self.addEventListener('fetch', event => {
+    const request = event.request;
+    if (request.method !== 'GET') {
+        return;
+    }
+    const url = new URL(request.url);
+
+    if (request.url === TELEGRAM_SCRIPT || (url.origin === location.origin && url.pathname.startsWith('/expenses/static/'))) {
+        event.respondWith(staleWhileRevalidate(event, request));
+    } else if (request.mode === 'navigate' && url.origin === location.origin && url.pathname === '/expenses/') {
+        event.respondWith(networkFirst(request));
+    }
+});
+
+// Static files are served from the cache at once and refreshed in the background,
+// so a deploy shows up on the next visit.
+async function staleWhileRevalidate(event, request) {
+    const cache = await caches.open(CACHE);
+    const cached = await cache.match(request);
+    const fresh = fetch(request)
+        .then(response => {
+            if (response.ok || response.type === 'opaque') {
+                return cache.put(request, response.clone()).then(() => response);
+            }
+            return response;
+        });
+    if (cached) {
+        event.waitUntil(fresh.catch(() => {}));
+        return cached;
+    }
+    return fresh;
+}
+
+// The page is fetched from the network while online and from the cache otherwise.
+async function networkFirst(request) {
+    const cache = await caches.open(CACHE);
+    try {
+        const response = await fetch(request);
+        if (response.ok) {
+            await cache.put('/expenses/', response.clone());
+        }
+        return response;
+    } catch (error) {
+        const cached = await cache.match('/expenses/');
+        if (cached) {
+            return cached;
+        }
+        throw error;
+    }
+}